@@ -15,34 +15,45 @@ func attach(ctx *cli.Context) (err error) {
 		if ctx.Command.Name == "" {
 			return common.Help(ctx)
 		}
-		return common.PrintErrWithCmdHelp(
-			ctx,
-			errors.New("no hash provided"),
-		)
 	} else if hash == "help" {
 		return cli.ShowCommandHelp(ctx, ctx.Command.Name)
 	}
+	if err = parseOutput(ctx); err != nil {
+		return usageErr(ctx, err)
+	}
+	if hash == "" {
+		return usageErr(ctx, errors.New("no hash provided"))
+	}
 	client, err := warpcli.NewClient()
 	if err != nil {
-		common.PrintRuntimeErr(ctx, "attach", "new_client", err)
+		return runtimeErr(ctx, "attach", "new_client", EXIT_DAEMON, err)
+	}
+	if !machineOutput() {
+		fmt.Println(">> Initiating a WARP download << ")
 	}
-	fmt.Println(">> Initiating a WARP download << ")
 	d, err := client.AttachDownload(hash)
 	if err != nil {
-		common.PrintRuntimeErr(ctx, "attach", "client-attach", err)
-		return nil
+		return runtimeErr(ctx, "attach", "client-attach", EXIT_ERROR, err)
+	}
+	info := &downloadEvent{
+		DownloadId: d.DownloadId,
+		FileName:   d.FileName,
+		SavePath:   d.SavePath,
+		Size:       int64(d.ContentLength),
+		Downloaded: int64(d.Downloaded),
 	}
-	txt := fmt.Sprintf(`
+	if !machineOutput() {
+		txt := fmt.Sprintf(`
 Download Info
 Name`+"\t\t"+`: %s
 Size`+"\t\t"+`: %s
 Save Location`+"\t"+`: %s/
 `,
-		d.FileName,
-		d.ContentLength,
-		d.DownloadDirectory,
-	)
-	fmt.Println(txt)
-	RegisterHandlers(client, int64(d.ContentLength))
-	return client.Listen()
+			d.FileName,
+			d.ContentLength,
+			d.DownloadDirectory,
+		)
+		fmt.Println(txt)
+	}
+	return follow(ctx, client, "attach", info, false)
 }
@@ -0,0 +1,228 @@
+package cmd
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/urfave/cli"
+	"github.com/warpdl/warpdl/common"
+	"github.com/warpdl/warpdl/pkg/warpcli"
+	"github.com/warpdl/warpdl/pkg/warplib"
+)
+
+var (
+	batchInput    string
+	batchDir      string
+	batchParallel int
+
+	batchFlags = []cli.Flag{
+		cli.StringFlag{
+			Name:        "input, i",
+			Usage:       "file containing the urls to download, use - to read from stdin",
+			Destination: &batchInput,
+		},
+		cli.StringFlag{
+			Name:        "download-path, l",
+			Usage:       "set the path where downloaded files should be saved, can be overridden per url (default: directory of the matching category of the daemon, or the current directory)",
+			Destination: &batchDir,
+		},
+		cli.IntFlag{
+			Name:        "parallel, j",
+			Usage:       "number of downloads to run at once (default: 4)",
+			Destination: &batchParallel,
+		},
+		cli.BoolFlag{
+			Name:        "no-glob",
+			Usage:       "don't expand [1-10] and {a,b} patterns in the urls (default: false)",
+			Destination: &noGlob,
+		},
+	}
+)
+
+func batch(ctx *cli.Context) error {
+	if ctx.Args().First() == "help" {
+		return cli.ShowCommandHelp(ctx, ctx.Command.Name)
+	}
+	if err := parseOutput(ctx); err != nil {
+		return usageErr(ctx, err)
+	}
+	if batchInput == "" {
+		return usageErr(ctx, errors.New("no input file provided"))
+	}
+	var r io.Reader = os.Stdin
+	if batchInput != "-" {
+		f, err := os.Open(batchInput)
+		if err != nil {
+			return runtimeErr(ctx, "batch", "open_input", EXIT_USAGE, err)
+		}
+		defer f.Close()
+		r = f
+	}
+	items, err := parseBatchList(r)
+	if err != nil {
+		return runtimeErr(ctx, "batch", "parse_input", EXIT_USAGE, err)
+	}
+	if len(items) == 0 {
+		if machineOutput() {
+			printBatch(&common.BatchResponse{Results: []common.BatchResult{}})
+			return nil
+		}
+		fmt.Println("warp: no urls found in input")
+		return nil
+	}
+	var headers warplib.Headers
+	if userAgent != "" {
+		headers = warplib.Headers{{
+			Key: warplib.USER_AGENT_KEY, Value: getUserAgent(userAgent),
+		}}
+	}
+	for i := range items {
+		it := &items[i]
+		if it.DownloadDirectory == "" {
+			it.DownloadDirectory = batchDir
+		}
+		it.DownloadDirectory, err = absPath(it.DownloadDirectory)
+		if err != nil {
+			return runtimeErr(ctx, "batch", "download_path", EXIT_USAGE, err)
+		}
+		it.Headers = headers
+		it.ForceParts = forceParts
+		it.MaxConnections = int32(maxConns)
+		it.MaxSegments = int32(maxParts)
+		it.RaceTail = raceTail
+	}
+	client, err := warpcli.NewClient()
+	if err != nil {
+		return runtimeErr(ctx, "batch", "new_client", EXIT_DAEMON, err)
+	}
+	if !machineOutput() {
+		fmt.Printf(">> Downloading %d urls in a WARP batch <<\n", len(items))
+	}
+	return runBatch(ctx, "batch", client, items, &warpcli.BatchOpts{
+		MaxParallel: batchParallel,
+		NoGlob:      noGlob,
+	})
+}
+
+// runBatch downloads the items as a batch of cmd and prints its
+// results, it exits with EXIT_FAILED if any download failed.
+func runBatch(ctx *cli.Context, cmd string, client *warpcli.Client, items []common.DownloadParams, opts *warpcli.BatchOpts) error {
+	res, err := client.Batch(items, opts)
+	if err != nil {
+		return runtimeErr(ctx, cmd, "client-batch", EXIT_ERROR, err)
+	}
+	printBatch(res)
+	if res.Failed != 0 {
+		return cli.NewExitError("", EXIT_FAILED)
+	}
+	return nil
+}
+
+// printBatch prints the results of a batch as a summary, a
+// JSON object or an NDJSON event per download.
+func printBatch(res *common.BatchResponse) {
+	switch output {
+	case OUTPUT_JSON:
+		printJSON(res)
+	case OUTPUT_NDJSON:
+		writeBatchEvents(res, "")
+	default:
+		printBatchSummary(res)
+	}
+}
+
+// downloadPattern downloads every url expanded from
+// the url pattern as a batch.
+func downloadPattern(ctx *cli.Context, pattern string, n int, headers warplib.Headers, conflictPolicy warplib.ConflictPolicy, rangeStart, rangeEnd int64, extractOpts *warplib.ExtractOpts) error {
+	if fileName != "" {
+		return usageErr(ctx, errors.New("file name can't be set for a url pattern"))
+	}
+	dir, err := absPath(dlPath)
+	if err != nil {
+		return runtimeErr(ctx, "download", "download_path", EXIT_USAGE, err)
+	}
+	client, err := warpcli.NewClient()
+	if err != nil {
+		return runtimeErr(ctx, "download", "new_client", EXIT_DAEMON, err)
+	}
+	if !machineOutput() {
+		fmt.Printf(">> Downloading %d files matching the url pattern <<\n", n)
+	}
+	return runBatch(ctx, "download", client, []common.DownloadParams{{
+		Url:               pattern,
+		DownloadDirectory: dir,
+		Headers:           headers,
+		ForceParts:        forceParts,
+		MaxConnections:    int32(maxConns),
+		MaxSegments:       int32(maxParts),
+		ConflictPolicy:    conflictPolicy,
+		DecodeContent:     decode,
+		RaceTail:          raceTail,
+		RangeStart:        rangeStart,
+		RangeEnd:          rangeEnd,
+		Extract:           extractOpts,
+		Tags:              dlTags,
+		Note:              dlNote,
+	}}, &warpcli.BatchOpts{})
+}
+
+// absPath returns the absolute path of the path as the daemon
+// resolves relative paths from its own directory. An empty path
+// is kept to let the daemon pick the directory of a category.
+func absPath(path string) (string, error) {
+	if path == "" {
+		return "", nil
+	}
+	return filepath.Abs(path)
+}
+
+// parseBatchList parses a list of urls, one per line, optionally
+// followed by space separated options: name=<file name> and
+// dir=<download directory>. Empty lines and lines starting with
+// # are ignored.
+func parseBatchList(r io.Reader) (items []common.DownloadParams, err error) {
+	sc := bufio.NewScanner(r)
+	var ln int
+	for sc.Scan() {
+		ln++
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+		item := common.DownloadParams{Url: fields[0]}
+		for _, opt := range fields[1:] {
+			key, val, ok := strings.Cut(opt, "=")
+			if !ok || val == "" {
+				return nil, fmt.Errorf("line %d: invalid option %q", ln, opt)
+			}
+			switch key {
+			case "name", "o":
+				item.FileName = val
+			case "dir", "l":
+				item.DownloadDirectory = val
+			default:
+				return nil, fmt.Errorf("line %d: unknown option %q", ln, key)
+			}
+		}
+		items = append(items, item)
+	}
+	return items, sc.Err()
+}
+
+func printBatchSummary(res *common.BatchResponse) {
+	fmt.Println("\nBatch Summary")
+	for i, r := range res.Results {
+		if r.Error == "" {
+			fmt.Printf("%3d. [done]   %s\n", i+1, r.SavePath)
+			continue
+		}
+		fmt.Printf("%3d. [failed] %s\n       error: %s\n", i+1, r.Url, r.Error)
+	}
+	fmt.Printf("\nCompleted\t: %d\nFailed\t\t: %d\n", res.Completed, res.Failed)
+}
@@ -1,6 +1,7 @@
 package cmd
 
 import (
+	"fmt"
 	"time"
 
 	"github.com/vbauerster/mpb/v8"
@@ -30,13 +31,16 @@ func downloadProgress(sc *SpeedCounter) func(dr *common.DownloadingResponse) err
 	}
 }
 
-func downloadComplete(client *warpcli.Client, dbar, cbar *mpb.Bar, sc *SpeedCounter) func(dr *common.DownloadingResponse) error {
+func downloadComplete(client *warpcli.Client, dbar, cbar *mpb.Bar, sc *SpeedCounter, extract bool) func(dr *common.DownloadingResponse) error {
 	return func(dr *common.DownloadingResponse) error {
 		// fmt.Println("Download Complete: ", dr.Hash)
 		if dr.Hash != warplib.MAIN_HASH {
 			return nil
 		}
-		defer client.Disconnect()
+		if !extract {
+			// wait for the extraction otherwise
+			defer client.Disconnect()
+		}
 		sc.Stop()
 		// fill download bar
 		if dbar.Completed() {
@@ -68,7 +72,36 @@ func compileProgress(bar *mpb.Bar) func(dr *common.DownloadingResponse) error {
 	}
 }
 
-func RegisterHandlers(client *warpcli.Client, contentLength int64) {
+func extractStart(p *mpb.Progress, ebar **mpb.Bar) func(dr *common.DownloadingResponse) error {
+	return func(dr *common.DownloadingResponse) error {
+		*ebar = cmdCommon.InitExtractBar(p, "", dr.Value)
+		return nil
+	}
+}
+
+func extractProgress(ebar **mpb.Bar) func(dr *common.DownloadingResponse) error {
+	return func(dr *common.DownloadingResponse) error {
+		if *ebar != nil {
+			(*ebar).IncrBy(int(dr.Value))
+		}
+		return nil
+	}
+}
+
+func extractComplete(client *warpcli.Client, ebar **mpb.Bar) func(dr *common.DownloadingResponse) error {
+	return func(dr *common.DownloadingResponse) error {
+		defer client.Disconnect()
+		if *ebar != nil {
+			(*ebar).SetTotal(-1, true)
+		}
+		fmt.Printf("Extracted to %s\n", dr.Path)
+		return nil
+	}
+}
+
+// RegisterHandlers shows the progress of a download, extract
+// reports whether the download is extracted once complete.
+func RegisterHandlers(client *warpcli.Client, contentLength int64, extract bool) {
 	sc := NewSpeedCounter(4350 * time.Microsecond)
 	p := mpb.New(mpb.WithWidth(64), mpb.WithRefreshRate(time.Millisecond*100))
 	dbar, cbar := cmdCommon.InitBars(p, "", contentLength)
@@ -84,7 +117,7 @@ func RegisterHandlers(client *warpcli.Client, contentLength int64) {
 	)
 	client.AddHandler(
 		common.UPDATE_DOWNLOADING,
-		warpcli.NewDownloadingHandler(common.DownloadComplete, downloadComplete(client, dbar, cbar, sc)),
+		warpcli.NewDownloadingHandler(common.DownloadComplete, downloadComplete(client, dbar, cbar, sc, extract)),
 	)
 	client.AddHandler(
 		common.UPDATE_DOWNLOADING,
@@ -98,4 +131,20 @@ func RegisterHandlers(client *warpcli.Client, contentLength int64) {
 		common.UPDATE_DOWNLOADING,
 		warpcli.NewDownloadingHandler(common.CompileStart, compileStart),
 	)
+	if !extract {
+		return
+	}
+	var ebar *mpb.Bar
+	client.AddHandler(
+		common.UPDATE_DOWNLOADING,
+		warpcli.NewDownloadingHandler(common.ExtractStart, extractStart(p, &ebar)),
+	)
+	client.AddHandler(
+		common.UPDATE_DOWNLOADING,
+		warpcli.NewDownloadingHandler(common.ExtractProgress, extractProgress(&ebar)),
+	)
+	client.AddHandler(
+		common.UPDATE_DOWNLOADING,
+		warpcli.NewDownloadingHandler(common.ExtractComplete, extractComplete(client, &ebar)),
+	)
 }
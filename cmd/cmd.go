@@ -43,6 +43,7 @@ func Execute(args []string, bArgs BuildArgs) error {
 			{
 				Name:   "daemon",
 				Action: daemon,
+				Flags:  daemonFlags,
 			},
 			{
 				Name:               "info",
@@ -57,10 +58,12 @@ func Execute(args []string, bArgs BuildArgs) error {
 			{
 				Name:   "stop",
 				Action: stop,
+				Flags:  stopFlags,
 			},
 			{
 				Name:   "attach",
 				Action: attach,
+				Flags:  []cli.Flag{outputFlag},
 			},
 			{
 				Name:                   "download",
@@ -84,6 +87,16 @@ func Execute(args []string, bArgs BuildArgs) error {
 				UseShortOptionHandling: true,
 				Flags:                  lsFlags,
 			},
+			{
+				Name:                   "top",
+				Usage:                  "monitor and control the downloads in a terminal dashboard",
+				Action:                 top,
+				OnUsageError:           common.UsageErrorCallback,
+				CustomHelpTemplate:     CMD_HELP_TEMPL,
+				Description:            TopDescription,
+				UseShortOptionHandling: true,
+				Flags:                  topFlags,
+			},
 			{
 				Name:                   "resume",
 				Aliases:                []string{"r"},
@@ -95,6 +108,48 @@ func Execute(args []string, bArgs BuildArgs) error {
 				UseShortOptionHandling: true,
 				Flags:                  rsFlags,
 			},
+			{
+				Name:                   "batch",
+				Aliases:                []string{"b"},
+				Usage:                  "download all the urls listed in a file",
+				Description:            BatchDescription,
+				OnUsageError:           common.UsageErrorCallback,
+				CustomHelpTemplate:     CMD_HELP_TEMPL,
+				Action:                 batch,
+				UseShortOptionHandling: true,
+				Flags:                  batchFlags,
+			},
+			{
+				Name:                   "crawl",
+				Aliases:                []string{"mirror"},
+				Usage:                  "download the files of a directory index recursively",
+				Description:            CrawlDescription,
+				OnUsageError:           common.UsageErrorCallback,
+				CustomHelpTemplate:     CMD_HELP_TEMPL,
+				Action:                 crawl,
+				UseShortOptionHandling: true,
+				Flags:                  crawlFlags,
+			},
+			{
+				Name:                   "schedule",
+				Usage:                  "schedule a download or list the scheduled downloads",
+				Description:            ScheduleDescription,
+				OnUsageError:           common.UsageErrorCallback,
+				CustomHelpTemplate:     CMD_HELP_TEMPL,
+				Action:                 schedule,
+				UseShortOptionHandling: true,
+				Flags:                  scheduleFlags,
+			},
+			{
+				Name:                   "tag",
+				Usage:                  "add or remove tags of a download and set its note",
+				Description:            TagDescription,
+				OnUsageError:           common.UsageErrorCallback,
+				CustomHelpTemplate:     CMD_HELP_TEMPL,
+				Action:                 tag,
+				UseShortOptionHandling: true,
+				Flags:                  tagFlags,
+			},
 			{
 				Name:                   "flush",
 				Aliases:                []string{"c"},
@@ -51,6 +51,26 @@ func InitBars(p *mpb.Progress, prefix string, cLength int64) (dbar *mpb.Bar, cba
 	return
 }
 
+// InitExtractBar adds a bar showing the extraction
+// progress of an archive of the size total.
+func InitExtractBar(p *mpb.Progress, prefix string, total int64) *mpb.Bar {
+	barStyle := mpb.BarStyle().Lbound("╢").Filler("█").Tip("█").Padding("░").Rbound("╟")
+	name := prefix + "Extracting"
+	bar := p.New(total,
+		barStyle,
+		mpb.PrependDecorators(
+			decor.Name(name, decor.WC{W: len(name) + 1, C: decor.DindentRight}),
+			decor.OnComplete(
+				decor.AverageETA(decor.ET_STYLE_GO, decor.WC{W: 4}), "Complete",
+			),
+		),
+		mpb.AppendDecorators(
+			decor.AverageSpeed(decor.SizeB1024(0), "% .2f"),
+		),
+	)
+	return bar
+}
+
 func Help(ctx *cli.Context) error {
 	arg := ctx.Args().First()
 	if arg == "" || arg == "help" {
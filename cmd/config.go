@@ -24,6 +24,48 @@ which can be used to resume pending downloads.
 Example:
         warpdl list
 
+Use --category or --tag to only list the downloads of a
+category or with a tag:
+        warpdl list -a --category isos
+        warpdl list -a --tag work
+
+Downloads can also be filtered by state, host, name,
+date added and size, sorted and paged:
+        warpdl list --state downloading --sort speed -r
+        warpdl list -a --name-regex '\.iso$' --min-size 1GB
+        warpdl list -a --added-after 7d --host github.com
+        warpdl list -a -n 20 --page 2
+
+Use --output json to print {"total", "downloads"} where total
+counts the matching downloads before paging, or --output ndjson
+to print a download per line. A download has hash, name, url,
+save_path, size (-1 if unknown), downloaded, progress (percent),
+speed (bytes/s), eta (seconds, -1 if not running), state,
+date_added, and group, parent_hash, category, tags and note
+when set.
+
+`
+	TopDescription = `The top command shows a live dashboard of the incomplete
+downloads with their speed, ETA, connections and the segment
+map of the selected download, updated as the daemon reports
+their progress.
+
+Keys:
+        up/down, k/j    select a download
+        p               pause or resume the download
+        r, s            resume or stop the download
+        +, -            raise or lower its connection cap
+        f               flush the download
+        a               add a url to download
+        q               quit
+
+Downloads resumed and added from the dashboard use the
+connection flags below, as the resume command does.
+
+Example:
+        warpdl top
+        warpdl top -a -l ~/Downloads
+
 `
 	InfoDescription = `The info command makes a GET request to the entered 
 url and and tries to fetch the basic file info like 
@@ -32,6 +74,10 @@ name, size etc.
 Example:
         warpdl info https://domain.com/file.zip
 
+Use --output json to print the info as a JSON object with url,
+file_name, size (-1 if unknown), effective_url, redirects,
+encoding, probe_method, status_code, ranges_supported and speed.
+
 `
 	DownloadDescription = `The download command lets you quickly fetch and save 
 files from the internet. You can initiate the download
@@ -47,13 +93,162 @@ Example:
 					OR
         warpdl download https://domain.com/file.zip
 
+Use "-o -" to stream the file to stdout without the daemon,
+an interrupted stream exits with 4:
+        warpdl download -o - https://domain.com/file.tar | tar x
+
+Use --at and --window to schedule the download, see
+"warpdl help schedule":
+        warpdl download --at 02:00 https://domain.com/file.zip
+
+Use the daemon's --on-complete and --on-error to run a command
+once a download ends, the daemon sets WARPDL_PATH, WARPDL_HASH,
+WARPDL_SIZE, WARPDL_URL, WARPDL_CHECKSUM, WARPDL_STATUS and
+WARPDL_ERROR for it. Its output is kept in the download's log:
+        warpdl daemon --on-complete 'notify-send "$WARPDL_PATH"'
+
+Use --extract to extract a zip or tar archive (optionally
+compressed with gzip, zstd, xz or bzip2) once downloaded:
+        warpdl download --extract --delete-archive https://domain.com/file.tar.gz
+        warpdl download --extract=./out https://domain.com/file.zip
+
+Without --download-path, the file is saved to the directory
+of the first matching category of the daemon, read from the
+categories.json file of the config directory (see the daemon's
+--categories flag):
+        [
+          {"name": "isos", "directory": "~/isos", "extensions": ["iso"]},
+          {"name": "videos", "directory": "~/Videos",
+           "mime_types": ["video/*"], "tags": ["media"]},
+          {"name": "github", "directory": "~/src",
+           "hosts": ["github.com"], "url_regex": "/releases/download/"}
+        ]
+A category matches by any of its extensions, mime types, hosts
+or its url regex.
+
+Numbered sequences and alternatives in the url download
+every matching file, use --no-glob to disable it:
+        warpdl download "https://domain.com/part-[001-250].tar"
+        warpdl download "https://domain.com/img{a,b,c}.png"
+
+Use --output ndjson (or json) to print the progress as one JSON
+event per line instead of progress bars. The first event is
+"info", followed by "download_progress" at most every 500ms,
+"connections_tuned", "download_complete", "extract_start" and
+"extract_complete", the last one being "download_complete",
+"extract_complete", "download_stopped" or "download_failed":
+        {"event": "download_progress", "download_id": "3669182c",
+         "time": "2026-01-02T15:04:05Z", "size": 30000000,
+         "downloaded": 11337728, "speed": 2210696}
+Every event has event, download_id, time, size (-1 if unknown)
+and downloaded. info adds file_name and save_path, as well as
+scheduled or group if nothing follows. connections_tuned adds
+connections, extract_start adds extract_size, extract_complete
+adds extract_path and download_failed adds error. The resume
+and attach commands print the same events.
+
+Download, resume, attach, list, info, batch and crawl exit with:
+        0  success
+        1  the daemon rejected the request
+        2  invalid arguments or flags
+        3  the daemon can't be reached
+        4  the download was stopped
+        5  the download failed
+With the json and ndjson outputs, errors are printed to stderr
+as {"error", "command", "action", "exit_code"}.
+
 `
 	ResumeDescription = `The resume command lets you resume an incomplete download
 using its unique download hash which you can retrieve by 
 using "warpdl list" command.
 
+If the download link has expired, a fresh one can be
+provided with the --url flag, the new link must point to
+a file of identical size.
+
 Example:
         warpdl resume <unique download hash>
+        warpdl resume --url <new url> <unique download hash>
+
+`
+	BatchDescription = `The batch command downloads all the urls listed in a
+file, one url per line. Empty lines and lines starting
+with # are ignored. A url can be followed by options
+to override the file name (name=) and the download
+directory (dir=) of that download.
+
+Urls can contain patterns like [001-250], [0-100:10]
+and {a,b,c}, see "warpdl help download".
+
+Use "-i -" to read the list from stdin. A summary of
+the completed and failed downloads is printed once
+every download has finished.
+
+Use --output json to print the summary as {"results",
+"completed", "failed"}, or --output ndjson to print a
+"download_complete" or "download_failed" event per url
+with url, download_id, file_name, save_path and error.
+Downloads of a url pattern print the same output. It
+exits with 5 if any download failed.
+
+Example:
+        # urls.txt
+        https://domain.com/file.zip
+        https://domain.com/other.zip name=renamed.zip dir=/data
+
+        warpdl batch -i urls.txt -j 2
+
+`
+	CrawlDescription = `The crawl command downloads all the files of a directory
+index, such as the ones served by Apache and nginx autoindex,
+and of its subdirectories into the same directory structure.
+
+Files can be filtered by their extensions and by regular
+expressions matching their urls. The crawl is listed as a
+single download which tracks the progress of all its files.
+
+Use --output json or ndjson to print the results as the
+batch command does, the JSON object adds download_id and
+download_directory of the crawl and the events add its
+download id as group_id.
+
+Example:
+        warpdl crawl https://domain.com/pub/
+        warpdl crawl -d 2 -e iso,tar.gz https://domain.com/pub/
+        warpdl crawl --exclude '/old/' https://domain.com/pub/
+
+`
+	ScheduleDescription = `The schedule command sets when a download is allowed to
+run: a time to start at and daily time windows, each with
+an optional speed limit. The daemon pauses the download at
+the end of a window and resumes it when the next one begins.
+Stopping a scheduled download removes its schedule.
+
+Without a download hash, it lists the scheduled downloads.
+
+Example:
+        warpdl schedule <unique download hash> --at 02:00
+        warpdl schedule <unique download hash> --window 22:00-06:00 --window 12:00-13:00@1MB
+        warpdl schedule <unique download hash> --clear
+        warpdl schedule
+
+`
+	TagDescription = `The tag command adds tags to a download, removes them with
+--remove and sets its note with --note. It shows the tags
+and the note of the download otherwise. Tags can be set
+when downloading with --tag.
+
+Downloads sharing a tag can be listed, resumed, stopped
+and flushed together with the --tag flag of these commands.
+
+Example:
+        warpdl tag <unique download hash> work iso
+        warpdl tag <unique download hash> --remove iso --note "for the lab"
+        warpdl download --tag work https://domain.com/file.zip
+        warpdl list -a --tag work
+        warpdl resume --tag work
+        warpdl stop --tag work
+        warpdl flush --tag work
 
 `
 	FlushDescription = `The flush command deletes download history for the current
@@ -62,6 +257,7 @@ user, it will also delete incomplete downloads and their date.
 Example:
         warpdl flush
 		warpdl flush [HASH]
+		warpdl flush --tag [TAG]
 
 `
 )
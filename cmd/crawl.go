@@ -0,0 +1,128 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/urfave/cli"
+	"github.com/warpdl/warpdl/common"
+	"github.com/warpdl/warpdl/pkg/warpcli"
+	"github.com/warpdl/warpdl/pkg/warplib"
+)
+
+var (
+	crawlDepth      int
+	crawlOtherHosts bool
+	crawlInclude    string
+	crawlExclude    string
+	crawlExtensions string
+
+	crawlFlags = []cli.Flag{
+		cli.StringFlag{
+			Name:        "download-path, l",
+			Usage:       "set the path where the crawled directory should be saved (default: directory of the matching category of the daemon, or the current directory)",
+			Destination: &dlPath,
+		},
+		cli.IntFlag{
+			Name:        "depth, d",
+			Usage:       "maximum depth of subdirectories to crawl, 0 for the index only",
+			Value:       warplib.DEF_CRAWL_DEPTH,
+			Destination: &crawlDepth,
+		},
+		cli.BoolFlag{
+			Name:        "other-hosts",
+			Usage:       "also download files linked from other hosts (default: false)",
+			Destination: &crawlOtherHosts,
+		},
+		cli.StringFlag{
+			Name:        "include",
+			Usage:       "only download files with urls matching the regular expression",
+			Destination: &crawlInclude,
+		},
+		cli.StringFlag{
+			Name:        "exclude",
+			Usage:       "skip files with urls matching the regular expression",
+			Destination: &crawlExclude,
+		},
+		cli.StringFlag{
+			Name:        "ext",
+			Usage:       "only download files with the comma separated extensions, e.g. iso,tar.gz",
+			Destination: &crawlExtensions,
+		},
+		cli.IntFlag{
+			Name:        "parallel, j",
+			Usage:       "number of downloads to run at once (default: 4)",
+			Destination: &batchParallel,
+		},
+	}
+)
+
+func crawl(ctx *cli.Context) error {
+	url := ctx.Args().First()
+	if url == "help" {
+		return cli.ShowCommandHelp(ctx, ctx.Command.Name)
+	}
+	if err := parseOutput(ctx); err != nil {
+		return usageErr(ctx, err)
+	}
+	if url == "" {
+		return usageErr(ctx, errors.New("no url provided"))
+	}
+	dir, err := absPath(dlPath)
+	if err != nil {
+		return runtimeErr(ctx, "crawl", "download_path", EXIT_USAGE, err)
+	}
+	var extensions []string
+	for _, ext := range strings.Split(crawlExtensions, ",") {
+		if ext = strings.TrimSpace(ext); ext != "" {
+			extensions = append(extensions, ext)
+		}
+	}
+	var headers warplib.Headers
+	if userAgent != "" {
+		headers = warplib.Headers{{
+			Key: warplib.USER_AGENT_KEY, Value: getUserAgent(userAgent),
+		}}
+	}
+	client, err := warpcli.NewClient()
+	if err != nil {
+		return runtimeErr(ctx, "crawl", "new_client", EXIT_DAEMON, err)
+	}
+	if !machineOutput() {
+		fmt.Println(">> Crawling the directory index, this may take a while <<")
+	}
+	res, err := client.Crawl(&common.CrawlParams{
+		DownloadParams: common.DownloadParams{
+			Url:               strings.TrimSpace(url),
+			DownloadDirectory: dir,
+			Headers:           headers,
+			ForceParts:        forceParts,
+			MaxConnections:    int32(maxConns),
+			MaxSegments:       int32(maxParts),
+			RaceTail:          raceTail,
+		},
+		MaxDepth:    crawlDepth,
+		OtherHosts:  crawlOtherHosts,
+		Include:     crawlInclude,
+		Exclude:     crawlExclude,
+		Extensions:  extensions,
+		MaxParallel: batchParallel,
+	})
+	if err != nil {
+		return runtimeErr(ctx, "crawl", "client-crawl", EXIT_ERROR, err)
+	}
+	switch output {
+	case OUTPUT_JSON:
+		printJSON(res)
+	case OUTPUT_NDJSON:
+		writeBatchEvents(&res.BatchResponse, res.DownloadId)
+	default:
+		fmt.Printf("\nSaved to %s/ (hash: %s)\n", res.DownloadDirectory, res.DownloadId)
+		printBatchSummary(&res.BatchResponse)
+	}
+	if res.Failed != 0 {
+		return cli.NewExitError("", EXIT_FAILED)
+	}
+	return nil
+}
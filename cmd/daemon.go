@@ -4,17 +4,94 @@ import (
 	"log"
 	"net/http"
 	"net/http/cookiejar"
+	"os"
 
 	"github.com/urfave/cli"
 	"github.com/warpdl/warpdl/cmd/common"
 	"github.com/warpdl/warpdl/internal/api"
 	"github.com/warpdl/warpdl/internal/extl"
 	"github.com/warpdl/warpdl/internal/server"
+	"github.com/warpdl/warpdl/internal/webhook"
 	"github.com/warpdl/warpdl/pkg/warplib"
 )
 
+var (
+	defConflictPolicy string
+	defHooks          warplib.Hooks
+	webhookUrls       cli.StringSlice
+	webhookSecret     string
+	webhookEvents     string
+	webhookRetries    int
+	categoriesFile    string
+
+	daemonFlags = []cli.Flag{
+		cli.StringFlag{
+			Name:        "on-conflict",
+			Usage:       "default action if file already exists: overwrite, rename, skip (if of the same size) or fail",
+			EnvVar:      "WARP_DAEMON_ON_CONFLICT",
+			Value:       string(warplib.ConflictRename),
+			Destination: &defConflictPolicy,
+		},
+		cli.StringFlag{
+			Name:        "on-complete",
+			Usage:       "command to run when a download completes, described by WARPDL_* environment variables",
+			EnvVar:      "WARP_DAEMON_ON_COMPLETE",
+			Destination: &defHooks.OnComplete,
+		},
+		cli.StringFlag{
+			Name:        "on-error",
+			Usage:       "command to run when a download fails, described by WARPDL_* environment variables",
+			EnvVar:      "WARP_DAEMON_ON_ERROR",
+			Destination: &defHooks.OnError,
+		},
+		cli.DurationFlag{
+			Name:        "hook-timeout",
+			Usage:       "time after which a hook command is killed",
+			EnvVar:      "WARP_DAEMON_HOOK_TIMEOUT",
+			Value:       warplib.DEF_HOOK_TIMEOUT,
+			Destination: &defHooks.Timeout,
+		},
+		cli.StringSliceFlag{
+			Name:   "webhook",
+			Usage:  "url to POST the events of downloads to (can be repeated)",
+			EnvVar: "WARP_DAEMON_WEBHOOKS",
+			Value:  &webhookUrls,
+		},
+		cli.StringFlag{
+			Name:        "webhook-secret",
+			Usage:       "secret to sign the webhook payloads with, see the X-Warpdl-Signature header",
+			EnvVar:      "WARP_DAEMON_WEBHOOK_SECRET",
+			Destination: &webhookSecret,
+		},
+		cli.StringFlag{
+			Name:        "webhook-events",
+			Usage:       "comma separated events sent to the webhooks: started, complete, stopped and failed (default: all)",
+			EnvVar:      "WARP_DAEMON_WEBHOOK_EVENTS",
+			Destination: &webhookEvents,
+		},
+		cli.IntFlag{
+			Name:        "webhook-retries",
+			Usage:       "number of times a failed webhook delivery is retried, -1 disables retries",
+			EnvVar:      "WARP_DAEMON_WEBHOOK_RETRIES",
+			Value:       webhook.DEF_MAX_RETRIES,
+			Destination: &webhookRetries,
+		},
+		cli.StringFlag{
+			Name:        "categories",
+			Usage:       "JSON file of the categories picking the directory of downloads without a download path",
+			EnvVar:      "WARP_DAEMON_CATEGORIES",
+			Value:       warplib.ConfigDir + "/categories.json",
+			Destination: &categoriesFile,
+		},
+	}
+)
+
 func daemon(ctx *cli.Context) error {
 	l := log.Default()
+	conflictPolicy, err := warplib.ParseConflictPolicy(defConflictPolicy)
+	if err != nil {
+		return common.PrintErrWithCmdHelp(ctx, err)
+	}
 	cm, err := getCookieManager(ctx)
 	if err != nil {
 		// nil because err has already been handled in getCookieManager function
@@ -39,12 +116,51 @@ func daemon(ctx *cli.Context) error {
 		common.PrintRuntimeErr(ctx, "daemon", "init_manager", err)
 		return nil
 	}
-	s, err := api.NewApi(l, m, client, elEng)
+	m.SetDefaultHooks(&defHooks)
+	webhooks, err := newWebhooks()
+	if err != nil {
+		common.PrintRuntimeErr(ctx, "daemon", "webhooks", err)
+		return nil
+	}
+	categories, err := warplib.LoadCategories(categoriesFile)
+	if err != nil {
+		common.PrintRuntimeErr(ctx, "daemon", "load_categories", err)
+		return nil
+	}
+	s, err := api.NewApi(l, m, client, elEng, conflictPolicy, webhooks, categories)
 	if err != nil {
 		common.PrintRuntimeErr(ctx, "daemon", "new_api", err)
 		return nil
 	}
 	serv := server.NewServer(l, m, DEF_PORT)
 	s.RegisterHandlers(serv)
+	go s.RunScheduler(serv.GetPool())
 	return serv.Start()
 }
+
+// newWebhooks returns the dispatcher of the webhooks set by the
+// flags, its deliveries are logged to webhooks.log in the config
+// directory. It returns nil if no webhook is set.
+func newWebhooks() (*webhook.Dispatcher, error) {
+	if len(webhookUrls) == 0 {
+		return nil, nil
+	}
+	events, err := webhook.ParseEvents(webhookEvents)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(
+		warplib.ConfigDir+"/webhooks.log",
+		os.O_WRONLY|os.O_CREATE|os.O_APPEND,
+		0666,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return webhook.NewDispatcher(log.New(f, "", log.LstdFlags), nil, &webhook.Config{
+		Urls:       webhookUrls,
+		Secret:     webhookSecret,
+		Events:     events,
+		MaxRetries: webhookRetries,
+	}), nil
+}
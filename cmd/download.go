@@ -3,6 +3,7 @@ package cmd
 import (
 	"errors"
 	"fmt"
+	"path/filepath"
 	"strings"
 
 	"github.com/urfave/cli"
@@ -12,43 +13,130 @@ import (
 )
 
 var (
-	dlPath   string
-	fileName string
+	dlPath        string
+	fileName      string
+	onConflict    string
+	decode        bool
+	byteRange     string
+	noGlob        bool
+	extract       extractFlag
+	deleteArchive bool
+	dlTags        cli.StringSlice
+	dlNote        string
 
 	dlFlags = []cli.Flag{
 		cli.StringFlag{
 			Name:        "file-name, o",
-			Usage:       "explicitly set the name of file (determined automatically if not specified)",
+			Usage:       "explicitly set the name of file (determined automatically if not specified), use - to write to stdout without the daemon",
 			Destination: &fileName,
 		},
 		cli.StringFlag{
 			Name:        "download-path, l",
-			Usage:       "set the path where downloaded file should be saved",
-			Value:       ".",
+			Usage:       "set the path where downloaded file should be saved (default: directory of the matching category of the daemon, or the current directory)",
 			Destination: &dlPath,
 		},
+		cli.StringFlag{
+			Name:        "on-conflict",
+			Usage:       "action if file already exists: overwrite, rename, skip (if of the same size) or fail (default: daemon's policy)",
+			EnvVar:      "WARP_ON_CONFLICT",
+			Destination: &onConflict,
+		},
+		cli.BoolFlag{
+			Name:        "decode",
+			Usage:       "request compressed content and decode it on the fly, disables segmentation and resuming (default: false)",
+			Destination: &decode,
+		},
+		cli.StringFlag{
+			Name:        "range",
+			Usage:       "download only a byte range of the file, e.g. 0-1048575, 1048576- or -65536 for the last 64KB",
+			Destination: &byteRange,
+		},
+		cli.BoolFlag{
+			Name:        "no-glob",
+			Usage:       "don't expand [1-10] and {a,b} patterns in the url (default: false)",
+			Destination: &noGlob,
+		},
+		cli.GenericFlag{
+			Name:  "extract",
+			Usage: "extract the archive once downloaded, into a directory named after it or --extract=<dir>",
+			Value: &extract,
+		},
+		cli.BoolFlag{
+			Name:        "delete-archive",
+			Usage:       "delete the archive once extracted (default: false)",
+			Destination: &deleteArchive,
+		},
+		cli.StringSliceFlag{
+			Name:  "tag",
+			Usage: "label the download with a tag to list, resume, stop or flush it along with others (can be repeated)",
+			Value: &dlTags,
+		},
+		cli.StringFlag{
+			Name:        "note",
+			Usage:       "add a free-form note to the download",
+			Destination: &dlNote,
+		},
 	}
 )
 
+// extractFlag is the value of --extract, which can be
+// set without a value to use the default directory.
+type extractFlag struct {
+	set bool
+	dir string
+}
+
+func (f *extractFlag) Set(s string) error {
+	switch s {
+	case "true":
+		f.set, f.dir = true, ""
+	case "false":
+		f.set, f.dir = false, ""
+	default:
+		f.set, f.dir = true, s
+	}
+	return nil
+}
+
+func (f *extractFlag) String() string {
+	return f.dir
+}
+
+// IsBoolFlag allows the flag to be set without a value.
+func (f *extractFlag) IsBoolFlag() bool {
+	return true
+}
+
 func download(ctx *cli.Context) (err error) {
 	url := ctx.Args().First()
 	if url == "" {
 		if ctx.Command.Name == "" {
 			return common.Help(ctx)
 		}
-		return common.PrintErrWithCmdHelp(
-			ctx,
-			errors.New("no url provided"),
-		)
 	} else if url == "help" {
 		return cli.ShowCommandHelp(ctx, ctx.Command.Name)
 	}
-	client, err := warpcli.NewClient()
+	if err = parseOutput(ctx); err != nil {
+		return usageErr(ctx, err)
+	}
+	if url == "" {
+		return usageErr(ctx, errors.New("no url provided"))
+	}
+	conflictPolicy, err := warplib.ParseConflictPolicy(onConflict)
 	if err != nil {
-		common.PrintRuntimeErr(ctx, "download", "new_client", err)
-		return
+		return usageErr(ctx, err)
+	}
+	if onConflict == "" {
+		// let daemon decide the policy
+		conflictPolicy = ""
+	}
+	var rangeStart, rangeEnd int64
+	if byteRange != "" {
+		rangeStart, rangeEnd, err = warplib.ParseByteRange(byteRange)
+		if err != nil {
+			return usageErr(ctx, err)
+		}
 	}
-	fmt.Println(">> Initiating a WARP download << ")
 	url = strings.TrimSpace(url)
 
 	var headers warplib.Headers
@@ -57,15 +145,80 @@ func download(ctx *cli.Context) (err error) {
 			Key: warplib.USER_AGENT_KEY, Value: getUserAgent(userAgent),
 		}}
 	}
+	sched, err := parseSchedule()
+	if err != nil {
+		return usageErr(ctx, err)
+	}
+	extractOpts, err := getExtractOpts()
+	if err != nil {
+		return usageErr(ctx, err)
+	}
+	if fileName == STREAM_FILE_NAME {
+		if sched != nil {
+			return usageErr(ctx, errors.New("a stream can't be scheduled"))
+		}
+		if extractOpts != nil {
+			return usageErr(ctx, errors.New("a stream can't be extracted"))
+		}
+		if len(dlTags) != 0 || dlNote != "" {
+			return usageErr(ctx, errors.New("a stream can't be tagged"))
+		}
+		if machineOutput() {
+			return usageErr(ctx, errors.New("a stream is written to stdout, it has no other output"))
+		}
+		return stream(ctx, url, headers, rangeStart, rangeEnd)
+	}
+	if !noGlob {
+		urls, err := warplib.ExpandUrl(url)
+		if err != nil {
+			return usageErr(ctx, err)
+		}
+		if len(urls) > 1 {
+			if sched != nil {
+				return usageErr(ctx, errors.New("downloads of a url pattern can't be scheduled"))
+			}
+			return downloadPattern(ctx, url, len(urls), headers, conflictPolicy, rangeStart, rangeEnd, extractOpts)
+		}
+	}
+	client, err := warpcli.NewClient()
+	if err != nil {
+		return runtimeErr(ctx, "download", "new_client", EXIT_DAEMON, err)
+	}
+	if !machineOutput() {
+		fmt.Println(">> Initiating a WARP download << ")
+	}
 	d, err := client.Download(url, fileName, dlPath, &warpcli.DownloadOpts{
 		ForceParts:     forceParts,
 		MaxConnections: int32(maxConns),
 		MaxSegments:    int32(maxParts),
 		Headers:        headers,
+		ConflictPolicy: conflictPolicy,
+		DecodeContent:  decode,
+		RaceTail:       raceTail,
+		RangeStart:     rangeStart,
+		RangeEnd:       rangeEnd,
+		Schedule:       sched,
+		Extract:        extractOpts,
+		Tags:           dlTags,
+		Note:           dlNote,
 	})
 	if err != nil {
-		common.PrintRuntimeErr(ctx, "info", "download", err)
-		return nil
+		return runtimeErr(ctx, "download", "client-download", EXIT_ERROR, err)
+	}
+	info := &downloadEvent{
+		DownloadId: d.DownloadId,
+		FileName:   d.FileName,
+		SavePath:   d.SavePath,
+		Size:       int64(d.ContentLength),
+		Downloaded: int64(d.Downloaded),
+		Scheduled:  d.Scheduled,
+	}
+	if machineOutput() {
+		if d.Scheduled {
+			writeInfo(info)
+			return nil
+		}
+		return follow(ctx, client, "download", info, extractOpts != nil)
 	}
 	txt := fmt.Sprintf(`
 Download Info
@@ -82,7 +235,49 @@ Max Connections`+"\t"+`: %d
 	if d.MaxSegments != 0 {
 		txt += fmt.Sprintf("Max Segments\t: %d\n", d.MaxSegments)
 	}
+	if d.Category != "" {
+		txt += fmt.Sprintf("Category\t: %s\n", d.Category)
+	}
+	if len(dlTags) != 0 {
+		txt += fmt.Sprintf("Tags\t\t: %s\n", strings.Join(dlTags, ", "))
+	}
+	if extractOpts != nil {
+		dir := extractOpts.Directory
+		if dir == "" {
+			dir = warplib.DefaultExtractDir(warplib.GetPath(d.DownloadDirectory, d.FileName))
+		}
+		txt += fmt.Sprintf("Extract To\t: %s\n", dir)
+	}
+	if d.Ranged {
+		txt += fmt.Sprintf("Byte Range\t: %d-%d\n", d.RangeStart, d.RangeStart+int64(d.ContentLength)-1)
+	}
+	if d.Scheduled {
+		txt += fmt.Sprintf("Schedule\t: %s\n", formatSchedule(sched))
+		fmt.Println(txt)
+		fmt.Printf("Download will be started by the daemon, use \"warpdl attach %s\" to follow it.\n", d.DownloadId)
+		return nil
+	}
 	fmt.Println(txt)
-	RegisterHandlers(client, int64(d.ContentLength))
-	return client.Listen()
+	return follow(ctx, client, "download", info, extractOpts != nil)
+}
+
+// getExtractOpts returns the extraction set by the flags,
+// nil if the download isn't extracted.
+func getExtractOpts() (*warplib.ExtractOpts, error) {
+	if !extract.set {
+		if deleteArchive {
+			return nil, errors.New("--delete-archive needs --extract")
+		}
+		return nil, nil
+	}
+	opts := &warplib.ExtractOpts{DeleteArchive: deleteArchive}
+	if extract.dir != "" {
+		// relative to the working directory like the download path
+		dir, err := filepath.Abs(extract.dir)
+		if err != nil {
+			return nil, err
+		}
+		opts.Directory = dir
+	}
+	return opts, nil
 }
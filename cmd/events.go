@@ -0,0 +1,243 @@
+package cmd
+
+import (
+	"encoding/json"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/urfave/cli"
+	"github.com/warpdl/warpdl/common"
+	"github.com/warpdl/warpdl/pkg/warpcli"
+	"github.com/warpdl/warpdl/pkg/warplib"
+)
+
+// DEF_PROGRESS_INTERVAL is the minimum interval
+// between two download_progress events.
+const DEF_PROGRESS_INTERVAL = 500 * time.Millisecond
+
+// EVENT_INFO is the first event of a download, other
+// events are named after their common.DownloadingAction.
+const EVENT_INFO = "info"
+
+// downloadEvent is a line of the NDJSON output of
+// the download, resume and attach commands.
+type downloadEvent struct {
+	Event      string    `json:"event"`
+	DownloadId string    `json:"download_id"`
+	Time       time.Time `json:"time"`
+	// Size of the download, -1 if it's unknown.
+	Size int64 `json:"size"`
+	// Downloaded bytes so far, including the bytes
+	// downloaded before the download was resumed.
+	Downloaded int64 `json:"downloaded"`
+	// FileName and SavePath are set by the info event.
+	FileName string `json:"file_name,omitempty"`
+	SavePath string `json:"save_path,omitempty"`
+	// Scheduled is set by the info event if the download waits
+	// for its schedule, no other event follows then.
+	Scheduled bool `json:"scheduled,omitempty"`
+	// Group is set by the info event if the download is a group,
+	// its downloads run in background and no other event follows.
+	Group bool `json:"group,omitempty"`
+	// Speed in bytes per second, set by download_progress.
+	Speed int64 `json:"speed,omitempty"`
+	// Connections is set by connections_tuned.
+	Connections int64 `json:"connections,omitempty"`
+	// ExtractSize is the size of the archive being
+	// extracted, set by extract_start.
+	ExtractSize int64 `json:"extract_size,omitempty"`
+	// ExtractPath is set by extract_complete.
+	ExtractPath string `json:"extract_path,omitempty"`
+	// Error is set by download_failed.
+	Error string `json:"error,omitempty"`
+}
+
+// eventWriter writes the events of a download as NDJSON.
+type eventWriter struct {
+	mu     sync.Mutex
+	enc    *json.Encoder
+	client *warpcli.Client
+	info   downloadEvent
+	// extract is set if the download is extracted once complete
+	extract bool
+	// downloaded bytes at the last progress event
+	last     int64
+	lastTime time.Time
+	// exit code once the download ended
+	code int
+}
+
+func newEventWriter(client *warpcli.Client, info *downloadEvent) *eventWriter {
+	w := &eventWriter{
+		enc:    json.NewEncoder(os.Stdout),
+		client: client,
+		info:   *info,
+		code:   EXIT_OK,
+	}
+	w.last, w.lastTime = info.Downloaded, time.Now()
+	return w
+}
+
+// write writes an event with the size and downloaded
+// bytes of the download, w.mu must be held.
+func (w *eventWriter) write(ev *downloadEvent) {
+	ev.DownloadId = w.info.DownloadId
+	ev.Time = time.Now()
+	ev.Size = w.info.Size
+	ev.Downloaded = w.info.Downloaded
+	_ = w.enc.Encode(ev)
+}
+
+// progress writes a download_progress event if force is set or
+// the last one is older than DEF_PROGRESS_INTERVAL, w.mu must be held.
+func (w *eventWriter) progress(force bool) {
+	now := time.Now()
+	elapsed := now.Sub(w.lastTime)
+	if !force && elapsed < DEF_PROGRESS_INTERVAL {
+		return
+	}
+	var speed int64
+	if elapsed > 0 {
+		speed = (w.info.Downloaded - w.last) * int64(time.Second) / int64(elapsed)
+	}
+	w.last, w.lastTime = w.info.Downloaded, now
+	w.write(&downloadEvent{
+		Event: string(common.DownloadProgress),
+		Speed: speed,
+	})
+}
+
+// end writes the final event of the download
+// and disconnects the client.
+func (w *eventWriter) end(ev *downloadEvent, code int) {
+	w.write(ev)
+	w.code = code
+	w.client.Disconnect()
+}
+
+func (w *eventWriter) handle(dr *common.DownloadingResponse) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	switch dr.Action {
+	case common.ResumeProgress:
+		// bytes downloaded before resuming don't count in the speed
+		w.info.Downloaded += dr.Value
+		w.last += dr.Value
+	case common.DownloadProgress:
+		w.info.Downloaded += dr.Value
+		w.progress(false)
+	case common.ConnectionsTuned:
+		w.write(&downloadEvent{
+			Event:       string(dr.Action),
+			Connections: dr.Value,
+		})
+	case common.DownloadComplete:
+		if dr.Hash != warplib.MAIN_HASH {
+			return nil
+		}
+		w.info.Downloaded = dr.Value
+		w.progress(true)
+		ev := &downloadEvent{Event: string(dr.Action)}
+		if w.extract {
+			// wait for the extraction
+			w.write(ev)
+			return nil
+		}
+		w.end(ev, EXIT_OK)
+	case common.DownloadStopped:
+		w.end(&downloadEvent{Event: string(dr.Action)}, EXIT_STOPPED)
+	case common.ExtractStart:
+		w.write(&downloadEvent{
+			Event:       string(dr.Action),
+			ExtractSize: dr.Value,
+		})
+	case common.ExtractComplete:
+		w.end(&downloadEvent{
+			Event:       string(dr.Action),
+			ExtractPath: dr.Path,
+		}, EXIT_OK)
+	}
+	return nil
+}
+
+// batchEvent is a line of the NDJSON output of the batch and
+// crawl commands, one per download once all of them ended.
+type batchEvent struct {
+	Event string    `json:"event"`
+	Time  time.Time `json:"time"`
+	// GroupId is the download id of the crawled directory.
+	GroupId string `json:"group_id,omitempty"`
+	common.BatchResult
+}
+
+// writeBatchEvents writes a download_complete or download_failed
+// event per download of the batch, groupId is set by crawl.
+func writeBatchEvents(res *common.BatchResponse, groupId string) {
+	enc := json.NewEncoder(os.Stdout)
+	for _, r := range res.Results {
+		ev := &batchEvent{
+			Event:       string(common.DownloadComplete),
+			Time:        time.Now(),
+			GroupId:     groupId,
+			BatchResult: r,
+		}
+		if r.Error != "" {
+			ev.Event = string(common.DownloadFailed)
+		}
+		_ = enc.Encode(ev)
+	}
+}
+
+// writeInfo writes the info event of a download.
+func writeInfo(info *downloadEvent) {
+	info.Event = EVENT_INFO
+	info.Time = time.Now()
+	_ = json.NewEncoder(os.Stdout).Encode(info)
+}
+
+// follow shows the progress of the download of info until it
+// ends, as progress bars or NDJSON events depending on the output.
+// extract reports whether the download is extracted once complete.
+func follow(ctx *cli.Context, client *warpcli.Client, cmd string, info *downloadEvent, extract bool) error {
+	if !machineOutput() {
+		var stopped bool
+		RegisterHandlers(client, info.Size, extract)
+		client.AddHandler(
+			common.UPDATE_DOWNLOADING,
+			warpcli.NewDownloadingHandler(common.DownloadStopped, func(*common.DownloadingResponse) error {
+				stopped = true
+				client.Disconnect()
+				return nil
+			}),
+		)
+		if err := client.Listen(); err != nil {
+			return runtimeErr(ctx, cmd, "listen", EXIT_FAILED, err)
+		}
+		if stopped {
+			return cli.NewExitError("", EXIT_STOPPED)
+		}
+		return nil
+	}
+	writeInfo(info)
+	w := newEventWriter(client, info)
+	w.extract = extract
+	client.AddHandler(
+		common.UPDATE_DOWNLOADING,
+		warpcli.NewDownloadingHandler("", w.handle),
+	)
+	err := client.Listen()
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if err != nil {
+		w.write(&downloadEvent{
+			Event: string(common.DownloadFailed),
+			Error: err.Error(),
+		})
+		return cli.NewExitError("", EXIT_FAILED)
+	}
+	if w.code != EXIT_OK {
+		return cli.NewExitError("", w.code)
+	}
+	return nil
+}
@@ -12,6 +12,7 @@ import (
 var (
 	forceFlush  bool
 	hashToFlush string
+	tagToFlush  string
 
 	flsFlags = []cli.Flag{
 		cli.BoolFlag{
@@ -24,6 +25,11 @@ var (
 			Usage:       "use this flag to flush a particular item (default: all)",
 			Destination: &hashToFlush,
 		},
+		cli.StringFlag{
+			Name:        "tag, t",
+			Usage:       "use this flag to flush the downloads with a tag (default: all)",
+			Destination: &tagToFlush,
+		},
 	}
 )
 
@@ -37,12 +43,28 @@ func flush(ctx *cli.Context) error {
 			errors.New("invalid amount of arguments"),
 		)
 	}
+	if hashToFlush != "" && tagToFlush != "" {
+		return common.PrintErrWithCmdHelp(
+			ctx,
+			errors.New("a hash and a tag can't be flushed at once"),
+		)
+	}
 	if !confirm(command("flush"), forceFlush) {
 		return nil
 	}
 	client, err := warpcli.NewClient()
 	if err != nil {
 		common.PrintRuntimeErr(ctx, "flush", "new_client", err)
+		return nil
+	}
+	if tagToFlush != "" {
+		r, err := client.FlushTag(tagToFlush)
+		if err != nil {
+			common.PrintRuntimeErr(ctx, "flush", "flush_tag", err)
+			return nil
+		}
+		fmt.Printf("Flushed %d downloads tagged %s\n", len(r.DownloadIds), tagToFlush)
+		return nil
 	}
 	_, err = client.Flush(hashToFlush)
 	if err != nil {
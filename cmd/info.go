@@ -6,7 +6,6 @@ import (
 	"net/http"
 
 	"github.com/urfave/cli"
-	"github.com/warpdl/warpdl/cmd/common"
 	"github.com/warpdl/warpdl/pkg/warplib"
 )
 
@@ -24,15 +23,18 @@ var (
 
 func info(ctx *cli.Context) error {
 	url := ctx.Args().First()
-	if url == "" {
-		return common.PrintErrWithCmdHelp(
-			ctx,
-			errors.New("no url provided"),
-		)
-	} else if url == "help" {
+	if url == "help" {
 		return cli.ShowCommandHelp(ctx, ctx.Command.Name)
 	}
-	fmt.Printf("%s: fetching details, please wait...\n", ctx.App.HelpName)
+	if err := parseOutput(ctx); err != nil {
+		return usageErr(ctx, err)
+	}
+	if url == "" {
+		return usageErr(ctx, errors.New("no url provided"))
+	}
+	if !machineOutput() {
+		fmt.Printf("%s: fetching details, please wait...\n", ctx.App.HelpName)
+	}
 	var headers warplib.Headers
 	if userAgent != "" {
 		headers = warplib.Headers{{
@@ -48,7 +50,10 @@ func info(ctx *cli.Context) error {
 		},
 	)
 	if err != nil {
-		common.PrintRuntimeErr(ctx, "info", "new_downloader", err)
+		return runtimeErr(ctx, "info", "new_downloader", EXIT_ERROR, err)
+	}
+	if machineOutput() {
+		printJSON(newFileInfo(url, d))
 		return nil
 	}
 	fName := d.GetFileName()
@@ -60,5 +65,65 @@ File Info
 Name`+"\t"+`: %s
 Size`+"\t"+`: %s
 `, fName, d.GetContentLengthAsString())
+	if p := d.GetProbeResult(); p != nil {
+		ranges := "not supported"
+		if p.RangesSupported {
+			ranges = "supported"
+		}
+		fmt.Printf("Probe\t: %s %d\nRanges\t: %s\n", p.Method, p.StatusCode, ranges)
+		if p.Speed != 0 {
+			fmt.Printf("Speed\t: %s/s (sample)\n", warplib.ContentLength(p.Speed))
+		}
+	}
+	if enc := d.GetContentEncoding(); enc != "" {
+		fmt.Printf("Encoding\t: %s\n", enc)
+	}
+	if chain := d.GetRedirectChain(); len(chain) != 0 {
+		fmt.Printf("Final URL\t: %s\nRedirects\t:\n", d.GetEffectiveUrl())
+		for i, u := range chain {
+			fmt.Printf("  %d. %s\n", i+1, u)
+		}
+	}
 	return nil
 }
+
+// fileInfo is the JSON and NDJSON output of info.
+type fileInfo struct {
+	Url      string `json:"url"`
+	FileName string `json:"file_name"`
+	// Size of the file, -1 if it's unknown.
+	Size int64 `json:"size"`
+	// EffectiveUrl and Redirects are set if
+	// the url was redirected.
+	EffectiveUrl string   `json:"effective_url,omitempty"`
+	Redirects    []string `json:"redirects,omitempty"`
+	Encoding     string   `json:"encoding,omitempty"`
+	// ProbeMethod and StatusCode are the request
+	// and response of the probe of the url.
+	ProbeMethod     string `json:"probe_method,omitempty"`
+	StatusCode      int    `json:"status_code,omitempty"`
+	RangesSupported bool   `json:"ranges_supported"`
+	// Speed is the sample speed of the probe in
+	// bytes per second, 0 if it wasn't measured.
+	Speed int64 `json:"speed,omitempty"`
+}
+
+func newFileInfo(url string, d *warplib.Downloader) *fileInfo {
+	fi := &fileInfo{
+		Url:      url,
+		FileName: d.GetFileName(),
+		Size:     d.GetContentLengthAsInt(),
+		Encoding: d.GetContentEncoding(),
+	}
+	if chain := d.GetRedirectChain(); len(chain) != 0 {
+		fi.EffectiveUrl = d.GetEffectiveUrl()
+		fi.Redirects = chain
+	}
+	if p := d.GetProbeResult(); p != nil {
+		fi.ProbeMethod = p.Method
+		fi.StatusCode = p.StatusCode
+		fi.RangesSupported = p.RangesSupported
+		fi.Speed = p.Speed
+	}
+	return fi
+}
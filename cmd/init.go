@@ -2,5 +2,17 @@ package cmd
 
 func init() {
 	rsFlags = append(rsFlags, infoFlags...)
+	topFlags = append(topFlags, rsFlags...)
+	dlFlags = append(dlFlags, schedFlags...)
 	dlFlags = append(dlFlags, rsFlags...)
+	scheduleFlags = append(scheduleFlags, schedFlags...)
+	batchFlags = append(batchFlags, rsFlags...)
+	crawlFlags = append(crawlFlags, rsFlags...)
+	rsFlags = append(rsFlags, rsOnlyFlags...)
+	dlFlags = append(dlFlags, outputFlag)
+	rsFlags = append(rsFlags, outputFlag)
+	lsFlags = append(lsFlags, outputFlag)
+	infoFlags = append(infoFlags, outputFlag)
+	batchFlags = append(batchFlags, outputFlag)
+	crawlFlags = append(crawlFlags, outputFlag)
 }
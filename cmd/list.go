@@ -1,11 +1,19 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
 
+	"github.com/mattn/go-runewidth"
 	"github.com/urfave/cli"
-	"github.com/warpdl/warpdl/cmd/common"
+	"github.com/vbauerster/mpb/v8/cwriter"
+	"github.com/warpdl/warpdl/common"
 	"github.com/warpdl/warpdl/pkg/warpcli"
+	"github.com/warpdl/warpdl/pkg/warplib"
 )
 
 var (
@@ -13,6 +21,20 @@ var (
 	showCompleted bool
 	showPending   bool
 	showAll       bool
+	listCategory  string
+	listTag       string
+	listStates    = &cli.StringSlice{}
+	listHost      string
+	listName      string
+	listNameRegex string
+	listAfter     string
+	listBefore    string
+	listMinSize   string
+	listMaxSize   string
+	listSort      string
+	listReverse   bool
+	listLimit     int
+	listPage      int
 
 	lsFlags = []cli.Flag{
 		cli.BoolFlag{
@@ -35,82 +57,384 @@ var (
 			Usage:       "use this flag to list hidden downloads (default: false)",
 			Destination: &showHidden,
 		},
+		cli.StringFlag{
+			Name:        "category",
+			Usage:       "only list the downloads of the category",
+			Destination: &listCategory,
+		},
+		cli.StringFlag{
+			Name:        "tag",
+			Usage:       "only list the downloads with the tag",
+			Destination: &listTag,
+		},
+		cli.StringSliceFlag{
+			Name:  "state, s",
+			Usage: "only list the downloads in the state, one of " + joinStates() + ", can be repeated",
+			Value: listStates,
+		},
+		cli.StringFlag{
+			Name:        "host",
+			Usage:       "only list the downloads from the host or its subdomains",
+			Destination: &listHost,
+		},
+		cli.StringFlag{
+			Name:        "name",
+			Usage:       "only list the downloads whose name contains the text",
+			Destination: &listName,
+		},
+		cli.StringFlag{
+			Name:        "name-regex",
+			Usage:       "only list the downloads whose name matches the regular expression",
+			Destination: &listNameRegex,
+		},
+		cli.StringFlag{
+			Name:        "added-after",
+			Usage:       "only list the downloads added after the date (2006-01-02 [15:04]) or duration ago (24h, 7d)",
+			Destination: &listAfter,
+		},
+		cli.StringFlag{
+			Name:        "added-before",
+			Usage:       "only list the downloads added before the date (2006-01-02 [15:04]) or duration ago (24h, 7d)",
+			Destination: &listBefore,
+		},
+		cli.StringFlag{
+			Name:        "min-size",
+			Usage:       "only list the downloads of at least the size, such as 100MB",
+			Destination: &listMinSize,
+		},
+		cli.StringFlag{
+			Name:        "max-size",
+			Usage:       "only list the downloads of at most the size, such as 2GB",
+			Destination: &listMaxSize,
+		},
+		cli.StringFlag{
+			Name:        "sort",
+			Usage:       "sort the downloads by date, name, size, progress or speed",
+			Value:       string(common.SortByDate),
+			Destination: &listSort,
+		},
+		cli.BoolFlag{
+			Name:        "reverse, r",
+			Usage:       "reverse the sort order",
+			Destination: &listReverse,
+		},
+		cli.IntFlag{
+			Name:        "limit, n",
+			Usage:       "list at most this many downloads, 0 lists all of them",
+			Destination: &listLimit,
+		},
+		cli.IntFlag{
+			Name:        "page",
+			Usage:       "the page of downloads to list when --limit is set",
+			Value:       1,
+			Destination: &listPage,
+		},
 	}
 )
 
+func joinStates() string {
+	states := make([]string, len(warplib.ItemStates))
+	for i, s := range warplib.ItemStates {
+		states[i] = string(s)
+	}
+	return strings.Join(states, ", ")
+}
+
+func listOpts() (*warpcli.ListOpts, error) {
+	opts := &warpcli.ListOpts{
+		ShowCompleted: showCompleted || showAll,
+		ShowPending:   showPending || showAll,
+		ShowHidden:    showHidden,
+		Category:      listCategory,
+		Tag:           listTag,
+		Host:          listHost,
+		Name:          listName,
+		NameRegex:     listNameRegex,
+		SortBy:        common.ListSort(listSort),
+		Reverse:       listReverse,
+		Limit:         listLimit,
+	}
+	for _, s := range listStates.Value() {
+		state, err := warplib.ParseItemState(s)
+		if err != nil {
+			return nil, err
+		}
+		opts.States = append(opts.States, state)
+	}
+	if len(opts.States) != 0 {
+		// the states select the downloads on their own
+		opts.ShowCompleted, opts.ShowPending = true, true
+	}
+	if listLimit < 0 || listPage < 1 {
+		return nil, errors.New("limit can't be negative and page starts at 1")
+	}
+	opts.Offset = (listPage - 1) * listLimit
+	var err error
+	if opts.AddedAfter, err = parseListTime(listAfter); err != nil {
+		return nil, err
+	}
+	if opts.AddedBefore, err = parseListTime(listBefore); err != nil {
+		return nil, err
+	}
+	if listMinSize != "" {
+		if opts.MinSize, err = warplib.ParseSize(listMinSize); err != nil {
+			return nil, err
+		}
+	}
+	if listMaxSize != "" {
+		if opts.MaxSize, err = warplib.ParseSize(listMaxSize); err != nil {
+			return nil, err
+		}
+	}
+	return opts, nil
+}
+
+// parseListTime parses a date, or a duration such as
+// "24h" or "7d" which is that long ago.
+func parseListTime(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		if n, err := strconv.Atoi(days); err == nil && n >= 0 {
+			return time.Now().AddDate(0, 0, -n), nil
+		}
+	}
+	if d, err := time.ParseDuration(s); err == nil {
+		return time.Now().Add(-d), nil
+	}
+	for _, layout := range []string{"2006-01-02", "2006-01-02 15:04", time.RFC3339} {
+		if t, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid date or duration %q", s)
+}
+
 func list(ctx *cli.Context) error {
 	if ctx.Args().First() == "help" {
 		return cli.ShowCommandHelp(ctx, ctx.Command.Name)
 	}
+	if err := parseOutput(ctx); err != nil {
+		return usageErr(ctx, err)
+	}
+	opts, err := listOpts()
+	if err != nil {
+		return usageErr(ctx, err)
+	}
 	client, err := warpcli.NewClient()
 	if err != nil {
-		common.PrintRuntimeErr(ctx, "list", "new_client", err)
-		return nil
+		return runtimeErr(ctx, "list", "new_client", EXIT_DAEMON, err)
 	}
-	l, err := client.List(&warpcli.ListOpts{
-		ShowCompleted: showCompleted || showAll,
-		ShowPending:   showPending || showAll,
-	})
+	l, err := client.List(opts)
 	if err != nil {
-		common.PrintRuntimeErr(ctx, "list", "get_list", err)
+		return runtimeErr(ctx, "list", "get_list", EXIT_ERROR, err)
+	}
+	if machineOutput() {
+		printList(l)
 		return nil
 	}
-	fback := func() error {
+	if len(l.Items) == 0 {
 		fmt.Println("warp: no downloads found")
 		return nil
 	}
-	if len(l.Items) == 0 {
-		return fback()
-	}
-	txt := "Here are your downloads:"
-	txt += "\n\n------------------------------------------------------"
-	txt += "\n|Num|\t         Name         | Unique Hash | Status |"
-	txt += "\n|---|-------------------------|-------------|--------|"
-	var i int
-	for _, item := range l.Items {
-		if !showHidden && (item.Hidden || item.Children) {
-			continue
+	rows := [][]string{{"#", "Name", "Hash", "Size", "Progress", "Speed", "ETA", "State"}}
+	for i, item := range l.Items {
+		stats := l.Stats[item.Hash]
+		if stats == nil {
+			stats = &common.ItemStats{State: item.State()}
 		}
-		i++
-		name := item.Name
-		n := len(name)
-		switch {
-		case n > 23:
-			name = name[:20] + "..."
-		case n < 23:
-			name = beaut(name, 23)
-		}
-		perc := fmt.Sprintf(`%d%%`, item.GetPercentage())
-		txt += fmt.Sprintf("\n| %d | %s |   %s  |  %s  |", i, name, item.Hash, beaut(perc, 4))
+		rows = append(rows, []string{
+			strconv.Itoa(opts.Offset + i + 1),
+			item.Name,
+			item.Hash,
+			listSize(item),
+			listProgress(item),
+			listSpeed(stats.Speed),
+			listEta(item, stats.Speed),
+			string(stats.State),
+		})
 	}
-	if i == 0 {
-		return fback()
+	fmt.Println("Here are your downloads:")
+	fmt.Println()
+	printTable(rows, 1, termWidth())
+	if opts.Limit > 0 {
+		pages := (l.Total + opts.Limit - 1) / opts.Limit
+		fmt.Printf("\nPage %d of %d, %d downloads in total\n", listPage, pages, l.Total)
 	}
-	txt += "\n------------------------------------------------------"
-	fmt.Println(txt)
 	return nil
 }
 
-func beaut(s string, n int) (b string) {
-	n1 := len(s)
-	x := n - n1
-	x1 := x / 2
-	w := string(
-		replic(' ', x1),
-	)
-	b = w
-	b += s
-	b += w
-	if x%2 != 0 {
-		b += " "
-	}
-	return
+// listEntry is a download in the JSON and NDJSON outputs of list.
+type listEntry struct {
+	Hash string `json:"hash"`
+	Name string `json:"name"`
+	Url  string `json:"url"`
+	// SavePath is the path of the downloaded file.
+	SavePath string `json:"save_path"`
+	// Size of the download, -1 if it's unknown.
+	Size       int64 `json:"size"`
+	Downloaded int64 `json:"downloaded"`
+	// Progress is the downloaded percentage, 0 if the size is unknown.
+	Progress int64 `json:"progress"`
+	// Speed in bytes per second.
+	Speed int64 `json:"speed"`
+	// Eta is the remaining time in seconds,
+	// -1 if the download isn't running.
+	Eta        int64             `json:"eta"`
+	State      warplib.ItemState `json:"state"`
+	DateAdded  time.Time         `json:"date_added"`
+	Group      bool              `json:"group,omitempty"`
+	ParentHash string            `json:"parent_hash,omitempty"`
+	Category   string            `json:"category,omitempty"`
+	Tags       []string          `json:"tags,omitempty"`
+	Note       string            `json:"note,omitempty"`
+}
+
+// listDocument is the JSON output of list, the NDJSON
+// output has a listEntry per line instead.
+type listDocument struct {
+	// Total is the number of matching downloads
+	// before --limit and --page are applied.
+	Total     int          `json:"total"`
+	Downloads []*listEntry `json:"downloads"`
+}
+
+func printList(l *common.ListResponse) {
+	entries := make([]*listEntry, len(l.Items))
+	for i, item := range l.Items {
+		stats := l.Stats[item.Hash]
+		if stats == nil {
+			stats = &common.ItemStats{State: item.State()}
+		}
+		eta := int64(-1)
+		if stats.Speed > 0 && item.TotalSize > 0 {
+			eta = max(int64(item.TotalSize-item.Downloaded), 0) / stats.Speed
+		}
+		entries[i] = &listEntry{
+			Hash:       item.Hash,
+			Name:       item.Name,
+			Url:        item.Url,
+			SavePath:   item.GetAbsolutePath(),
+			Size:       int64(item.TotalSize),
+			Downloaded: int64(item.Downloaded),
+			Progress:   item.GetPercentage(),
+			Speed:      stats.Speed,
+			Eta:        eta,
+			State:      stats.State,
+			DateAdded:  item.DateAdded,
+			Group:      item.Group,
+			ParentHash: item.ParentHash,
+			Category:   item.Category,
+			Tags:       item.Tags,
+			Note:       item.Note,
+		}
+	}
+	if output == OUTPUT_NDJSON {
+		for _, e := range entries {
+			printJSON(e)
+		}
+		return
+	}
+	printJSON(&listDocument{Total: l.Total, Downloads: entries})
+}
+
+// termWidth returns the width of the terminal,
+// 80 if the output isn't a terminal.
+func termWidth() int {
+	w, _, err := cwriter.GetSize(int(os.Stdout.Fd()))
+	if err != nil || w <= 0 {
+		return 80
+	}
+	return w
+}
+
+// printTable prints the rows formatted by formatTable.
+func printTable(rows [][]string, flex, width int) {
+	for _, line := range formatTable(rows, flex, width) {
+		fmt.Println(line)
+	}
+}
+
+// formatTable formats the rows as columns, the first row is
+// the header. The cells of the flex column are truncated so
+// that the table fits in width.
+func formatTable(rows [][]string, flex, width int) []string {
+	const gap = 2
+	widths := make([]int, len(rows[0]))
+	for _, row := range rows {
+		for i, cell := range row {
+			widths[i] = max(widths[i], runewidth.StringWidth(cell))
+		}
+	}
+	total := gap * (len(widths) - 1)
+	for _, w := range widths {
+		total += w
+	}
+	if total > width {
+		widths[flex] = max(widths[flex]-(total-width), runewidth.StringWidth(rows[0][flex]), 8)
+	}
+	lines := make([]string, 0, len(rows))
+	var b strings.Builder
+	for _, row := range rows {
+		b.Reset()
+		for i, cell := range row {
+			if i != 0 {
+				b.WriteString(strings.Repeat(" ", gap))
+			}
+			cell = runewidth.Truncate(cell, widths[i], "…")
+			if i == len(row)-1 {
+				b.WriteString(cell)
+				continue
+			}
+			b.WriteString(runewidth.FillRight(cell, widths[i]))
+		}
+		lines = append(lines, b.String())
+	}
+	return lines
+}
+
+func listSize(item *warplib.Item) string {
+	if item.TotalSize.IsUnknown() {
+		return "?"
+	}
+	return shortSize(int64(item.TotalSize))
+}
+
+func listProgress(item *warplib.Item) string {
+	if item.TotalSize.IsUnknown() {
+		return shortSize(int64(item.Downloaded))
+	}
+	return fmt.Sprintf("%d%%", item.GetPercentage())
 }
 
-func replic[aT any](v aT, n int) []aT {
-	a := make([]aT, n)
-	for i := range a {
-		a[i] = v
+func listSpeed(speed int64) string {
+	if speed <= 0 {
+		return "-"
+	}
+	return shortSize(speed) + "/s"
+}
+
+func listEta(item *warplib.Item, speed int64) string {
+	if speed <= 0 || item.TotalSize <= 0 {
+		return "-"
+	}
+	left := max(int64(item.TotalSize-item.Downloaded), 0)
+	eta := time.Duration(left/speed) * time.Second
+	return eta.String()
+}
+
+// shortSize formats n in its largest unit
+// with one decimal, such as "2.9MB".
+func shortSize(n int64) string {
+	units := []struct {
+		val  int64
+		unit string
+	}{{warplib.TB, "TB"}, {warplib.GB, "GB"}, {warplib.MB, "MB"}, {warplib.KB, "KB"}}
+	for _, u := range units {
+		if n >= u.val {
+			return strconv.FormatFloat(float64(n)/float64(u.val), 'f', 1, 64) + u.unit
+		}
 	}
-	return a
+	return strconv.FormatInt(n, 10) + "B"
 }
@@ -0,0 +1,119 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/urfave/cli"
+	cmdCommon "github.com/warpdl/warpdl/cmd/common"
+)
+
+// Formats of the --output flag.
+const (
+	OUTPUT_TABLE  = "table"
+	OUTPUT_JSON   = "json"
+	OUTPUT_NDJSON = "ndjson"
+)
+
+// Exit codes of the commands supporting --output, scripts
+// can rely on them whatever the output format is.
+const (
+	EXIT_OK = 0
+	// EXIT_ERROR is used if the daemon rejected the request.
+	EXIT_ERROR = 1
+	// EXIT_USAGE is used for invalid arguments and flags.
+	EXIT_USAGE = 2
+	// EXIT_DAEMON is used if the daemon can't be reached.
+	EXIT_DAEMON = 3
+	// EXIT_STOPPED is used if the download was stopped.
+	EXIT_STOPPED = 4
+	// EXIT_FAILED is used if the download failed.
+	EXIT_FAILED = 5
+)
+
+var (
+	// output is the format of the running command,
+	// set by parseOutput.
+	output = OUTPUT_TABLE
+
+	outputFlag = cli.StringFlag{
+		Name:   "output",
+		Usage:  "output format: table, json or ndjson (default: table)",
+		EnvVar: "WARP_OUTPUT",
+	}
+)
+
+// parseOutput sets the output format from the flag of the
+// command, or the global flag if the command doesn't set it.
+func parseOutput(ctx *cli.Context) error {
+	out := ctx.String("output")
+	if !ctx.IsSet("output") && ctx.GlobalIsSet("output") {
+		out = ctx.GlobalString("output")
+	}
+	switch out {
+	case "":
+		output = OUTPUT_TABLE
+	case OUTPUT_TABLE, OUTPUT_JSON, OUTPUT_NDJSON:
+		output = out
+	default:
+		output = OUTPUT_TABLE
+		return fmt.Errorf("invalid output format %q, use table, json or ndjson", out)
+	}
+	return nil
+}
+
+// machineOutput reports whether the output is JSON or NDJSON.
+func machineOutput() bool {
+	return output != OUTPUT_TABLE
+}
+
+// printJSON writes v to stdout, indented for the JSON
+// output and on a single line for NDJSON.
+func printJSON(v any) {
+	enc := json.NewEncoder(os.Stdout)
+	if output == OUTPUT_JSON {
+		enc.SetIndent("", "  ")
+	}
+	_ = enc.Encode(v)
+}
+
+// outputError is written to stderr on errors
+// with the JSON and NDJSON outputs.
+type outputError struct {
+	Error    string `json:"error"`
+	Command  string `json:"command"`
+	Action   string `json:"action"`
+	ExitCode int    `json:"exit_code"`
+}
+
+// runtimeErr prints the error of the action of cmd
+// and returns an error exiting with code.
+func runtimeErr(ctx *cli.Context, cmd, action string, code int, err error) error {
+	if machineOutput() {
+		b, _ := json.Marshal(&outputError{
+			Error:    err.Error(),
+			Command:  cmd,
+			Action:   action,
+			ExitCode: code,
+		})
+		fmt.Fprintln(os.Stderr, string(b))
+	} else {
+		cmdCommon.PrintRuntimeErr(ctx, cmd, action, err)
+	}
+	return cli.NewExitError("", code)
+}
+
+// usageErr prints the error along with the help of the
+// command and returns an error exiting with EXIT_USAGE.
+func usageErr(ctx *cli.Context, err error) error {
+	if machineOutput() {
+		cmd := ctx.Command.Name
+		if cmd == "" {
+			cmd = "download"
+		}
+		return runtimeErr(ctx, cmd, "usage", EXIT_USAGE, err)
+	}
+	_ = cmdCommon.PrintErrWithCmdHelp(ctx, err)
+	return cli.NewExitError("", EXIT_USAGE)
+}
@@ -15,6 +15,10 @@ var (
 	maxConns   int
 	forceParts bool
 	timeTaken  bool
+	newUrl     string
+	refreshUrl bool
+	raceTail   bool
+	resumeTag  string
 
 	rsFlags = []cli.Flag{
 		cli.IntFlag{
@@ -37,26 +41,55 @@ var (
 			EnvVar:      "WARP_FORCE_SEGMENTS",
 			Destination: &forceParts,
 		},
+		cli.BoolFlag{
+			Name:        "race-tail",
+			Usage:       "download the last segments in duplicate over idle connections and keep the faster copy (default: false)",
+			EnvVar:      "WARP_RACE_TAIL",
+			Destination: &raceTail,
+		},
 		cli.BoolFlag{
 			Name:        "time-taken, e",
 			Destination: &timeTaken,
 			Hidden:      true,
 		},
 	}
+
+	// flags specific to resume command
+	rsOnlyFlags = []cli.Flag{
+		cli.StringFlag{
+			Name:        "url, u",
+			Usage:       "replace the download url (e.g. an expired link) before resuming",
+			Destination: &newUrl,
+		},
+		cli.BoolFlag{
+			Name:        "refresh-url",
+			Usage:       "re-extract the download url using the matching extension before resuming",
+			Destination: &refreshUrl,
+		},
+		cli.StringFlag{
+			Name:        "tag, t",
+			Usage:       "resume all the downloads with a tag in background",
+			Destination: &resumeTag,
+		},
+	}
 )
 
 func resume(ctx *cli.Context) (err error) {
 	hash := ctx.Args().First()
+	if hash == "help" {
+		return cli.ShowCommandHelp(ctx, ctx.Command.Name)
+	}
+	if err = parseOutput(ctx); err != nil {
+		return usageErr(ctx, err)
+	}
+	if resumeTag != "" {
+		return resumeTagged(ctx)
+	}
 	if hash == "" {
 		if ctx.Command.Name == "" {
 			return common.Help(ctx)
 		}
-		return common.PrintErrWithCmdHelp(
-			ctx,
-			errors.New("no hash provided"),
-		)
-	} else if hash == "help" {
-		return cli.ShowCommandHelp(ctx, ctx.Command.Name)
+		return usageErr(ctx, errors.New("no hash provided"))
 	}
 	var headers warplib.Headers
 	if userAgent != "" {
@@ -66,18 +99,39 @@ func resume(ctx *cli.Context) (err error) {
 	}
 	client, err := warpcli.NewClient()
 	if err != nil {
-		common.PrintRuntimeErr(ctx, "resume", "new_client", err)
-		return
+		return runtimeErr(ctx, "resume", "new_client", EXIT_DAEMON, err)
+	}
+	if !machineOutput() {
+		fmt.Println(">> Initiating a WARP download << ")
 	}
-	fmt.Println(">> Initiating a WARP download << ")
 	r, err := client.Resume(hash, &warpcli.ResumeOpts{
 		ForceParts:     forceParts,
 		MaxConnections: int32(maxConns),
 		MaxSegments:    int32(maxParts),
+		RaceTail:       raceTail,
 		Headers:        headers,
+		Url:            newUrl,
+		RefreshUrl:     refreshUrl,
 	})
 	if err != nil {
-		common.PrintRuntimeErr(ctx, "resume", "client-resume", err)
+		return runtimeErr(ctx, "resume", "client-resume", EXIT_ERROR, err)
+	}
+	info := &downloadEvent{
+		DownloadId: hash,
+		FileName:   r.FileName,
+		SavePath:   r.SavePath,
+		Size:       int64(r.ContentLength),
+		Group:      r.Group,
+	}
+	if machineOutput() {
+		if r.Group {
+			writeInfo(info)
+			return nil
+		}
+		return follow(ctx, client, "resume", info, false)
+	}
+	if r.Group {
+		fmt.Printf("Resuming the downloads of %s in background\n", r.FileName)
 		return nil
 	}
 
@@ -103,6 +157,49 @@ Max Connections`+"\t"+`: %d
 		txt += fmt.Sprintf("Max Segments\t: %d\n", r.MaxSegments)
 	}
 	fmt.Println(txt)
-	RegisterHandlers(client, int64(r.ContentLength))
-	return client.Listen()
+	return follow(ctx, client, "resume", info, false)
+}
+
+// resumeTagged resumes the downloads with the tag set by the flag,
+// they aren't followed as they run in background.
+func resumeTagged(ctx *cli.Context) error {
+	if ctx.NArg() != 0 {
+		return usageErr(ctx, errors.New("a hash and a tag can't be resumed at once"))
+	}
+	if newUrl != "" || refreshUrl {
+		return usageErr(ctx, errors.New("urls of tagged downloads can't be replaced"))
+	}
+	var headers warplib.Headers
+	if userAgent != "" {
+		headers = warplib.Headers{{
+			Key: warplib.USER_AGENT_KEY, Value: getUserAgent(userAgent),
+		}}
+	}
+	client, err := warpcli.NewClient()
+	if err != nil {
+		return runtimeErr(ctx, "resume", "new_client", EXIT_DAEMON, err)
+	}
+	r, err := client.ResumeTag(resumeTag, &warpcli.ResumeOpts{
+		ForceParts:     forceParts,
+		MaxConnections: int32(maxConns),
+		MaxSegments:    int32(maxParts),
+		RaceTail:       raceTail,
+		Headers:        headers,
+	})
+	if err != nil {
+		return runtimeErr(ctx, "resume", "client-resume-tag", EXIT_ERROR, err)
+	}
+	if machineOutput() {
+		printJSON(r)
+		return nil
+	}
+	if len(r.DownloadIds) == 0 {
+		fmt.Printf("warp: no downloads tagged %s to resume\n", resumeTag)
+		return nil
+	}
+	fmt.Printf("Resuming %d downloads tagged %s in background, use \"warpdl attach <hash>\" to follow one:\n", len(r.DownloadIds), resumeTag)
+	for _, hash := range r.DownloadIds {
+		fmt.Println(" ", hash)
+	}
+	return nil
 }
@@ -0,0 +1,140 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/urfave/cli"
+	"github.com/warpdl/warpdl/cmd/common"
+	"github.com/warpdl/warpdl/pkg/warpcli"
+	"github.com/warpdl/warpdl/pkg/warplib"
+)
+
+var (
+	startAt       string
+	timeWindows   cli.StringSlice
+	clearSchedule bool
+
+	schedFlags = []cli.Flag{
+		cli.StringFlag{
+			Name:        "at",
+			Usage:       "start the download at a time, e.g. 02:00 or \"2024-01-31 02:00\"",
+			Destination: &startAt,
+		},
+		cli.StringSliceFlag{
+			Name:  "window",
+			Usage: "only download during a daily time window with an optional speed limit, e.g. 22:00-06:00 or 22:00-06:00@2MB (can be repeated)",
+			Value: &timeWindows,
+		},
+	}
+
+	scheduleFlags = []cli.Flag{
+		cli.BoolFlag{
+			Name:        "clear",
+			Usage:       "remove the schedule of the download (default: false)",
+			Destination: &clearSchedule,
+		},
+	}
+)
+
+// parseSchedule returns the schedule set by the flags,
+// nil if none of them is set.
+func parseSchedule() (*warplib.Schedule, error) {
+	if startAt == "" && len(timeWindows) == 0 {
+		return nil, nil
+	}
+	var (
+		s   warplib.Schedule
+		err error
+	)
+	if startAt != "" {
+		s.StartAt, err = warplib.ParseStartTime(startAt, time.Now())
+		if err != nil {
+			return nil, err
+		}
+	}
+	for _, w := range timeWindows {
+		tw, err := warplib.ParseTimeWindow(w)
+		if err != nil {
+			return nil, err
+		}
+		s.Windows = append(s.Windows, tw)
+	}
+	return &s, nil
+}
+
+func formatSchedule(s *warplib.Schedule) string {
+	var parts []string
+	if !s.StartAt.IsZero() {
+		parts = append(parts, "starts at "+s.StartAt.Format("2006-01-02 15:04"))
+	}
+	if len(s.Windows) != 0 {
+		windows := make([]string, len(s.Windows))
+		for i, w := range s.Windows {
+			windows[i] = w.String()
+		}
+		parts = append(parts, "runs during "+strings.Join(windows, ", "))
+	}
+	return strings.Join(parts, ", ")
+}
+
+func schedule(ctx *cli.Context) error {
+	hash := ctx.Args().First()
+	if hash == "help" {
+		return cli.ShowCommandHelp(ctx, ctx.Command.Name)
+	}
+	sched, err := parseSchedule()
+	if err != nil {
+		return common.PrintErrWithCmdHelp(ctx, err)
+	}
+	client, err := warpcli.NewClient()
+	if err != nil {
+		common.PrintRuntimeErr(ctx, "schedule", "new_client", err)
+		return nil
+	}
+	if hash == "" {
+		return listSchedules(ctx, client)
+	}
+	if sched == nil && !clearSchedule {
+		return common.PrintErrWithCmdHelp(ctx, errors.New("no schedule provided, use --at, --window or --clear"))
+	}
+	r, err := client.Schedule(hash, sched)
+	if err != nil {
+		common.PrintRuntimeErr(ctx, "schedule", "client-schedule", err)
+		return nil
+	}
+	if r.Schedule == nil {
+		fmt.Printf("Removed the schedule of %s\n", r.FileName)
+		return nil
+	}
+	fmt.Printf("Scheduled %s: %s\n", r.FileName, formatSchedule(r.Schedule))
+	return nil
+}
+
+func listSchedules(ctx *cli.Context, client *warpcli.Client) error {
+	l, err := client.List(&warpcli.ListOpts{
+		ShowPending: true,
+		ShowHidden:  true,
+	})
+	if err != nil {
+		common.PrintRuntimeErr(ctx, "schedule", "get_list", err)
+		return nil
+	}
+	var n int
+	for _, item := range l.Items {
+		if item.Schedule == nil {
+			continue
+		}
+		if n == 0 {
+			fmt.Println("Scheduled downloads:")
+		}
+		n++
+		fmt.Printf("%3d. %s (%s): %s\n", n, item.Name, item.Hash, formatSchedule(item.Schedule))
+	}
+	if n == 0 {
+		fmt.Println("warp: no scheduled downloads found")
+	}
+	return nil
+}
@@ -9,9 +9,21 @@ import (
 	"github.com/warpdl/warpdl/pkg/warpcli"
 )
 
+var (
+	tagToStop string
+
+	stopFlags = []cli.Flag{
+		cli.StringFlag{
+			Name:        "tag, t",
+			Usage:       "stop all the running downloads with a tag",
+			Destination: &tagToStop,
+		},
+	}
+)
+
 func stop(ctx *cli.Context) (err error) {
 	hash := ctx.Args().First()
-	if hash == "" {
+	if hash == "" && tagToStop == "" {
 		if ctx.Command.Name == "" {
 			return common.Help(ctx)
 		}
@@ -21,12 +33,26 @@ func stop(ctx *cli.Context) (err error) {
 		)
 	} else if hash == "help" {
 		return cli.ShowCommandHelp(ctx, ctx.Command.Name)
+	} else if hash != "" && tagToStop != "" {
+		return common.PrintErrWithCmdHelp(
+			ctx,
+			errors.New("a hash and a tag can't be stopped at once"),
+		)
 	}
 	client, err := warpcli.NewClient()
 	if err != nil {
 		common.PrintRuntimeErr(ctx, "stop", "new_client", err)
 		return nil
 	}
+	if tagToStop != "" {
+		r, err := client.StopTag(tagToStop)
+		if err != nil {
+			common.PrintRuntimeErr(ctx, "stop", "stop-tag", err)
+			return nil
+		}
+		fmt.Printf("Stopped %d downloads tagged %s\n", len(r.DownloadIds), tagToStop)
+		return nil
+	}
 	_, err = client.StopDownload(hash)
 	if err != nil {
 		common.PrintRuntimeErr(ctx, "stop", "stop-download", err)
@@ -0,0 +1,62 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/urfave/cli"
+	"github.com/warpdl/warpdl/pkg/warplib"
+)
+
+// STREAM_FILE_NAME is the file name which makes download
+// command write the content to stdout.
+const STREAM_FILE_NAME = "-"
+
+var errStreamInterrupted = errors.New("stream was interrupted before the end of the file")
+
+// stream downloads url to stdout without the daemon, everything
+// else is printed to stderr to keep the output pipeable.
+func stream(ctx *cli.Context, url string, headers warplib.Headers, rangeStart, rangeEnd int64) error {
+	d, err := warplib.NewDownloader(
+		&http.Client{},
+		url,
+		&warplib.DownloaderOpts{
+			Headers:        headers,
+			MaxConnections: int32(maxConns),
+			DecodeContent:  decode,
+			RangeStart:     rangeStart,
+			RangeEnd:       rangeEnd,
+			SkipSetup:      true,
+		},
+	)
+	if err != nil {
+		return streamErr(ctx, "new_downloader", EXIT_ERROR, err)
+	}
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(sig)
+	go func() {
+		if _, ok := <-sig; ok {
+			d.Stop()
+		}
+	}()
+	err = d.Stream(os.Stdout, nil)
+	if err != nil {
+		return streamErr(ctx, "stream", EXIT_ERROR, err)
+	}
+	if d.IsStopped() {
+		// the output is truncated, scripts mustn't
+		// take it for the whole file.
+		return streamErr(ctx, "stream", EXIT_STOPPED, errStreamInterrupted)
+	}
+	return nil
+}
+
+func streamErr(ctx *cli.Context, action string, code int, err error) error {
+	fmt.Fprintf(os.Stderr, "%s: download[%s]: %s\n", ctx.App.HelpName, action, err.Error())
+	return cli.NewExitError("", code)
+}
@@ -0,0 +1,62 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/urfave/cli"
+	"github.com/warpdl/warpdl/cmd/common"
+	"github.com/warpdl/warpdl/pkg/warpcli"
+)
+
+var (
+	removeTags cli.StringSlice
+	tagNote    string
+
+	tagFlags = []cli.Flag{
+		cli.StringSliceFlag{
+			Name:  "remove, r",
+			Usage: "remove a tag from the download (can be repeated)",
+			Value: &removeTags,
+		},
+		cli.StringFlag{
+			Name:        "note",
+			Usage:       "set the note of the download, an empty note removes it",
+			Destination: &tagNote,
+		},
+	}
+)
+
+func tag(ctx *cli.Context) error {
+	hash := ctx.Args().First()
+	switch hash {
+	case "":
+		return common.PrintErrWithCmdHelp(ctx, errors.New("no hash provided"))
+	case "help":
+		return cli.ShowCommandHelp(ctx, ctx.Command.Name)
+	}
+	var note *string
+	if ctx.IsSet("note") {
+		note = &tagNote
+	}
+	client, err := warpcli.NewClient()
+	if err != nil {
+		common.PrintRuntimeErr(ctx, "tag", "new_client", err)
+		return nil
+	}
+	r, err := client.Tag(hash, ctx.Args().Tail(), removeTags, note)
+	if err != nil {
+		common.PrintRuntimeErr(ctx, "tag", "tag", err)
+		return nil
+	}
+	tags := "-"
+	if len(r.Tags) != 0 {
+		tags = strings.Join(r.Tags, ", ")
+	}
+	fmt.Printf("Tags of %s: %s\n", r.FileName, tags)
+	if r.Note != "" {
+		fmt.Printf("Note: %s\n", r.Note)
+	}
+	return nil
+}
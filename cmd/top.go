@@ -0,0 +1,684 @@
+package cmd
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/mattn/go-runewidth"
+	"github.com/urfave/cli"
+	cmdCommon "github.com/warpdl/warpdl/cmd/common"
+	"github.com/warpdl/warpdl/common"
+	"github.com/warpdl/warpdl/pkg/warpcli"
+	"github.com/warpdl/warpdl/pkg/warplib"
+	"golang.org/x/term"
+)
+
+const (
+	// DEF_TOP_INTERVAL is the interval the downloads
+	// shown by top are refreshed at.
+	DEF_TOP_INTERVAL = time.Second
+	// DEF_TOP_RESUBSCRIBE is the delay before top subscribes
+	// again once its subscription to the daemon is lost.
+	DEF_TOP_RESUBSCRIBE = 2 * time.Second
+)
+
+// Keys of the top dashboard which aren't printable.
+const (
+	keyUp        = "up"
+	keyDown      = "down"
+	keyEnter     = "enter"
+	keyEsc       = "esc"
+	keyBackspace = "backspace"
+	keyCtrlC     = "ctrl-c"
+)
+
+const topHelp = "↑/↓ select  p pause/resume  r resume  s stop  +/- connections  f flush  a add url  q quit"
+
+var (
+	topShowAll bool
+	topDlPath  string
+
+	topFlags = []cli.Flag{
+		cli.BoolFlag{
+			Name:        "show-all, a",
+			Usage:       "use this flag to show completed downloads as well (default: false)",
+			Destination: &topShowAll,
+		},
+		cli.StringFlag{
+			Name:        "download-path, l",
+			Usage:       "set the path where the urls added from the dashboard are saved (default: directory of the matching category of the daemon, or the current directory)",
+			Destination: &topDlPath,
+		},
+	}
+)
+
+func top(ctx *cli.Context) error {
+	if ctx.Args().First() == "help" {
+		return cli.ShowCommandHelp(ctx, ctx.Command.Name)
+	}
+	in, out := int(os.Stdin.Fd()), int(os.Stdout.Fd())
+	if !term.IsTerminal(in) || !term.IsTerminal(out) {
+		return cmdCommon.PrintErrWithCmdHelp(
+			ctx,
+			errors.New("top needs a terminal, use the list command in scripts"),
+		)
+	}
+	if topDlPath != "" {
+		var err error
+		topDlPath, err = filepath.Abs(topDlPath)
+		if err != nil {
+			cmdCommon.PrintRuntimeErr(ctx, "top", "download_path", err)
+			return nil
+		}
+	}
+	client, err := warpcli.NewClient()
+	if err != nil {
+		cmdCommon.PrintRuntimeErr(ctx, "top", "new_client", err)
+		return nil
+	}
+	t := &topUi{
+		client:  client,
+		out:     bufio.NewWriter(os.Stdout),
+		results: make(chan string, 8),
+	}
+	// refresh replaces the client if the connection is lost.
+	defer func() { t.client.Close() }()
+	state, err := term.MakeRaw(in)
+	if err != nil {
+		cmdCommon.PrintRuntimeErr(ctx, "top", "raw_mode", err)
+		return nil
+	}
+	defer term.Restore(in, state)
+	t.run()
+	return nil
+}
+
+// topUi is the state of the top dashboard, it's
+// only accessed by the goroutine running run.
+type topUi struct {
+	// client lists the downloads, actions use their own
+	// clients as the daemon may send them download updates.
+	client *warpcli.Client
+	items  []*warplib.Item
+	stats  map[string]*common.ItemStats
+	// selected is the hash of the selected item
+	// and sel its index.
+	selected string
+	sel      int
+	// offset is the index of the first shown item.
+	offset int
+	status string
+	// prompt reads a line in the status line if set.
+	prompt *topPrompt
+	// results receives the status of the actions.
+	results chan string
+	out     *bufio.Writer
+}
+
+// topPrompt reads a line, or a single key if confirm is set.
+type topPrompt struct {
+	label   string
+	input   []rune
+	confirm bool
+	submit  func(input string)
+}
+
+// run shows the dashboard until the user quits.
+func (t *topUi) run() {
+	// use the alternate screen and hide the cursor.
+	t.out.WriteString("\x1b[?1049h\x1b[?25l")
+	defer func() {
+		t.out.WriteString("\x1b[?25h\x1b[?1049l")
+		t.out.Flush()
+	}()
+	keys := make(chan []byte)
+	go readKeys(keys)
+	events := make(chan *common.DownloadingResponse, 64)
+	done := make(chan struct{})
+	defer close(done)
+	go subscribeAll(events, done)
+	ticker := time.NewTicker(DEF_TOP_INTERVAL)
+	defer ticker.Stop()
+	t.refresh()
+	for {
+		t.draw()
+		select {
+		case b, ok := <-keys:
+			if !ok || !t.handleKeys(b) {
+				return
+			}
+		case dr := <-events:
+			if !t.handleEvent(dr) {
+				continue
+			}
+		case msg := <-t.results:
+			t.status = msg
+			t.refresh()
+		case <-ticker.C:
+			t.refresh()
+		}
+	}
+}
+
+// readKeys sends the input of the terminal to keys
+// and closes it once the input can't be read.
+func readKeys(keys chan<- []byte) {
+	buf := make([]byte, 256)
+	for {
+		n, err := os.Stdin.Read(buf)
+		if err != nil {
+			close(keys)
+			return
+		}
+		keys <- slices.Clone(buf[:n])
+	}
+}
+
+// parseKeys splits the input read from the terminal
+// into keys, printable keys are returned as is.
+func parseKeys(b []byte) []string {
+	var keys []string
+	for len(b) > 0 {
+		switch b[0] {
+		case 0x1b:
+			if len(b) < 3 || (b[1] != '[' && b[1] != 'O') {
+				keys = append(keys, keyEsc)
+				b = b[1:]
+				continue
+			}
+			// skip the escape sequence up to its final byte.
+			end := 2
+			for end < len(b)-1 && (b[end] < 0x40 || b[end] > 0x7e) {
+				end++
+			}
+			switch b[end] {
+			case 'A':
+				keys = append(keys, keyUp)
+			case 'B':
+				keys = append(keys, keyDown)
+			}
+			b = b[end+1:]
+			continue
+		case 0x03:
+			keys = append(keys, keyCtrlC)
+		case '\r', '\n':
+			keys = append(keys, keyEnter)
+		case 0x7f, 0x08:
+			keys = append(keys, keyBackspace)
+		default:
+			r, size := utf8.DecodeRune(b)
+			if unicode.IsPrint(r) {
+				keys = append(keys, string(r))
+			}
+			b = b[size:]
+			continue
+		}
+		b = b[1:]
+	}
+	return keys
+}
+
+// subscribeAll sends the updates of all the downloads to events
+// until done is closed, it subscribes again if the subscription
+// is lost, such as when the daemon restarts.
+func subscribeAll(events chan<- *common.DownloadingResponse, done <-chan struct{}) {
+	for {
+		client, err := warpcli.NewClient()
+		if err == nil {
+			client.AddHandler(
+				common.UPDATE_DOWNLOADING,
+				warpcli.NewDownloadingHandler("", func(dr *common.DownloadingResponse) error {
+					if dr.Action == common.DownloadProgress {
+						// progress is refreshed by listing
+						// anyway, don't hold the daemon back.
+						select {
+						case events <- dr:
+						default:
+						}
+						return nil
+					}
+					select {
+					case events <- dr:
+					case <-done:
+					}
+					return nil
+				}),
+			)
+			if err = client.Subscribe(); err == nil {
+				ended := make(chan struct{})
+				go func() {
+					select {
+					case <-done:
+						client.Close()
+					case <-ended:
+					}
+				}()
+				_ = client.Listen()
+				close(ended)
+			} else {
+				client.Close()
+			}
+		}
+		select {
+		case <-done:
+			return
+		case <-time.After(DEF_TOP_RESUBSCRIBE):
+		}
+	}
+}
+
+// refresh lists the downloads again.
+func (t *topUi) refresh() {
+	l, err := t.client.List(&warpcli.ListOpts{
+		ShowPending:   true,
+		ShowCompleted: topShowAll,
+		ShowSegments:  true,
+	})
+	if err != nil {
+		// the daemon may have restarted, use a new
+		// connection for the next refresh.
+		t.client.Close()
+		if c, cerr := warpcli.NewClient(); cerr == nil {
+			t.client = c
+		}
+		t.status = "list: " + err.Error()
+		return
+	}
+	t.items, t.stats = l.Items, l.Stats
+	if i := slices.IndexFunc(t.items, func(item *warplib.Item) bool {
+		return item.Hash == t.selected
+	}); i != -1 {
+		t.sel = i
+	}
+	t.sel = max(min(t.sel, len(t.items)-1), 0)
+	t.selected = ""
+	if len(t.items) != 0 {
+		t.selected = t.items[t.sel].Hash
+	}
+}
+
+// item returns the listed item of hash, nil if it isn't listed.
+func (t *topUi) item(hash string) *warplib.Item {
+	for _, item := range t.items {
+		if item.Hash == hash {
+			return item
+		}
+	}
+	return nil
+}
+
+func (t *topUi) itemStats(item *warplib.Item) *common.ItemStats {
+	if stats := t.stats[item.Hash]; stats != nil {
+		return stats
+	}
+	return &common.ItemStats{State: item.State()}
+}
+
+// handleEvent applies an update of a download and
+// reports whether the dashboard has to be drawn.
+func (t *topUi) handleEvent(dr *common.DownloadingResponse) bool {
+	item := t.item(dr.DownloadId)
+	name := dr.DownloadId
+	if item != nil {
+		name = item.Name
+	}
+	switch dr.Action {
+	case common.DownloadProgress:
+		if item != nil {
+			item.Downloaded += warplib.ContentLength(dr.Value)
+		}
+		return false
+	case common.ConnectionsTuned:
+		if item != nil {
+			t.itemStats(item).ConnectionLimit = int32(dr.Value)
+		}
+		return true
+	case common.DownloadComplete:
+		if dr.Hash != warplib.MAIN_HASH {
+			return false
+		}
+		t.status = name + ": download complete"
+	case common.DownloadStopped:
+		t.status = name + ": download stopped"
+	case common.DownloadFailed:
+		t.status = name + ": download failed: " + dr.Error
+	case common.ExtractComplete:
+		t.status = name + ": extracted to " + dr.Path
+		if dr.Error != "" {
+			t.status = name + ": extraction failed: " + dr.Error
+		}
+	default:
+		return false
+	}
+	t.refresh()
+	return true
+}
+
+// handleKeys handles the input of the terminal and
+// reports whether the dashboard keeps running.
+func (t *topUi) handleKeys(b []byte) bool {
+	for _, key := range parseKeys(b) {
+		if t.prompt != nil {
+			t.handlePromptKey(key)
+			continue
+		}
+		switch key {
+		case "q", keyCtrlC:
+			return false
+		case keyUp, "k":
+			t.sel = max(t.sel-1, 0)
+		case keyDown, "j":
+			t.sel = max(min(t.sel+1, len(t.items)-1), 0)
+		case "a":
+			t.prompt = &topPrompt{label: "Add url: ", submit: t.add}
+		}
+		if len(t.items) != 0 {
+			t.selected = t.items[t.sel].Hash
+		}
+		item := t.item(t.selected)
+		if item == nil {
+			continue
+		}
+		switch key {
+		case "p":
+			if t.itemStats(item).State == warplib.StateDownloading {
+				t.stop(item)
+			} else {
+				t.resume(item)
+			}
+		case "r":
+			t.resume(item)
+		case "s":
+			t.stop(item)
+		case "+", "=":
+			t.connections(item, 1)
+		case "-":
+			t.connections(item, -1)
+		case "f":
+			t.prompt = &topPrompt{
+				label:   fmt.Sprintf("Flush %s? (y/n) ", item.Name),
+				confirm: true,
+				submit: func(string) {
+					t.do("flush", func(c *warpcli.Client) (string, error) {
+						_, err := c.Flush(item.Hash)
+						return "Flushed " + item.Name, err
+					})
+				},
+			}
+		}
+	}
+	return true
+}
+
+func (t *topUi) handlePromptKey(key string) {
+	p := t.prompt
+	if p.confirm {
+		t.prompt = nil
+		if key == "y" || key == "Y" {
+			p.submit("")
+		}
+		return
+	}
+	switch key {
+	case keyEnter:
+		t.prompt = nil
+		if input := strings.TrimSpace(string(p.input)); input != "" {
+			p.submit(input)
+		}
+	case keyEsc, keyCtrlC:
+		t.prompt = nil
+	case keyBackspace:
+		if len(p.input) != 0 {
+			p.input = p.input[:len(p.input)-1]
+		}
+	case keyUp, keyDown:
+	default:
+		p.input = append(p.input, []rune(key)...)
+	}
+}
+
+// do runs the action in background with a new client and shows
+// its result, or error, in the status line once it's done.
+func (t *topUi) do(name string, action func(*warpcli.Client) (string, error)) {
+	t.status = name + "..."
+	go func() {
+		msg, err := func() (string, error) {
+			c, err := warpcli.NewClient()
+			if err != nil {
+				return "", err
+			}
+			// the daemon sends the updates of a started download
+			// to the client, closing it stops them.
+			defer c.Close()
+			return action(c)
+		}()
+		if err != nil {
+			msg = name + ": " + err.Error()
+		}
+		t.results <- msg
+	}()
+}
+
+func (t *topUi) stop(item *warplib.Item) {
+	t.do("stop", func(c *warpcli.Client) (string, error) {
+		_, err := c.StopDownload(item.Hash)
+		return "Stopping " + item.Name, err
+	})
+}
+
+func (t *topUi) resume(item *warplib.Item) {
+	t.do("resume", func(c *warpcli.Client) (string, error) {
+		_, err := c.Resume(item.Hash, &warpcli.ResumeOpts{
+			Headers:        topHeaders(),
+			ForceParts:     forceParts,
+			MaxConnections: int32(maxConns),
+			MaxSegments:    int32(maxParts),
+			RaceTail:       raceTail,
+		})
+		return "Resumed " + item.Name, err
+	})
+}
+
+// connections changes the connection cap of a
+// running download by delta connections.
+func (t *topUi) connections(item *warplib.Item, delta int32) {
+	limit := t.itemStats(item).ConnectionLimit
+	if limit == 0 {
+		t.status = item.Name + " isn't downloading"
+		return
+	}
+	t.do("connections", func(c *warpcli.Client) (string, error) {
+		r, err := c.SetMaxConnections(item.Hash, limit+delta)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("%s capped to %d connections", item.Name, r.MaxConnections), nil
+	})
+}
+
+func (t *topUi) add(url string) {
+	t.do("add", func(c *warpcli.Client) (string, error) {
+		d, err := c.Download(url, "", topDlPath, &warpcli.DownloadOpts{
+			Headers:        topHeaders(),
+			ForceParts:     forceParts,
+			MaxConnections: int32(maxConns),
+			MaxSegments:    int32(maxParts),
+			RaceTail:       raceTail,
+		})
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("Added %s (%s)", d.FileName, d.DownloadId), nil
+	})
+}
+
+// topHeaders returns the headers of the downloads
+// resumed and added from the dashboard.
+func topHeaders() warplib.Headers {
+	if userAgent == "" {
+		return nil
+	}
+	return warplib.Headers{{
+		Key: warplib.USER_AGENT_KEY, Value: getUserAgent(userAgent),
+	}}
+}
+
+// draw draws the dashboard on the whole terminal.
+func (t *topUi) draw() {
+	width, height, err := term.GetSize(int(os.Stdout.Fd()))
+	if err != nil || width <= 0 || height <= 0 {
+		width, height = 80, 24
+	}
+	var (
+		downloading int
+		speed       int64
+	)
+	rows := [][]string{{"Name", "Size", "Progress", "Speed", "ETA", "Conns", "State"}}
+	for _, item := range t.items {
+		stats := t.itemStats(item)
+		if stats.State == warplib.StateDownloading {
+			downloading++
+			speed += stats.Speed
+		}
+		conns := "-"
+		if stats.ConnectionLimit != 0 {
+			conns = fmt.Sprintf("%d/%d", stats.Connections, stats.ConnectionLimit)
+		}
+		rows = append(rows, []string{
+			item.Name,
+			listSize(item),
+			listProgress(item),
+			listSpeed(stats.Speed),
+			listEta(item, stats.Speed),
+			conns,
+			string(stats.State),
+		})
+	}
+	table := formatTable(rows, 0, width)
+	lines := []string{
+		fmt.Sprintf("warpdl top - %d downloads, %d downloading at %s", len(t.items), downloading, listSpeed(speed)),
+		"",
+		"\x1b[1m" + runewidth.Truncate(table[0], width, "") + "\x1b[0m",
+	}
+	// the header, the segment map and the status
+	// lines are left out of the visible rows.
+	visible := max(height-8, 1)
+	if t.sel < t.offset {
+		t.offset = t.sel
+	} else if t.sel >= t.offset+visible {
+		t.offset = t.sel - visible + 1
+	}
+	t.offset = max(min(t.offset, len(t.items)-visible), 0)
+	for i := t.offset; i < min(len(t.items), t.offset+visible); i++ {
+		line := runewidth.Truncate(table[i+1], width, "")
+		if i == t.sel {
+			line = "\x1b[7m" + runewidth.FillRight(line, width) + "\x1b[0m"
+		}
+		lines = append(lines, line)
+	}
+	if len(t.items) == 0 {
+		lines = append(lines, "no downloads, press a to add one")
+	}
+	for len(lines) < height-5 {
+		lines = append(lines, "")
+	}
+	lines = append(lines, "")
+	lines = append(lines, t.segmentLines(width)...)
+	status := t.status
+	if t.prompt != nil {
+		status = t.prompt.label + string(t.prompt.input)
+		if !t.prompt.confirm {
+			status += "█"
+		}
+	}
+	lines = append(lines, status, "\x1b[2m"+runewidth.Truncate(topHelp, width, "…")+"\x1b[0m")
+
+	t.out.WriteString("\x1b[H")
+	for i, line := range lines[:min(len(lines), height)] {
+		if i != 0 {
+			t.out.WriteString("\r\n")
+		}
+		if !strings.Contains(line, "\x1b") {
+			line = runewidth.Truncate(line, width, "…")
+		}
+		t.out.WriteString(line)
+		t.out.WriteString("\x1b[K")
+	}
+	t.out.WriteString("\x1b[J")
+	t.out.Flush()
+}
+
+// segmentLines returns the title and the segment
+// map of the selected item, width cells wide.
+func (t *topUi) segmentLines(width int) []string {
+	item := t.item(t.selected)
+	if item == nil {
+		return []string{"", ""}
+	}
+	segs := t.itemStats(item).Segments
+	if len(segs) == 0 || item.TotalSize <= 0 {
+		return []string{item.Name + ": no segments", ""}
+	}
+	var active int
+	for _, seg := range segs {
+		if seg.Active {
+			active++
+		}
+	}
+	return []string{
+		fmt.Sprintf("%s: %d segments, %d active", item.Name, len(segs), active),
+		segmentMap(segs, int64(item.TotalSize), width),
+	}
+}
+
+// segmentMap draws the segments of a download of size bytes as
+// a bar of width cells. A cell is full once all its bytes are
+// downloaded and the cells being downloaded are highlighted.
+func segmentMap(segs []warplib.Segment, size int64, width int) string {
+	width = int(min(int64(width), size))
+	if width <= 0 {
+		return ""
+	}
+	bounds := func(i int) (lo, hi int64) {
+		return int64(i) * size / int64(width), int64(i+1) * size / int64(width)
+	}
+	read := make([]int64, width)
+	active := make([]bool, width)
+	for _, seg := range segs {
+		start, end := seg.Start, seg.Start+seg.Read
+		first := int(start * int64(width) / size)
+		for i := first; i < width; i++ {
+			lo, hi := bounds(i)
+			if lo >= end {
+				break
+			}
+			read[i] += max(min(hi, end)-max(lo, start), 0)
+		}
+		if seg.Active && end < size {
+			active[int(end*int64(width)/size)] = true
+		}
+	}
+	var b strings.Builder
+	for i := range width {
+		lo, hi := bounds(i)
+		switch {
+		case active[i]:
+			b.WriteRune('▓')
+		case read[i] >= hi-lo:
+			b.WriteRune('█')
+		case read[i] > 0:
+			b.WriteRune('▒')
+		default:
+			b.WriteRune('░')
+		}
+	}
+	return b.String()
+}
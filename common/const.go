@@ -4,15 +4,33 @@ type UpdateType string
 
 const (
 	UPDATE_DOWNLOAD    UpdateType = "download"
+	UPDATE_BATCH       UpdateType = "batch"
+	UPDATE_CRAWL       UpdateType = "crawl"
+	UPDATE_SCHEDULE    UpdateType = "schedule"
+	UPDATE_TAG         UpdateType = "tag"
 	UPDATE_DOWNLOADING UpdateType = "downloading"
 	UPDATE_ATTACH      UpdateType = "attach"
 	UPDATE_RESUME      UpdateType = "resume"
+	UPDATE_REFRESH_URL UpdateType = "refresh_url"
 	UPDATE_FLUSH       UpdateType = "flush"
 	UPDATE_STOP        UpdateType = "stop"
 	UPDATE_LIST        UpdateType = "list"
 	UPDATE_LOAD_EXT    UpdateType = "load_extension"
 	UPDATE_UNLOAD_EXT  UpdateType = "unload_extension"
 	UPDATE_GET_EXT     UpdateType = "get_extension"
+	UPDATE_SUBSCRIBE   UpdateType = "subscribe"
+	UPDATE_CONNECTIONS UpdateType = "connections"
+)
+
+// ListSort is the order of the listed downloads.
+type ListSort string
+
+const (
+	SortByDate     ListSort = "date"
+	SortByName     ListSort = "name"
+	SortBySize     ListSort = "size"
+	SortByProgress ListSort = "progress"
+	SortBySpeed    ListSort = "speed"
 )
 
 type DownloadingAction string
@@ -25,4 +43,16 @@ const (
 	CompileStart     DownloadingAction = "compile_start"
 	CompileProgress  DownloadingAction = "compile_progress"
 	CompileComplete  DownloadingAction = "compile_complete"
+	// ConnectionsTuned is sent with the new connection
+	// limit as value.
+	ConnectionsTuned DownloadingAction = "connections_tuned"
+	// DownloadStarted is only sent to webhooks, DownloadFailed
+	// is sent to webhooks and subscribers with the error.
+	DownloadStarted DownloadingAction = "download_started"
+	DownloadFailed  DownloadingAction = "download_failed"
+	// ExtractStart is sent with the size of the archive as
+	// value and ExtractComplete with the directory as path.
+	ExtractStart    DownloadingAction = "extract_start"
+	ExtractProgress DownloadingAction = "extract_progress"
+	ExtractComplete DownloadingAction = "extract_complete"
 )
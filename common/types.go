@@ -1,6 +1,8 @@
 package common
 
 import (
+	"time"
+
 	"github.com/warpdl/warpdl/pkg/warplib"
 )
 
@@ -19,6 +21,27 @@ type DownloadParams struct {
 	ChildHash         string          `json:"child_hash,omitempty"`
 	IsHidden          bool            `json:"is_hidden,omitempty"`
 	IsChildren        bool            `json:"is_children,omitempty"`
+	// ConflictPolicy is used if a file already exists at the
+	// save path, daemon's default policy is used if empty.
+	ConflictPolicy warplib.ConflictPolicy `json:"conflict_policy,omitempty"`
+	// DecodeContent decodes compressed responses on the fly.
+	DecodeContent bool `json:"decode_content,omitempty"`
+	// RaceTail races duplicate requests for the last segments.
+	RaceTail bool `json:"race_tail,omitempty"`
+	// RangeStart and RangeEnd limit the download to a byte range,
+	// see warplib.DownloaderOpts for their semantics.
+	RangeStart int64 `json:"range_start,omitempty"`
+	RangeEnd   int64 `json:"range_end,omitempty"`
+	// ParentHash adds the download to a group, such as a crawl.
+	ParentHash string `json:"parent_hash,omitempty"`
+	// Schedule restricts when the download runs, it is
+	// started by the daemon's scheduler if not allowed now.
+	Schedule *warplib.Schedule `json:"schedule,omitempty"`
+	// Extract extracts the archive once downloaded.
+	Extract *warplib.ExtractOpts `json:"extract,omitempty"`
+	// Tags label the download, see TagParams.
+	Tags []string `json:"tags,omitempty"`
+	Note string   `json:"note,omitempty"`
 }
 
 type DownloadResponse struct {
@@ -30,6 +53,75 @@ type DownloadResponse struct {
 	Downloaded        warplib.ContentLength `json:"downloaded,omitempty"`
 	MaxConnections    int32                 `json:"max_connections"`
 	MaxSegments       int32                 `json:"max_segments"`
+	// Ranged is set if only a byte range of the file starting
+	// at RangeStart is downloaded.
+	Ranged     bool  `json:"ranged,omitempty"`
+	RangeStart int64 `json:"range_start,omitempty"`
+	// Scheduled is set if the download waits for its schedule.
+	Scheduled bool `json:"scheduled,omitempty"`
+	// Category is the category which picked the
+	// download directory, if any.
+	Category string `json:"category,omitempty"`
+}
+
+type BatchParams struct {
+	Items []DownloadParams `json:"items"`
+	// MaxParallel limits the number of downloads
+	// running at once, daemon's default is used if 0.
+	MaxParallel int `json:"max_parallel,omitempty"`
+	// NoGlob disables the expansion of url patterns, see
+	// warplib.ExpandUrl for the supported patterns.
+	NoGlob bool `json:"no_glob,omitempty"`
+}
+
+// BatchResult is the outcome of a single download of a batch.
+type BatchResult struct {
+	Url        string `json:"url"`
+	DownloadId string `json:"download_id,omitempty"`
+	FileName   string `json:"file_name,omitempty"`
+	SavePath   string `json:"save_path,omitempty"`
+	// Error is empty if download completed successfully.
+	Error string `json:"error,omitempty"`
+}
+
+type BatchResponse struct {
+	Results   []BatchResult `json:"results"`
+	Completed int           `json:"completed"`
+	Failed    int           `json:"failed"`
+}
+
+// ScheduleParams sets the schedule of a download,
+// a nil Schedule removes it.
+type ScheduleParams struct {
+	DownloadId string            `json:"download_id"`
+	Schedule   *warplib.Schedule `json:"schedule,omitempty"`
+}
+
+type ScheduleResponse struct {
+	DownloadId string            `json:"download_id"`
+	FileName   string            `json:"file_name"`
+	Schedule   *warplib.Schedule `json:"schedule,omitempty"`
+}
+
+// CrawlParams crawls the directory index at Url and downloads the
+// files found, the embedded DownloadParams are used for each file.
+type CrawlParams struct {
+	DownloadParams
+	MaxDepth   int      `json:"max_depth"`
+	OtherHosts bool     `json:"other_hosts,omitempty"`
+	Include    string   `json:"include,omitempty"`
+	Exclude    string   `json:"exclude,omitempty"`
+	Extensions []string `json:"extensions,omitempty"`
+	// MaxParallel limits the number of downloads
+	// running at once, daemon's default is used if 0.
+	MaxParallel int `json:"max_parallel,omitempty"`
+}
+
+type CrawlResponse struct {
+	// DownloadId is the hash of the group of the downloads.
+	DownloadId        string `json:"download_id"`
+	DownloadDirectory string `json:"download_directory"`
+	BatchResponse
 }
 
 type DownloadingResponse struct {
@@ -37,6 +129,10 @@ type DownloadingResponse struct {
 	Action     DownloadingAction `json:"action"`
 	Hash       string            `json:"hash"`
 	Value      int64             `json:"value,omitempty"`
+	// Path is the directory an archive was extracted to.
+	Path string `json:"path,omitempty"`
+	// Error is the error of a failed download.
+	Error string `json:"error,omitempty"`
 }
 
 type ResumeParams struct {
@@ -45,6 +141,28 @@ type ResumeParams struct {
 	ForceParts     bool            `json:"force_parts,omitempty"`
 	MaxConnections int32           `json:"max_connections,omitempty"`
 	MaxSegments    int32           `json:"max_segments,omitempty"`
+	// RaceTail races duplicate requests for the last segments.
+	RaceTail bool `json:"race_tail,omitempty"`
+	// Url replaces the download url before resuming.
+	Url string `json:"url,omitempty"`
+	// RefreshUrl re-extracts the download url using the
+	// matching extension before resuming.
+	RefreshUrl bool `json:"refresh_url,omitempty"`
+	// Tag resumes the downloads with the tag in
+	// background instead, see TaggedResponse.
+	Tag string `json:"tag,omitempty"`
+}
+
+type RefreshUrlParams struct {
+	DownloadId string          `json:"download_id"`
+	Url        string          `json:"url,omitempty"`
+	Headers    warplib.Headers `json:"headers,omitempty"`
+}
+
+type RefreshUrlResponse struct {
+	DownloadId   string `json:"download_id"`
+	Url          string `json:"url"`
+	EffectiveUrl string `json:"effective_url,omitempty"`
 }
 
 type ResumeResponse struct {
@@ -56,19 +174,127 @@ type ResumeResponse struct {
 	ContentLength     warplib.ContentLength `json:"content_length"`
 	MaxConnections    int32                 `json:"max_connections"`
 	MaxSegments       int32                 `json:"max_segments"`
+	// Group reports whether the item is a group, its
+	// downloads are resumed one by one in background.
+	Group bool `json:"group,omitempty"`
 }
 
 type FlushParams struct {
 	DownloadId string `json:"download_id,omitempty"`
+	// Tag flushes the downloads with the tag instead.
+	Tag string `json:"tag,omitempty"`
+}
+
+type StopParams struct {
+	DownloadId string `json:"download_id,omitempty"`
+	// Tag stops the running downloads with the tag instead.
+	Tag string `json:"tag,omitempty"`
+}
+
+// TaggedResponse lists the downloads resumed,
+// stopped or flushed by their tag.
+type TaggedResponse struct {
+	DownloadIds []string `json:"download_ids"`
+}
+
+// TagParams adds and removes tags of a download,
+// its note is set as well unless Note is nil.
+type TagParams struct {
+	DownloadId string   `json:"download_id"`
+	Add        []string `json:"add,omitempty"`
+	Remove     []string `json:"remove,omitempty"`
+	Note       *string  `json:"note,omitempty"`
+}
+
+type TagResponse struct {
+	DownloadId string   `json:"download_id"`
+	FileName   string   `json:"file_name"`
+	Tags       []string `json:"tags,omitempty"`
+	Note       string   `json:"note,omitempty"`
 }
 
 type ListParams struct {
 	ShowCompleted bool `json:"show_completed"`
 	ShowPending   bool `json:"show_pending"`
+	// ShowHidden lists hidden downloads and the
+	// downloads of other downloads as well.
+	ShowHidden bool `json:"show_hidden,omitempty"`
+	// Category only lists the downloads of the category if set.
+	Category string `json:"category,omitempty"`
+	// Tag only lists the downloads with the tag if set.
+	Tag string `json:"tag,omitempty"`
+	// States only lists the downloads in one of the states.
+	States []warplib.ItemState `json:"states,omitempty"`
+	// Host only lists the downloads from the host
+	// or its subdomains if set.
+	Host string `json:"host,omitempty"`
+	// Name only lists the downloads whose name contains it,
+	// ignoring the case, and NameRegex the ones matching it.
+	Name      string `json:"name,omitempty"`
+	NameRegex string `json:"name_regex,omitempty"`
+	// AddedAfter and AddedBefore limit when the listed
+	// downloads were added, they are ignored if zero.
+	AddedAfter  time.Time `json:"added_after,omitempty"`
+	AddedBefore time.Time `json:"added_before,omitempty"`
+	// MinSize and MaxSize limit the size of the listed downloads
+	// in bytes, they are ignored if 0. Downloads of unknown size
+	// are left out if either is set.
+	MinSize int64 `json:"min_size,omitempty"`
+	MaxSize int64 `json:"max_size,omitempty"`
+	// SortBy orders the downloads, by date added if empty.
+	SortBy ListSort `json:"sort_by,omitempty"`
+	// Reverse lists the downloads in descending order.
+	Reverse bool `json:"reverse,omitempty"`
+	// Offset skips the first matching downloads and Limit
+	// limits the number of listed ones if not 0.
+	Offset int `json:"offset,omitempty"`
+	Limit  int `json:"limit,omitempty"`
+	// ShowSegments adds the segments of the listed
+	// downloads to their stats.
+	ShowSegments bool `json:"show_segments,omitempty"`
 }
 
 type ListResponse struct {
 	Items []*warplib.Item `json:"items"`
+	// Stats are the live stats of the items by their hash.
+	Stats map[string]*ItemStats `json:"stats,omitempty"`
+	// Total is the number of matching downloads, Items
+	// only holds a page of them if a limit is set.
+	Total int `json:"total"`
+}
+
+// ItemStats are the stats of an item which aren't stored
+// with it. Groups are downloading while their downloads
+// run, at the sum of their speeds.
+type ItemStats struct {
+	State warplib.ItemState `json:"state"`
+	// Speed is the download speed in bytes per second.
+	Speed int64 `json:"speed,omitempty"`
+	// Connections is the number of connections of a running
+	// download and ConnectionLimit the number it may use.
+	Connections     int32 `json:"connections,omitempty"`
+	ConnectionLimit int32 `json:"connection_limit,omitempty"`
+	// Segments are set if ListParams.ShowSegments is.
+	Segments []warplib.Segment `json:"segments,omitempty"`
+}
+
+// SubscribeResponse is the response of a subscription,
+// the updates of all the downloads follow it.
+type SubscribeResponse struct {
+	Subscribed bool `json:"subscribed"`
+}
+
+type ConnectionsParams struct {
+	DownloadId string `json:"download_id"`
+	// MaxConnections caps the connections of the running
+	// download, it's clamped to the max connections the
+	// download was started with.
+	MaxConnections int32 `json:"max_connections"`
+}
+
+type ConnectionsResponse struct {
+	// MaxConnections is the cap after clamping.
+	MaxConnections int32 `json:"max_connections"`
 }
 
 type LoadExtensionParams struct {
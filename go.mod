@@ -5,11 +5,16 @@ go 1.22
 toolchain go1.23.1
 
 require (
+	github.com/andybalholm/brotli v1.1.1
 	github.com/dop251/goja v0.0.0-20241009100908-5f46f2705ca3
 	github.com/dop251/goja_nodejs v0.0.0-20240728170619-29b559befffc
+	github.com/klauspost/compress v1.18.0
+	github.com/mattn/go-runewidth v0.0.16
+	github.com/ulikunitz/xz v0.5.12
 	github.com/urfave/cli v1.22.16
 	github.com/vbauerster/mpb/v8 v8.8.3
 	golang.org/x/net v0.30.0
+	golang.org/x/term v0.25.0
 )
 
 require (
@@ -25,7 +30,6 @@ require (
 	github.com/dlclark/regexp2 v1.11.4 // indirect
 	github.com/go-sourcemap/sourcemap v2.1.4+incompatible // indirect
 	github.com/google/pprof v0.0.0-20241009165004-a3522334989c // indirect
-	github.com/mattn/go-runewidth v0.0.16 // indirect
 	github.com/rivo/uniseg v0.4.7 // indirect
 	github.com/russross/blackfriday/v2 v2.1.0 // indirect
 	github.com/zalando/go-keyring v0.2.5
@@ -3,10 +3,12 @@ package api
 import (
 	"log"
 	"net/http"
+	"sync"
 
 	"github.com/warpdl/warpdl/common"
 	"github.com/warpdl/warpdl/internal/extl"
 	"github.com/warpdl/warpdl/internal/server"
+	"github.com/warpdl/warpdl/internal/webhook"
 	"github.com/warpdl/warpdl/pkg/warplib"
 )
 
@@ -15,31 +17,55 @@ type Api struct {
 	manager  *warplib.Manager
 	elEngine *extl.Engine
 	client   *http.Client
+	// default policy for file name conflicts
+	conflictPolicy warplib.ConflictPolicy
+	// downloads started by the scheduler, guarded by schedMu
+	started map[string]bool
+	schedMu sync.Mutex
+	// webhooks notified of the lifecycle events, nil if none
+	webhooks *webhook.Dispatcher
+	// categories picking the directory of downloads without one
+	categories warplib.Categories
 }
 
-func NewApi(l *log.Logger, m *warplib.Manager, client *http.Client, elEngine *extl.Engine) (*Api, error) {
+func NewApi(l *log.Logger, m *warplib.Manager, client *http.Client, elEngine *extl.Engine, conflictPolicy warplib.ConflictPolicy, webhooks *webhook.Dispatcher, categories warplib.Categories) (*Api, error) {
 	return &Api{
-		log:      l,
-		manager:  m,
-		client:   client,
-		elEngine: elEngine,
+		log:            l,
+		manager:        m,
+		client:         client,
+		elEngine:       elEngine,
+		conflictPolicy: conflictPolicy,
+		started:        make(map[string]bool),
+		webhooks:       webhooks,
+		categories:     categories,
 	}, nil
 }
 
 func (s *Api) RegisterHandlers(server *server.Server) {
 	// downloader API methods
 	server.RegisterHandler(common.UPDATE_DOWNLOAD, s.downloadHandler)
+	server.RegisterHandler(common.UPDATE_BATCH, s.batchHandler)
+	server.RegisterHandler(common.UPDATE_CRAWL, s.crawlHandler)
+	server.RegisterHandler(common.UPDATE_SCHEDULE, s.scheduleHandler)
+	server.RegisterHandler(common.UPDATE_TAG, s.tagHandler)
 	server.RegisterHandler(common.UPDATE_RESUME, s.resumeHandler)
+	server.RegisterHandler(common.UPDATE_REFRESH_URL, s.refreshUrlHandler)
 	server.RegisterHandler(common.UPDATE_ATTACH, s.attachHandler)
 	server.RegisterHandler(common.UPDATE_FLUSH, s.flushHandler)
 	server.RegisterHandler(common.UPDATE_STOP, s.stopHandler)
 	server.RegisterHandler(common.UPDATE_LIST, s.listHandler)
+	server.RegisterHandler(common.UPDATE_SUBSCRIBE, s.subscribeHandler)
+	server.RegisterHandler(common.UPDATE_CONNECTIONS, s.connectionsHandler)
 
 	// extension API methods
 	server.RegisterHandler(common.UPDATE_LOAD_EXT, s.loadExtHandler)
 	server.RegisterHandler(common.UPDATE_GET_EXT, s.getExtHandler)
+
+	// downloads captured by the browser extension
+	server.SetWebDownload(s.webDownload)
 }
 
 func (s *Api) Close() error {
+	s.webhooks.Close()
 	return s.manager.Close()
 }
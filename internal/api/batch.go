@@ -0,0 +1,202 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"sync"
+
+	"github.com/warpdl/warpdl/common"
+	"github.com/warpdl/warpdl/internal/server"
+	"github.com/warpdl/warpdl/pkg/warplib"
+)
+
+// DEF_BATCH_PARALLEL is the default number of downloads
+// of a batch running at once.
+const DEF_BATCH_PARALLEL = 4
+
+var (
+	errBatchDownloadStopped = errors.New("download stopped")
+	errBatchPatternFileName = errors.New("file name can't be set for a url pattern")
+)
+
+// batchItem is a single download of a batch.
+type batchItem struct {
+	m   common.DownloadParams
+	d   *warplib.Downloader
+	res common.BatchResult
+	mu  sync.Mutex
+	// first error reported by the download
+	err error
+}
+
+func (b *batchItem) onError(err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.err == nil {
+		b.err = err
+	}
+}
+
+func (b *batchItem) fail(err error) {
+	b.res.Error = err.Error()
+}
+
+// batchHandler downloads all items of the batch and responds
+// once every download has either completed or failed.
+func (s *Api) batchHandler(sconn *server.SyncConn, pool *server.Pool, body json.RawMessage) (common.UpdateType, any, error) {
+	var m common.BatchParams
+	if err := json.Unmarshal(body, &m); err != nil {
+		return common.UPDATE_BATCH, nil, err
+	}
+	if m.MaxParallel <= 0 {
+		m.MaxParallel = DEF_BATCH_PARALLEL
+	}
+	var items []*batchItem
+	for _, p := range m.Items {
+		urls := []string{p.Url}
+		if !m.NoGlob {
+			var err error
+			urls, err = warplib.ExpandUrl(p.Url)
+			if err != nil {
+				b := &batchItem{res: common.BatchResult{Url: p.Url}}
+				b.fail(err)
+				items = append(items, b)
+				continue
+			}
+		}
+		if len(urls) == 1 {
+			p.Url = urls[0]
+			items = append(items, &batchItem{m: p, res: common.BatchResult{Url: p.Url}})
+			continue
+		}
+		group := make([]*batchItem, len(urls))
+		for i, url := range urls {
+			q := p
+			q.Url = url
+			group[i] = &batchItem{m: q, res: common.BatchResult{Url: url}}
+			if p.FileName != "" {
+				group[i].fail(errBatchPatternFileName)
+			}
+		}
+		if p.FileName == "" {
+			s.prepareGroup(pool, group, m.MaxParallel, false)
+		}
+		items = append(items, group...)
+	}
+	s.log.Printf("batch: downloading %d items, %d at once\n", len(items), m.MaxParallel)
+	forEachParallel(items, m.MaxParallel, func(b *batchItem) {
+		s.batchDownload(pool, b)
+	})
+	res := batchResponse(items)
+	s.log.Printf("batch: %d completed, %d failed\n", res.Completed, res.Failed)
+	return common.UPDATE_BATCH, res, nil
+}
+
+// prepareGroup adds the downloads of a group, such as the ones
+// expanded from a url pattern, to the manager: each item points to
+// the next one with its ChildHash and all of them are hidden children
+// except the first one if the group has no parent. It returns the
+// hash of the first item added.
+func (s *Api) prepareGroup(pool *server.Pool, group []*batchItem, parallel int, hasParent bool) (head string) {
+	forEachParallel(group, parallel, func(b *batchItem) {
+		if b.res.Error != "" {
+			return
+		}
+		var err error
+		b.d, err = s.newDownloader(pool, s.client, &b.m, b.onError)
+		if err != nil {
+			b.fail(err)
+		}
+	})
+	// the first download created is the parent if the group has none
+	parent := -1
+	for i, b := range group {
+		if b.d != nil {
+			parent = i
+			break
+		}
+	}
+	if parent == -1 {
+		return ""
+	}
+	var childHash string
+	for i := len(group) - 1; i >= parent; i-- {
+		b := group[i]
+		if b.d == nil {
+			continue
+		}
+		b.m.IsChildren = hasParent || i != parent
+		b.m.ChildHash = childHash
+		if err := s.addDownload(b.d, &b.m); err != nil {
+			b.d = nil
+			b.fail(err)
+			continue
+		}
+		childHash = b.d.GetHash()
+	}
+	return childHash
+}
+
+// batchDownload downloads b and waits for it to finish.
+func (s *Api) batchDownload(pool *server.Pool, b *batchItem) {
+	if b.res.Error != "" {
+		return
+	}
+	if b.d == nil {
+		d, err := s.newDownload(pool, s.client, &b.m, b.onError)
+		if err != nil {
+			b.fail(err)
+			return
+		}
+		b.d = d
+	}
+	d := b.d
+	b.res.DownloadId = d.GetHash()
+	b.res.FileName = d.GetFileName()
+	b.res.SavePath = d.GetSavePath()
+	err := d.Start()
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	switch {
+	case err != nil:
+	case b.err != nil:
+		err = b.err
+	case d.IsStopped():
+		err = errBatchDownloadStopped
+	}
+	if err != nil {
+		b.fail(err)
+	}
+}
+
+func batchResponse(items []*batchItem) *common.BatchResponse {
+	res := &common.BatchResponse{
+		Results: make([]common.BatchResult, len(items)),
+	}
+	for i, b := range items {
+		res.Results[i] = b.res
+		if b.res.Error == "" {
+			res.Completed++
+		} else {
+			res.Failed++
+		}
+	}
+	return res
+}
+
+// forEachParallel calls fn for every item, at most n at once.
+func forEachParallel(items []*batchItem, n int, fn func(b *batchItem)) {
+	var (
+		sem = make(chan struct{}, n)
+		wg  sync.WaitGroup
+	)
+	for _, b := range items {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(b *batchItem) {
+			defer func() { <-sem; wg.Done() }()
+			fn(b)
+		}(b)
+	}
+	wg.Wait()
+}
@@ -0,0 +1,107 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+
+	"github.com/warpdl/warpdl/common"
+	"github.com/warpdl/warpdl/internal/server"
+	"github.com/warpdl/warpdl/pkg/warplib"
+)
+
+var errCrawlNoFiles = errors.New("no files found in the directory index")
+
+// crawlHandler crawls a directory index and downloads the files found
+// into the same directory structure. The crawl is added as a group item
+// which tracks the progress of its downloads. It responds once every
+// download has either completed or failed.
+func (s *Api) crawlHandler(sconn *server.SyncConn, pool *server.Pool, body json.RawMessage) (common.UpdateType, any, error) {
+	var m common.CrawlParams
+	if err := json.Unmarshal(body, &m); err != nil {
+		return common.UPDATE_CRAWL, nil, err
+	}
+	if m.MaxParallel <= 0 {
+		m.MaxParallel = DEF_BATCH_PARALLEL
+	}
+	opts := &warplib.CrawlOpts{
+		MaxDepth:   m.MaxDepth,
+		OtherHosts: m.OtherHosts,
+		Extensions: m.Extensions,
+		Headers:    m.Headers,
+	}
+	var err error
+	if m.Include != "" {
+		if opts.Include, err = regexp.Compile(m.Include); err != nil {
+			return common.UPDATE_CRAWL, nil, err
+		}
+	}
+	if m.Exclude != "" {
+		if opts.Exclude, err = regexp.Compile(m.Exclude); err != nil {
+			return common.UPDATE_CRAWL, nil, err
+		}
+	}
+	s.log.Printf("crawl: crawling %s up to depth %d\n", m.Url, m.MaxDepth)
+	files, root, err := warplib.Crawl(context.Background(), s.client, m.Url, opts)
+	if err != nil {
+		return common.UPDATE_CRAWL, nil, err
+	}
+	if len(files) == 0 {
+		return common.UPDATE_CRAWL, nil, errCrawlNoFiles
+	}
+	name := path.Base(root.Path)
+	if name == "/" || name == "." {
+		name = root.Hostname()
+	}
+	if m.DownloadDirectory == "" {
+		// the crawled directory is kept as a whole in the
+		// directory of the category matching its url.
+		if c := s.categories.Match(name, "", m.Url); c != nil {
+			m.DownloadDirectory = c.Directory
+		} else if m.DownloadDirectory, err = filepath.Abs("."); err != nil {
+			return common.UPDATE_CRAWL, nil, err
+		}
+	}
+	dir := filepath.Join(m.DownloadDirectory, name)
+	group, err := s.manager.AddGroup(name, m.Url, m.DownloadDirectory, &warplib.AddDownloadOpts{
+		AbsoluteLocation: m.DownloadDirectory,
+		SourceUrl:        m.Url,
+		Tags:             m.Tags,
+		Note:             m.Note,
+	})
+	if err != nil {
+		return common.UPDATE_CRAWL, nil, err
+	}
+	items := make([]*batchItem, len(files))
+	for i, f := range files {
+		p := m.DownloadParams
+		p.Url = f.Url
+		p.FileName = ""
+		p.DownloadDirectory = filepath.Join(dir, filepath.FromSlash(f.Dir))
+		p.ParentHash = group.Hash
+		// tags are kept so that the files can be flushed along
+		// with the group, the note only describes the group.
+		p.Note = ""
+		items[i] = &batchItem{m: p, res: common.BatchResult{Url: f.Url}}
+		if err = os.MkdirAll(p.DownloadDirectory, os.ModePerm); err != nil {
+			items[i].fail(err)
+		}
+	}
+	s.log.Printf("crawl: downloading %d files into %s\n", len(files), dir)
+	group.ChildHash = s.prepareGroup(pool, items, m.MaxParallel, true)
+	s.manager.UpdateItem(group)
+	forEachParallel(items, m.MaxParallel, func(b *batchItem) {
+		s.batchDownload(pool, b)
+	})
+	res := &common.CrawlResponse{
+		DownloadId:        group.Hash,
+		DownloadDirectory: dir,
+		BatchResponse:     *batchResponse(items),
+	}
+	s.log.Printf("crawl: %d completed, %d failed\n", res.Completed, res.Failed)
+	return common.UPDATE_CRAWL, res, nil
+}
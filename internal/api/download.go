@@ -2,6 +2,8 @@ package api
 
 import (
 	"encoding/json"
+	"net/http"
+	"time"
 
 	"github.com/warpdl/warpdl/common"
 	"github.com/warpdl/warpdl/internal/server"
@@ -13,98 +15,102 @@ func (s *Api) downloadHandler(sconn *server.SyncConn, pool *server.Pool, body js
 	if err := json.Unmarshal(body, &m); err != nil {
 		return common.UPDATE_DOWNLOAD, nil, err
 	}
-	var (
-		d *warplib.Downloader
-	)
+	d, err := s.newDownload(pool, s.client, &m, nil)
+	if err != nil {
+		return common.UPDATE_DOWNLOAD, nil, err
+	}
+	if m.Schedule != nil {
+		s.schedMu.Lock()
+		defer s.schedMu.Unlock()
+		ok, limit := m.Schedule.Allowed(time.Now())
+		if !ok {
+			// scheduler starts it once allowed
+			res := downloadResponse(d)
+			res.Scheduled = true
+			return common.UPDATE_DOWNLOAD, res, nil
+		}
+		d.SetSpeedLimit(limit)
+		s.started[d.GetHash()] = true
+	}
+	pool.AddDownload(d.GetHash(), sconn)
+	// todo: handle download start error
+	go d.Start()
+	return common.UPDATE_DOWNLOAD, downloadResponse(d), nil
+}
+
+// newDownload creates a downloader for m and adds it to the manager,
+// onError is called with errors of the download if it isn't nil.
+func (s *Api) newDownload(pool *server.Pool, client *http.Client, m *common.DownloadParams, onError func(err error)) (d *warplib.Downloader, err error) {
+	d, err = s.newDownloader(pool, client, m, onError)
+	if err != nil {
+		return
+	}
+	err = s.addDownload(d, m)
+	return
+}
+
+// newDownloader creates a downloader for m without adding it to the
+// manager, client is used for the requests of the download.
+func (s *Api) newDownloader(pool *server.Pool, client *http.Client, m *common.DownloadParams, onError func(err error)) (d *warplib.Downloader, err error) {
+	if m.ConflictPolicy == "" {
+		m.ConflictPolicy = s.conflictPolicy
+	}
 	url, err := s.elEngine.Extract(m.Url)
 	if err != nil {
 		s.log.Printf("failed to extract URL from extension: %s\n", err.Error())
 		url = m.Url
 	}
-	d, err = warplib.NewDownloader(s.client, url, &warplib.DownloaderOpts{
+	d, err = warplib.NewDownloader(client, url, &warplib.DownloaderOpts{
 		Headers:           m.Headers,
 		ForceParts:        m.ForceParts,
 		FileName:          m.FileName,
 		DownloadDirectory: m.DownloadDirectory,
+		Categories:        s.categories,
 		MaxConnections:    m.MaxConnections,
 		MaxSegments:       m.MaxSegments,
-		Handlers: &warplib.Handlers{
-			ErrorHandler: func(_ string, err error) {
-				uid := d.GetHash()
-				pool.Broadcast(uid, server.InitError(err))
-				pool.WriteError(uid, server.ErrorTypeCritical, err.Error())
-				pool.StopDownload(uid)
-				d.Stop()
-			},
-			DownloadProgressHandler: func(hash string, nread int) {
-				uid := d.GetHash()
-				pool.Broadcast(uid, server.MakeResult(common.UPDATE_DOWNLOADING, &common.DownloadingResponse{
-					DownloadId: uid,
-					Action:     common.DownloadProgress,
-					Value:      int64(nread),
-					Hash:       hash,
-				}))
-			},
-			DownloadCompleteHandler: func(hash string, tread int64) {
-				uid := d.GetHash()
-				pool.Broadcast(uid, server.MakeResult(common.UPDATE_DOWNLOADING, &common.DownloadingResponse{
-					DownloadId: uid,
-					Action:     common.DownloadComplete,
-					Value:      tread,
-					Hash:       hash,
-				}))
-			},
-			DownloadStoppedHandler: func() {
-				uid := d.GetHash()
-				pool.Broadcast(uid, server.MakeResult(common.UPDATE_DOWNLOADING, &common.DownloadingResponse{
-					DownloadId: uid,
-					Action:     common.DownloadStopped,
-				}))
-			},
-			CompileStartHandler: func(hash string) {
-				uid := d.GetHash()
-				pool.Broadcast(uid, server.MakeResult(common.UPDATE_DOWNLOADING, &common.DownloadingResponse{
-					DownloadId: uid,
-					Action:     common.CompileStart,
-					Hash:       hash,
-				}))
-			},
-			CompileProgressHandler: func(hash string, nread int) {
-				uid := d.GetHash()
-				pool.Broadcast(uid, server.MakeResult(common.UPDATE_DOWNLOADING, &common.DownloadingResponse{
-					DownloadId: uid,
-					Action:     common.CompileProgress,
-					Value:      int64(nread),
-					Hash:       hash,
-				}))
-			},
-			CompileCompleteHandler: func(hash string, tread int64) {
-				uid := d.GetHash()
-				pool.Broadcast(uid, server.MakeResult(common.UPDATE_DOWNLOADING, &common.DownloadingResponse{
-					DownloadId: uid,
-					Action:     common.CompileComplete,
-					Value:      tread,
-					Hash:       hash,
-				}))
-			},
-		},
+		ConflictPolicy:    m.ConflictPolicy,
+		DecodeContent:     m.DecodeContent,
+		RaceTail:          m.RaceTail,
+		RangeStart:        m.RangeStart,
+		RangeEnd:          m.RangeEnd,
+		Handlers:          s.downloadHandlers(pool, func() string { return d.GetHash() }, func() { d.Stop() }, onError),
 	})
-	if err != nil {
-		return common.UPDATE_DOWNLOAD, nil, err
-	}
-	pool.AddDownload(d.GetHash(), sconn)
-	err = s.manager.AddDownload(d, &warplib.AddDownloadOpts{
+	return
+}
+
+// webDownload creates a download captured by the browser extension
+// like the ones of the API, client carries the cookies of its page.
+func (s *Api) webDownload(pool *server.Pool, client *http.Client, url string, headers warplib.Headers) (*warplib.Downloader, error) {
+	return s.newDownload(pool, client, &common.DownloadParams{
+		Url:            url,
+		Headers:        headers,
+		MaxConnections: 24,
+		MaxSegments:    200,
+	}, nil)
+}
+
+func (s *Api) addDownload(d *warplib.Downloader, m *common.DownloadParams) error {
+	return s.manager.AddDownload(d, &warplib.AddDownloadOpts{
 		ChildHash:        m.ChildHash,
 		IsHidden:         m.IsHidden,
 		IsChildren:       m.IsChildren,
 		AbsoluteLocation: d.GetDownloadDirectory(),
+		SourceUrl:        m.Url,
+		ParentHash:       m.ParentHash,
+		Schedule:         m.Schedule,
+		Extract:          m.Extract,
+		Tags:             m.Tags,
+		Note:             m.Note,
 	})
-	if err != nil {
-		return common.UPDATE_DOWNLOAD, nil, err
+}
+
+func downloadResponse(d *warplib.Downloader) *common.DownloadResponse {
+	rangeStart, _ := d.GetRange()
+	var category string
+	if c := d.GetCategory(); c != nil {
+		category = c.Name
 	}
-	// todo: handle download start error
-	go d.Start()
-	return common.UPDATE_DOWNLOAD, &common.DownloadResponse{
+	return &common.DownloadResponse{
 		ContentLength:     d.GetContentLength(),
 		DownloadId:        d.GetHash(),
 		FileName:          d.GetFileName(),
@@ -112,5 +118,8 @@ func (s *Api) downloadHandler(sconn *server.SyncConn, pool *server.Pool, body js
 		DownloadDirectory: d.GetDownloadDirectory(),
 		MaxConnections:    d.GetMaxConnections(),
 		MaxSegments:       d.GetMaxParts(),
-	}, nil
+		Ranged:            d.IsRanged(),
+		RangeStart:        rangeStart,
+		Category:          category,
+	}
 }
@@ -2,9 +2,11 @@ package api
 
 import (
 	"encoding/json"
+	"errors"
 
 	"github.com/warpdl/warpdl/common"
 	"github.com/warpdl/warpdl/internal/server"
+	"github.com/warpdl/warpdl/pkg/warplib"
 )
 
 func (s *Api) flushHandler(sconn *server.SyncConn, pool *server.Pool, body json.RawMessage) (common.UpdateType, any, error) {
@@ -13,10 +15,32 @@ func (s *Api) flushHandler(sconn *server.SyncConn, pool *server.Pool, body json.
 	if err = json.Unmarshal(body, &m); err != nil {
 		return common.UPDATE_FLUSH, nil, err
 	}
-	if m.DownloadId == "" {
+	switch {
+	case m.Tag != "":
+		return common.UPDATE_FLUSH, s.flushTag(m.Tag), nil
+	case m.DownloadId == "":
 		s.manager.Flush()
-	} else {
+	default:
 		err = s.manager.FlushOne(m.DownloadId)
 	}
 	return common.UPDATE_FLUSH, nil, err
 }
+
+// flushTag flushes the downloads with the tag, skipping
+// the running ones like a flush of all downloads.
+func (s *Api) flushTag(tag string) *common.TaggedResponse {
+	r := &common.TaggedResponse{DownloadIds: []string{}}
+	for _, item := range s.manager.GetItems() {
+		if !item.HasTag(tag) {
+			continue
+		}
+		err := s.manager.FlushOne(item.Hash)
+		switch {
+		case err == nil:
+			r.DownloadIds = append(r.DownloadIds, item.Hash)
+		case !errors.Is(err, warplib.ErrFlushItemDownloading):
+			s.log.Printf("failed to flush %s: %s\n", item.Hash, err.Error())
+		}
+	}
+	return r
+}
@@ -1,7 +1,13 @@
 package api
 
 import (
+	"cmp"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
 
 	"github.com/warpdl/warpdl/common"
 	"github.com/warpdl/warpdl/internal/server"
@@ -13,6 +19,10 @@ func (s *Api) listHandler(sconn *server.SyncConn, pool *server.Pool, body json.R
 	if err := json.Unmarshal(body, &m); err != nil {
 		return common.UPDATE_LIST, nil, err
 	}
+	keep, err := listFilter(&m)
+	if err != nil {
+		return common.UPDATE_LIST, nil, err
+	}
 	var items []*warplib.Item
 	switch {
 	case m.ShowCompleted && m.ShowPending:
@@ -22,7 +32,164 @@ func (s *Api) listHandler(sconn *server.SyncConn, pool *server.Pool, body json.R
 	default:
 		items = s.manager.GetIncompleteItems()
 	}
+	stats := s.itemStats()
+	items = filterItems(items, func(item *warplib.Item) bool {
+		return keep(item, stats[item.Hash])
+	})
+	err = sortItems(items, stats, m.SortBy)
+	if err != nil {
+		return common.UPDATE_LIST, nil, err
+	}
+	if m.Reverse {
+		slices.Reverse(items)
+	}
+	total := len(items)
+	items = items[min(m.Offset, total):]
+	if m.Limit > 0 && m.Limit < len(items) {
+		items = items[:m.Limit]
+	}
+	listed := make(map[string]*common.ItemStats, len(items))
+	for _, item := range items {
+		listed[item.Hash] = stats[item.Hash]
+		if m.ShowSegments {
+			listed[item.Hash].Segments = item.GetSegments()
+		}
+	}
 	return common.UPDATE_LIST, &common.ListResponse{
 		Items: items,
+		Stats: listed,
+		Total: total,
 	}, nil
 }
+
+// itemStats returns the stats of all the items by their hash.
+func (s *Api) itemStats() map[string]*common.ItemStats {
+	items := s.manager.GetItems()
+	stats := make(map[string]*common.ItemStats, len(items))
+	for _, item := range items {
+		conns, limit := item.GetConnections()
+		stats[item.Hash] = &common.ItemStats{
+			State:           item.State(),
+			Speed:           item.GetSpeed(),
+			Connections:     conns,
+			ConnectionLimit: limit,
+		}
+	}
+	for _, item := range items {
+		group, ok := stats[item.ParentHash]
+		if !ok || stats[item.Hash].State != warplib.StateDownloading {
+			continue
+		}
+		group.State = warplib.StateDownloading
+		group.Speed += stats[item.Hash].Speed
+		group.Connections += stats[item.Hash].Connections
+	}
+	return stats
+}
+
+// listFilter returns whether an item is listed
+// according to the filters of m.
+func listFilter(m *common.ListParams) (func(*warplib.Item, *common.ItemStats) bool, error) {
+	if m.Offset < 0 || m.Limit < 0 {
+		return nil, errors.New("offset and limit can't be negative")
+	}
+	for _, state := range m.States {
+		if !slices.Contains(warplib.ItemStates, state) {
+			return nil, fmt.Errorf("%w: %s", warplib.ErrInvalidItemState, state)
+		}
+	}
+	var nameRe *regexp.Regexp
+	if m.NameRegex != "" {
+		var err error
+		nameRe, err = regexp.Compile(m.NameRegex)
+		if err != nil {
+			return nil, fmt.Errorf("invalid name regex: %w", err)
+		}
+	}
+	name := strings.ToLower(m.Name)
+	return func(item *warplib.Item, stats *common.ItemStats) bool {
+		switch {
+		case !m.ShowHidden && (item.Hidden || item.Children):
+			return false
+		case m.Category != "" && !strings.EqualFold(item.Category, m.Category):
+			return false
+		case m.Tag != "" && !item.HasTag(m.Tag):
+			return false
+		case len(m.States) != 0 && !slices.Contains(m.States, stats.State):
+			return false
+		case m.Host != "" && !warplib.MatchHost(item.Url, m.Host):
+			return false
+		case name != "" && !strings.Contains(strings.ToLower(item.Name), name):
+			return false
+		case nameRe != nil && !nameRe.MatchString(item.Name):
+			return false
+		case !m.AddedAfter.IsZero() && item.DateAdded.Before(m.AddedAfter):
+			return false
+		case !m.AddedBefore.IsZero() && !item.DateAdded.Before(m.AddedBefore):
+			return false
+		case (m.MinSize != 0 || m.MaxSize != 0) && item.TotalSize.IsUnknown():
+			return false
+		case m.MinSize != 0 && int64(item.TotalSize) < m.MinSize:
+			return false
+		case m.MaxSize != 0 && int64(item.TotalSize) > m.MaxSize:
+			return false
+		}
+		return true
+	}, nil
+}
+
+// filterItems returns the items for which keep returns true.
+func filterItems(items []*warplib.Item, keep func(*warplib.Item) bool) []*warplib.Item {
+	kept := make([]*warplib.Item, 0, len(items))
+	for _, item := range items {
+		if keep(item) {
+			kept = append(kept, item)
+		}
+	}
+	return kept
+}
+
+// sortItems sorts the items in ascending order of by,
+// items which compare equal are sorted by date added.
+func sortItems(items []*warplib.Item, stats map[string]*common.ItemStats, by common.ListSort) error {
+	var compare func(a, b *warplib.Item) int
+	switch by {
+	case "", common.SortByDate:
+	case common.SortByName:
+		compare = func(a, b *warplib.Item) int {
+			return cmp.Compare(strings.ToLower(a.Name), strings.ToLower(b.Name))
+		}
+	case common.SortBySize:
+		compare = func(a, b *warplib.Item) int {
+			return cmp.Compare(a.TotalSize, b.TotalSize)
+		}
+	case common.SortByProgress:
+		compare = func(a, b *warplib.Item) int {
+			return cmp.Compare(progress(a), progress(b))
+		}
+	case common.SortBySpeed:
+		compare = func(a, b *warplib.Item) int {
+			return cmp.Compare(stats[a.Hash].Speed, stats[b.Hash].Speed)
+		}
+	default:
+		return fmt.Errorf("invalid sort order %q", by)
+	}
+	slices.SortFunc(items, func(a, b *warplib.Item) int {
+		if compare != nil {
+			if c := compare(a, b); c != 0 {
+				return c
+			}
+		}
+		return cmp.Or(a.DateAdded.Compare(b.DateAdded), cmp.Compare(a.Hash, b.Hash))
+	})
+	return nil
+}
+
+// progress returns the downloaded fraction of the item,
+// 0 if its size is unknown.
+func progress(item *warplib.Item) float64 {
+	if item.TotalSize <= 0 {
+		return 0
+	}
+	return float64(item.Downloaded) / float64(item.TotalSize)
+}
@@ -0,0 +1,51 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+
+	"github.com/warpdl/warpdl/common"
+	"github.com/warpdl/warpdl/internal/server"
+	"github.com/warpdl/warpdl/pkg/warplib"
+)
+
+// refreshUrl swaps the url of a download with the provided one.
+// If url is empty, the url is re-extracted from the item's source
+// url using the matching extension.
+func (s *Api) refreshUrl(hash, url string, headers warplib.Headers) (*warplib.Item, error) {
+	if url == "" {
+		item := s.manager.GetItem(hash)
+		if item == nil {
+			return nil, warplib.ErrDownloadNotFound
+		}
+		src := item.SourceUrl
+		if src == "" {
+			src = item.Url
+		}
+		var err error
+		url, err = s.elEngine.Extract(src)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return s.manager.RefreshUrl(s.client, hash, url, headers)
+}
+
+func (s *Api) refreshUrlHandler(sconn *server.SyncConn, pool *server.Pool, body json.RawMessage) (common.UpdateType, any, error) {
+	var m common.RefreshUrlParams
+	if err := json.Unmarshal(body, &m); err != nil {
+		return common.UPDATE_REFRESH_URL, nil, err
+	}
+	if m.DownloadId == "" {
+		return common.UPDATE_REFRESH_URL, nil, errors.New("download_id is required")
+	}
+	item, err := s.refreshUrl(m.DownloadId, m.Url, m.Headers)
+	if err != nil {
+		return common.UPDATE_REFRESH_URL, nil, err
+	}
+	return common.UPDATE_REFRESH_URL, &common.RefreshUrlResponse{
+		DownloadId:   item.Hash,
+		Url:          item.Url,
+		EffectiveUrl: item.EffectiveUrl,
+	}, nil
+}
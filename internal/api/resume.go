@@ -8,80 +8,12 @@ import (
 	"github.com/warpdl/warpdl/pkg/warplib"
 )
 
-func getHandler(pool *server.Pool, uidPtr *string, stopDownloadPtr *func() error) *warplib.Handlers {
-	return &warplib.Handlers{
-		ErrorHandler: func(_ string, err error) {
-			uid := *uidPtr
-			pool.Broadcast(uid, server.InitError(err))
-			pool.WriteError(uid, server.ErrorTypeCritical, err.Error())
-			pool.StopDownload(uid)
-			(*stopDownloadPtr)()
-		},
-		ResumeProgressHandler: func(hash string, nread int) {
-			uid := *uidPtr
-			pool.Broadcast(uid, server.MakeResult(common.UPDATE_DOWNLOADING, &common.DownloadingResponse{
-				DownloadId: uid,
-				Action:     common.ResumeProgress,
-				Value:      int64(nread),
-				Hash:       hash,
-			}))
-		},
-		DownloadProgressHandler: func(hash string, nread int) {
-			uid := *uidPtr
-			pool.Broadcast(uid, server.MakeResult(common.UPDATE_DOWNLOADING, &common.DownloadingResponse{
-				DownloadId: uid,
-				Action:     common.DownloadProgress,
-				Value:      int64(nread),
-				Hash:       hash,
-			}))
-		},
-		DownloadCompleteHandler: func(hash string, tread int64) {
-			uid := *uidPtr
-			pool.Broadcast(uid, server.MakeResult(common.UPDATE_DOWNLOADING, &common.DownloadingResponse{
-				DownloadId: uid,
-				Action:     common.DownloadComplete,
-				Value:      tread,
-				Hash:       hash,
-			}))
-		},
-		DownloadStoppedHandler: func() {
-			uid := *uidPtr
-			pool.Broadcast(uid, server.MakeResult(common.UPDATE_DOWNLOADING, &common.DownloadingResponse{
-				DownloadId: uid,
-				Action:     common.DownloadStopped,
-			}))
-		},
-		CompileStartHandler: func(hash string) {
-			uid := *uidPtr
-			pool.Broadcast(uid, server.MakeResult(common.UPDATE_DOWNLOADING, &common.DownloadingResponse{
-				DownloadId: uid,
-				Action:     common.CompileStart,
-				Hash:       hash,
-			}))
-		},
-		CompileProgressHandler: func(hash string, nread int) {
-			uid := *uidPtr
-			pool.Broadcast(uid, server.MakeResult(common.UPDATE_DOWNLOADING, &common.DownloadingResponse{
-				DownloadId: uid,
-				Action:     common.CompileProgress,
-				Value:      int64(nread),
-				Hash:       hash,
-			}))
-		},
-		CompileCompleteHandler: func(hash string, tread int64) {
-			uid := *uidPtr
-			pool.Broadcast(uid, server.MakeResult(common.UPDATE_DOWNLOADING, &common.DownloadingResponse{
-				DownloadId: uid,
-				Action:     common.CompileComplete,
-				Value:      tread,
-				Hash:       hash,
-			}))
-		},
-	}
+func (s *Api) getHandler(pool *server.Pool, uidPtr *string, stopDownloadPtr *func() error) *warplib.Handlers {
+	return s.downloadHandlers(pool, func() string { return *uidPtr }, func() { (*stopDownloadPtr)() }, nil)
 }
 
 func resumeItem(i *warplib.Item) error {
-	if i.Downloaded >= i.TotalSize {
+	if !i.TotalSize.IsUnknown() && i.Downloaded >= i.TotalSize {
 		return nil
 	}
 	return i.Resume()
@@ -94,37 +26,74 @@ func (s *Api) resumeHandler(sconn *server.SyncConn, pool *server.Pool, body json
 	if err := json.Unmarshal(body, &m); err != nil {
 		return common.UPDATE_RESUME, nil, err
 	}
+	if m.Tag != "" {
+		return common.UPDATE_RESUME, s.resumeTag(pool, &m), nil
+	}
+	r, err := s.resume(sconn, pool, &m)
+	if err != nil {
+		return common.UPDATE_RESUME, nil, err
+	}
+	return common.UPDATE_RESUME, r, nil
+}
+
+// resume resumes the download of m.DownloadId, its progress is
+// sent to sconn. Groups are resumed in background.
+func (s *Api) resume(sconn *server.SyncConn, pool *server.Pool, m *common.ResumeParams) (*common.ResumeResponse, error) {
 	var (
 		err          error
 		item         *warplib.Item
 		hash         = &m.DownloadId
-		stopDownload = &__stop
+		stop         = __stop
+		stopDownload = &stop
 	)
+	if item = s.manager.GetItem(m.DownloadId); item != nil && item.Group {
+		go s.resumeGroup(nil, pool, item.ChildHash, m)
+		return &common.ResumeResponse{
+			ChildHash:         item.ChildHash,
+			ContentLength:     item.TotalSize,
+			FileName:          item.Name,
+			SavePath:          item.GetSavePath(),
+			DownloadDirectory: item.DownloadLocation,
+			AbsoluteLocation:  item.AbsoluteLocation,
+			Group:             true,
+		}, nil
+	}
+	if m.Url != "" || m.RefreshUrl {
+		_, err = s.refreshUrl(m.DownloadId, m.Url, m.Headers)
+		if err != nil {
+			return nil, err
+		}
+	}
 	item, err = s.manager.ResumeDownload(s.client, m.DownloadId, &warplib.ResumeDownloadOpts{
 		Headers:        m.Headers,
 		ForceParts:     m.ForceParts,
 		MaxConnections: m.MaxConnections,
 		MaxSegments:    m.MaxSegments,
-		Handlers:       getHandler(pool, hash, stopDownload),
+		RaceTail:       m.RaceTail,
+		Handlers:       s.getHandler(pool, hash, stopDownload),
 	})
 	if err != nil {
-		return common.UPDATE_RESUME, nil, err
+		return nil, err
 	}
 	pool.AddDownload(m.DownloadId, sconn)
 	*hash = item.Hash
 	*stopDownload = item.StopDownload
 	var cItem *warplib.Item
 	if item.ChildHash != "" {
-		var cStopDownload = &__stop
+		var (
+			cStop         = __stop
+			cStopDownload = &cStop
+		)
 		cItem, err = s.manager.ResumeDownload(s.client, item.ChildHash, &warplib.ResumeDownloadOpts{
 			Headers:        m.Headers,
 			ForceParts:     m.ForceParts,
 			MaxConnections: m.MaxConnections,
 			MaxSegments:    m.MaxSegments,
-			Handlers:       getHandler(pool, &item.ChildHash, cStopDownload),
+			RaceTail:       m.RaceTail,
+			Handlers:       s.getHandler(pool, &item.ChildHash, cStopDownload),
 		})
 		if err != nil {
-			return common.UPDATE_RESUME, nil, err
+			return nil, err
 		}
 		pool.AddDownload(item.ChildHash, sconn)
 		*cStopDownload = cItem.StopDownload
@@ -134,10 +103,13 @@ func (s *Api) resumeHandler(sconn *server.SyncConn, pool *server.Pool, body json
 	go resumeItem(item)
 	if cItem != nil {
 		go resumeItem(cItem)
+		if cItem.ChildHash != "" {
+			go s.resumeGroup(sconn, pool, cItem.ChildHash, m)
+		}
 	}
 	maxConn, _ := item.GetMaxConnections()
 	maxParts, _ := item.GetMaxParts()
-	return common.UPDATE_RESUME, &common.ResumeResponse{
+	return &common.ResumeResponse{
 		ChildHash:         item.ChildHash,
 		ContentLength:     item.TotalSize,
 		FileName:          item.Name,
@@ -148,3 +120,61 @@ func (s *Api) resumeHandler(sconn *server.SyncConn, pool *server.Pool, body json
 		MaxSegments:       maxParts,
 	}, nil
 }
+
+// resumeGroup resumes the downloads of a group one by one
+// starting from hash, following their ChildHash.
+func (s *Api) resumeGroup(sconn *server.SyncConn, pool *server.Pool, hash string, m *common.ResumeParams) {
+	for hash != "" {
+		item := s.manager.GetItem(hash)
+		if item == nil {
+			return
+		}
+		next := item.ChildHash
+		if !item.IsDownloading() && (item.TotalSize.IsUnknown() || item.Downloaded < item.TotalSize) {
+			var (
+				uid  = hash
+				stop = __stop
+			)
+			item, err := s.manager.ResumeDownload(s.client, uid, &warplib.ResumeDownloadOpts{
+				Headers:        m.Headers,
+				ForceParts:     m.ForceParts,
+				MaxConnections: m.MaxConnections,
+				MaxSegments:    m.MaxSegments,
+				RaceTail:       m.RaceTail,
+				Handlers:       s.getHandler(pool, &uid, &stop),
+			})
+			if err != nil {
+				s.log.Printf("failed to resume %s of the group: %s\n", uid, err.Error())
+			} else {
+				pool.AddDownload(uid, sconn)
+				stop = item.StopDownload
+				resumeItem(item)
+			}
+		}
+		hash = next
+	}
+}
+
+// resumeTag resumes the incomplete downloads with the tag which
+// aren't running in background, files of a group with the tag
+// are resumed by their group.
+func (s *Api) resumeTag(pool *server.Pool, m *common.ResumeParams) *common.TaggedResponse {
+	r := &common.TaggedResponse{DownloadIds: []string{}}
+	for _, item := range s.manager.GetItems() {
+		if !item.HasTag(m.Tag) || item.Children || item.IsDownloading() ||
+			!item.Group && !item.TotalSize.IsUnknown() && item.Downloaded >= item.TotalSize {
+			continue
+		}
+		if parent := s.manager.GetItem(item.ParentHash); parent != nil && parent.HasTag(m.Tag) {
+			continue
+		}
+		p := *m
+		p.DownloadId, p.Tag = item.Hash, ""
+		if _, err := s.resume(nil, pool, &p); err != nil {
+			s.log.Printf("failed to resume %s: %s\n", item.Hash, err.Error())
+			continue
+		}
+		r.DownloadIds = append(r.DownloadIds, item.Hash)
+	}
+	return r
+}
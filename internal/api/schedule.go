@@ -0,0 +1,95 @@
+package api
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/warpdl/warpdl/common"
+	"github.com/warpdl/warpdl/internal/server"
+	"github.com/warpdl/warpdl/pkg/warplib"
+)
+
+// DEF_SCHEDULE_INTERVAL is how often the scheduler
+// checks the schedules of the downloads.
+const DEF_SCHEDULE_INTERVAL = 15 * time.Second
+
+// scheduleHandler sets or removes the schedule of a download, the
+// scheduler applies it on its next check.
+func (s *Api) scheduleHandler(sconn *server.SyncConn, pool *server.Pool, body json.RawMessage) (common.UpdateType, any, error) {
+	var m common.ScheduleParams
+	if err := json.Unmarshal(body, &m); err != nil {
+		return common.UPDATE_SCHEDULE, nil, err
+	}
+	item, err := s.manager.SetSchedule(m.DownloadId, m.Schedule)
+	if err != nil {
+		return common.UPDATE_SCHEDULE, nil, err
+	}
+	if m.Schedule == nil && item.IsDownloading() {
+		// limits of the removed schedule don't apply anymore
+		item.SetSpeedLimit(0)
+	}
+	go s.schedule(pool, time.Now())
+	return common.UPDATE_SCHEDULE, &common.ScheduleResponse{
+		DownloadId: item.Hash,
+		FileName:   item.Name,
+		Schedule:   item.Schedule,
+	}, nil
+}
+
+// RunScheduler starts, pauses and limits the speed of the scheduled
+// downloads according to their schedules, it never returns.
+func (s *Api) RunScheduler(pool *server.Pool) {
+	ticker := time.NewTicker(DEF_SCHEDULE_INTERVAL)
+	defer ticker.Stop()
+	for {
+		s.schedule(pool, time.Now())
+		<-ticker.C
+	}
+}
+
+func (s *Api) schedule(pool *server.Pool, now time.Time) {
+	s.schedMu.Lock()
+	defer s.schedMu.Unlock()
+	for _, item := range s.manager.GetItems() {
+		if item.Schedule == nil || item.Group || item.Failure != "" ||
+			!item.TotalSize.IsUnknown() && item.Downloaded >= item.TotalSize {
+			continue
+		}
+		ok, limit := item.Schedule.Allowed(now)
+		switch running := item.IsDownloading(); {
+		case running && ok:
+			item.SetSpeedLimit(limit)
+		case running:
+			s.log.Printf("schedule: pausing %s outside of its time windows\n", item.Hash)
+			item.StopDownload()
+		case ok:
+			if s.started[item.Hash] && !item.IsStopped() {
+				// still starting up or it failed
+				continue
+			}
+			s.startScheduled(pool, item.Hash, limit)
+		}
+	}
+}
+
+// startScheduled resumes the download of the item with the hash,
+// s.schedMu must be held.
+func (s *Api) startScheduled(pool *server.Pool, hash string, limit int64) {
+	var (
+		uid  = hash
+		stop = __stop
+	)
+	item, err := s.manager.ResumeDownload(s.client, hash, &warplib.ResumeDownloadOpts{
+		SpeedLimit: limit,
+		Handlers:   s.getHandler(pool, &uid, &stop),
+	})
+	if err != nil {
+		s.log.Printf("schedule: failed to start %s: %s\n", hash, err.Error())
+		return
+	}
+	s.log.Printf("schedule: starting %s\n", hash)
+	pool.AddDownload(hash, nil)
+	stop = item.StopDownload
+	s.started[hash] = true
+	go resumeItem(item)
+}
@@ -5,16 +5,20 @@ import (
 	"errors"
 
 	"github.com/warpdl/warpdl/common"
+	"github.com/warpdl/warpdl/pkg/warplib"
 
 	"github.com/warpdl/warpdl/internal/server"
 )
 
 func (s *Api) stopHandler(sconn *server.SyncConn, pool *server.Pool, body json.RawMessage) (common.UpdateType, any, error) {
-	var m common.InputDownloadId
+	var m common.StopParams
 	var err error
 	if err = json.Unmarshal(body, &m); err != nil {
 		return common.UPDATE_STOP, nil, err
 	}
+	if m.Tag != "" {
+		return common.UPDATE_STOP, s.stopTag(pool, m.Tag), nil
+	}
 	if m.DownloadId == "" {
 		return common.UPDATE_STOP, nil, errors.New("download_id is required")
 	}
@@ -25,6 +29,29 @@ func (s *Api) stopHandler(sconn *server.SyncConn, pool *server.Pool, body json.R
 	if !pool.HasDownload(m.DownloadId) {
 		return common.UPDATE_STOP, nil, errors.New("download not running")
 	}
+	return common.UPDATE_STOP, nil, s.stopItem(item)
+}
+
+// stopTag stops the running downloads with the tag.
+func (s *Api) stopTag(pool *server.Pool, tag string) *common.TaggedResponse {
+	r := &common.TaggedResponse{DownloadIds: []string{}}
+	for _, item := range s.manager.GetItems() {
+		if !item.HasTag(tag) || !pool.HasDownload(item.Hash) || !item.IsDownloading() {
+			continue
+		}
+		if err := s.stopItem(item); err != nil {
+			s.log.Printf("failed to stop %s: %s\n", item.Hash, err.Error())
+		}
+		r.DownloadIds = append(r.DownloadIds, item.Hash)
+	}
+	return r
+}
+
+func (s *Api) stopItem(item *warplib.Item) (err error) {
+	if item.Schedule != nil {
+		// otherwise scheduler would start it again
+		_, err = s.manager.SetSchedule(item.Hash, nil)
+	}
 	item.StopDownload()
-	return common.UPDATE_STOP, nil, err
+	return
 }
@@ -0,0 +1,36 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+
+	"github.com/warpdl/warpdl/common"
+	"github.com/warpdl/warpdl/internal/server"
+)
+
+// subscribeHandler sends the updates of all the downloads
+// to the connection, the connection can't invoke other
+// methods afterwards as updates may precede responses.
+func (s *Api) subscribeHandler(sconn *server.SyncConn, pool *server.Pool, _ json.RawMessage) (common.UpdateType, any, error) {
+	pool.Subscribe(sconn)
+	return common.UPDATE_SUBSCRIBE, &common.SubscribeResponse{Subscribed: true}, nil
+}
+
+func (s *Api) connectionsHandler(sconn *server.SyncConn, pool *server.Pool, body json.RawMessage) (common.UpdateType, any, error) {
+	var m common.ConnectionsParams
+	if err := json.Unmarshal(body, &m); err != nil {
+		return common.UPDATE_CONNECTIONS, nil, err
+	}
+	item := s.manager.GetItem(m.DownloadId)
+	if item == nil {
+		return common.UPDATE_CONNECTIONS, nil, errors.New("download not found")
+	}
+	if !pool.HasDownload(m.DownloadId) || !item.IsDownloading() {
+		return common.UPDATE_CONNECTIONS, nil, errors.New("download not running")
+	}
+	n, err := item.SetMaxConnections(m.MaxConnections)
+	if err != nil {
+		return common.UPDATE_CONNECTIONS, nil, err
+	}
+	return common.UPDATE_CONNECTIONS, &common.ConnectionsResponse{MaxConnections: n}, nil
+}
@@ -0,0 +1,32 @@
+package api
+
+import (
+	"encoding/json"
+
+	"github.com/warpdl/warpdl/common"
+	"github.com/warpdl/warpdl/internal/server"
+)
+
+// tagHandler adds and removes the tags of a download and sets its note.
+func (s *Api) tagHandler(sconn *server.SyncConn, pool *server.Pool, body json.RawMessage) (common.UpdateType, any, error) {
+	var m common.TagParams
+	if err := json.Unmarshal(body, &m); err != nil {
+		return common.UPDATE_TAG, nil, err
+	}
+	item, err := s.manager.UpdateTags(m.DownloadId, m.Add, m.Remove)
+	if err != nil {
+		return common.UPDATE_TAG, nil, err
+	}
+	if m.Note != nil {
+		item, err = s.manager.SetNote(m.DownloadId, *m.Note)
+		if err != nil {
+			return common.UPDATE_TAG, nil, err
+		}
+	}
+	return common.UPDATE_TAG, &common.TagResponse{
+		DownloadId: item.Hash,
+		FileName:   item.Name,
+		Tags:       item.Tags,
+		Note:       item.Note,
+	}, nil
+}
@@ -0,0 +1,87 @@
+package api
+
+import (
+	"sync/atomic"
+
+	"github.com/warpdl/warpdl/common"
+	"github.com/warpdl/warpdl/internal/server"
+	"github.com/warpdl/warpdl/internal/webhook"
+	"github.com/warpdl/warpdl/pkg/warplib"
+)
+
+// downloadHandlers returns the handlers of a download which broadcast
+// its events to the pool and notify the webhooks, onError is called
+// with errors of the download if it isn't nil.
+func (s *Api) downloadHandlers(pool *server.Pool, uid func() string, stop func(), onError func(err error)) *warplib.Handlers {
+	h := pool.DownloadHandlers(uid, stop)
+	if onError != nil {
+		oEH := h.ErrorHandler
+		h.ErrorHandler = func(hash string, err error) {
+			onError(err)
+			oEH(hash, err)
+		}
+	}
+	return s.notifyHandlers(h, uid)
+}
+
+// notifyHandlers patches the handlers of a download to notify the
+// webhooks of its lifecycle events, uid returns the download id.
+func (s *Api) notifyHandlers(h *warplib.Handlers, uid func() string) *warplib.Handlers {
+	if s.webhooks == nil {
+		return h
+	}
+	// a failed download is stopped afterwards
+	var failed int32
+	oDSH := h.DownloadStartedHandler
+	h.DownloadStartedHandler = func() {
+		atomic.StoreInt32(&failed, 0)
+		s.notify(uid(), common.DownloadStarted, 0, nil)
+		if oDSH != nil {
+			oDSH()
+		}
+	}
+	oDCH := h.DownloadCompleteHandler
+	h.DownloadCompleteHandler = func(hash string, tread int64) {
+		if hash == warplib.MAIN_HASH {
+			s.notify(uid(), common.DownloadComplete, tread, nil)
+		}
+		if oDCH != nil {
+			oDCH(hash, tread)
+		}
+	}
+	oEH := h.ErrorHandler
+	h.ErrorHandler = func(hash string, err error) {
+		if atomic.CompareAndSwapInt32(&failed, 0, 1) {
+			s.notify(uid(), common.DownloadFailed, 0, err)
+		}
+		if oEH != nil {
+			oEH(hash, err)
+		}
+	}
+	oSH := h.DownloadStoppedHandler
+	h.DownloadStoppedHandler = func() {
+		if atomic.LoadInt32(&failed) == 0 {
+			s.notify(uid(), common.DownloadStopped, 0, nil)
+		}
+		if oSH != nil {
+			oSH()
+		}
+	}
+	return h
+}
+
+func (s *Api) notify(uid string, action common.DownloadingAction, value int64, err error) {
+	p := &webhook.Payload{
+		DownloadingResponse: common.DownloadingResponse{
+			DownloadId: uid,
+			Action:     action,
+			Hash:       warplib.MAIN_HASH,
+			Value:      value,
+		},
+		Item: webhook.NewItemInfo(s.manager.GetItem(uid)),
+	}
+	if err != nil {
+		p.Error = err.Error()
+	}
+	s.webhooks.Notify(p)
+}
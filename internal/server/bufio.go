@@ -1,6 +1,7 @@
 package server
 
 import (
+	"io"
 	"net"
 	"sync"
 )
@@ -22,12 +23,12 @@ func read(mu *sync.Mutex, conn net.Conn) ([]byte, error) {
 	mu.Lock()
 	defer mu.Unlock()
 	head := make([]byte, 4)
-	_, err := conn.Read(head)
+	_, err := io.ReadFull(conn, head)
 	if err != nil {
 		return nil, err
 	}
 	buf := make([]byte, bytesToInt(head))
-	_, err = conn.Read(buf)
+	_, err = io.ReadFull(conn, buf)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,74 @@
+package server
+
+import (
+	"github.com/warpdl/warpdl/common"
+	"github.com/warpdl/warpdl/pkg/warplib"
+)
+
+// DownloadHandlers returns the handlers of a download which broadcast
+// its events to the connections of the download, uid returns the id
+// of the download and stop stops the download once it has failed.
+func (p *Pool) DownloadHandlers(uid func() string, stop func()) *warplib.Handlers {
+	broadcast := func(action common.DownloadingAction, hash string, value int64) {
+		id := uid()
+		p.Broadcast(id, MakeResult(common.UPDATE_DOWNLOADING, &common.DownloadingResponse{
+			DownloadId: id,
+			Action:     action,
+			Value:      value,
+			Hash:       hash,
+		}))
+	}
+	return &warplib.Handlers{
+		ErrorHandler: func(_ string, err error) {
+			id := uid()
+			p.BroadcastError(id, common.DownloadFailed, err)
+			p.WriteError(id, ErrorTypeCritical, err.Error())
+			p.StopDownload(id)
+			stop()
+		},
+		ResumeProgressHandler: func(hash string, nread int) {
+			broadcast(common.ResumeProgress, hash, int64(nread))
+		},
+		DownloadProgressHandler: func(hash string, nread int) {
+			broadcast(common.DownloadProgress, hash, int64(nread))
+		},
+		DownloadCompleteHandler: func(hash string, tread int64) {
+			broadcast(common.DownloadComplete, hash, tread)
+		},
+		ConnectionsTunedHandler: func(prev, curr int32, throughput int64) {
+			broadcast(common.ConnectionsTuned, "", int64(curr))
+		},
+		DownloadStoppedHandler: func() {
+			broadcast(common.DownloadStopped, "", 0)
+		},
+		CompileStartHandler: func(hash string) {
+			broadcast(common.CompileStart, hash, 0)
+		},
+		CompileProgressHandler: func(hash string, nread int) {
+			broadcast(common.CompileProgress, hash, int64(nread))
+		},
+		CompileCompleteHandler: func(hash string, tread int64) {
+			broadcast(common.CompileComplete, hash, tread)
+		},
+		ExtractStartHandler: func(total int64) {
+			broadcast(common.ExtractStart, warplib.MAIN_HASH, total)
+		},
+		ExtractProgressHandler: func(nread int) {
+			broadcast(common.ExtractProgress, warplib.MAIN_HASH, int64(nread))
+		},
+		ExtractCompleteHandler: func(dir string, err error) {
+			id := uid()
+			if err != nil {
+				p.BroadcastError(id, common.ExtractComplete, err)
+				p.WriteError(id, ErrorTypeWarning, err.Error())
+				return
+			}
+			p.Broadcast(id, MakeResult(common.UPDATE_DOWNLOADING, &common.DownloadingResponse{
+				DownloadId: id,
+				Action:     common.ExtractComplete,
+				Hash:       warplib.MAIN_HASH,
+				Path:       dir,
+			}))
+		},
+	}
+}
@@ -2,7 +2,11 @@ package server
 
 import (
 	"log"
+	"slices"
 	"sync"
+
+	"github.com/warpdl/warpdl/common"
+	"github.com/warpdl/warpdl/pkg/warplib"
 )
 
 type Pool struct {
@@ -10,6 +14,8 @@ type Pool struct {
 	mu *sync.RWMutex
 	m  map[string][]*SyncConn
 	e  map[string]*Error
+	// subs receive the updates of all the downloads.
+	subs []*SyncConn
 }
 
 func NewPool(l *log.Logger) *Pool {
@@ -54,28 +60,70 @@ func (p *Pool) AddConnection(uid string, sconn *SyncConn) {
 	p.m[uid] = _conns
 }
 
-func (p *Pool) writeBroadcastedMessage(uid string, i int, sconn *SyncConn, head []byte, data []byte) {
-	sconn.wmu.Lock()
-	_, err := sconn.Conn.Write(head)
+func (p *Pool) writeBroadcastedMessage(uid string, i int, sconn *SyncConn, data []byte) {
+	err := sconn.Write(data)
 	if err != nil {
 		p.removeConn(uid, i)
-		return
 	}
-	_, err = sconn.Conn.Write(data)
-	if err != nil {
-		p.removeConn(uid, i)
-		return
-	}
-	sconn.wmu.Unlock()
 }
 
 func (p *Pool) Broadcast(uid string, data []byte) {
-	head := intToBytes(uint32(len(data)))
 	p.mu.RLock()
 	sconns := p.m[uid]
 	p.mu.RUnlock()
 	for i, sconn := range sconns {
-		p.writeBroadcastedMessage(uid, i, sconn, head, data)
+		p.writeBroadcastedMessage(uid, i, sconn, data)
+	}
+	p.publish(data)
+}
+
+// BroadcastError broadcasts the error of the action of the download
+// of uid, such as download_failed. Subscribers receive it as an
+// update of the action as an error response would end their
+// subscription.
+func (p *Pool) BroadcastError(uid string, action common.DownloadingAction, err error) {
+	p.mu.RLock()
+	sconns := p.m[uid]
+	p.mu.RUnlock()
+	data := InitError(err)
+	for i, sconn := range sconns {
+		p.writeBroadcastedMessage(uid, i, sconn, data)
+	}
+	p.publish(MakeResult(common.UPDATE_DOWNLOADING, &common.DownloadingResponse{
+		DownloadId: uid,
+		Action:     action,
+		Hash:       warplib.MAIN_HASH,
+		Error:      err.Error(),
+	}))
+}
+
+// Subscribe sends the updates of all the downloads to sconn
+// until it's unsubscribed or writing to it fails.
+func (p *Pool) Subscribe(sconn *SyncConn) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if !slices.Contains(p.subs, sconn) {
+		p.subs = append(p.subs, sconn)
+	}
+}
+
+func (p *Pool) Unsubscribe(sconn *SyncConn) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.subs = slices.DeleteFunc(p.subs, func(s *SyncConn) bool {
+		return s == sconn
+	})
+}
+
+// publish writes data to the subscribers.
+func (p *Pool) publish(data []byte) {
+	p.mu.RLock()
+	subs := slices.Clone(p.subs)
+	p.mu.RUnlock()
+	for _, sconn := range subs {
+		if sconn.Write(data) != nil {
+			p.Unsubscribe(sconn)
+		}
 	}
 }
 
@@ -30,6 +30,18 @@ func NewServer(l *log.Logger, m *warplib.Manager, port int) *Server {
 	}
 }
 
+// GetPool returns the pool of the connections
+// listening to the downloads.
+func (s *Server) GetPool() *Pool {
+	return s.pool
+}
+
+// SetWebDownload sets how the downloads captured by the web server
+// are created, they only use the pool's handlers by default.
+func (s *Server) SetWebDownload(f DownloadFunc) {
+	s.ws.download = f
+}
+
 func (s *Server) RegisterHandler(method common.UpdateType, handler HandlerFunc) {
 	s.handler[method] = handler
 }
@@ -55,7 +67,8 @@ func (s *Server) Start() error {
 			return fmt.Errorf("error listening: %s", err.Error())
 		}
 	} else {
-		_ = os.Chmod(socketPath, 0766)
+		// only the user of the daemon may control it.
+		_ = os.Chmod(socketPath, 0600)
 	}
 	defer l.Close()
 	for {
@@ -72,6 +85,7 @@ func (s *Server) Start() error {
 func (s *Server) handleConnection(conn net.Conn) {
 	sconn := NewSyncConn(conn)
 	defer conn.Close()
+	defer s.pool.Unsubscribe(sconn)
 	for {
 		buf, err := sconn.Read()
 		if err != nil {
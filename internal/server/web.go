@@ -9,18 +9,22 @@ import (
 	"net/http/cookiejar"
 	"net/url"
 
-	"github.com/warpdl/warpdl/common"
 	"github.com/warpdl/warpdl/pkg/warplib"
 	"golang.org/x/net/websocket"
 )
 
 type WebServer struct {
-	port int
-	l    *log.Logger
-	m    *warplib.Manager
-	pool *Pool
+	port     int
+	l        *log.Logger
+	m        *warplib.Manager
+	pool     *Pool
+	download DownloadFunc
 }
 
+// DownloadFunc creates a download captured by the browser extension
+// and adds it to the manager, client carries the cookies of its page.
+type DownloadFunc func(pool *Pool, client *http.Client, url string, headers warplib.Headers) (*warplib.Downloader, error)
+
 type capturedDownload struct {
 	Url     string          `json:"url"`
 	Headers warplib.Headers `json:"headers"`
@@ -28,7 +32,26 @@ type capturedDownload struct {
 }
 
 func NewWebServer(l *log.Logger, m *warplib.Manager, pool *Pool, port int) *WebServer {
-	return &WebServer{port: port, l: l, m: m, pool: pool}
+	s := &WebServer{port: port, l: l, m: m, pool: pool}
+	s.download = s.addDownload
+	return s
+}
+
+// addDownload is the default DownloadFunc of the web server.
+func (s *WebServer) addDownload(pool *Pool, client *http.Client, url string, headers warplib.Headers) (d *warplib.Downloader, err error) {
+	d, err = warplib.NewDownloader(client, url, &warplib.DownloaderOpts{
+		Headers:        headers,
+		MaxConnections: 24,
+		MaxSegments:    200,
+		Handlers:       pool.DownloadHandlers(func() string { return d.GetHash() }, func() { d.Stop() }),
+	})
+	if err != nil {
+		return
+	}
+	err = s.m.AddDownload(d, &warplib.AddDownloadOpts{
+		AbsoluteLocation: d.GetDownloadDirectory(),
+	})
+	return
 }
 
 func (s *WebServer) processDownload(cd *capturedDownload) error {
@@ -44,78 +67,7 @@ func (s *WebServer) processDownload(cd *capturedDownload) error {
 		Jar: jar,
 	}
 	client.Jar.SetCookies(parsedURL, cd.Cookies)
-	var d *warplib.Downloader
-	d, err = warplib.NewDownloader(client, cd.Url, &warplib.DownloaderOpts{
-		Headers:        cd.Headers,
-		MaxConnections: 24,
-		MaxSegments:    200,
-		Handlers: &warplib.Handlers{
-			ErrorHandler: func(_ string, err error) {
-				uid := d.GetHash()
-				s.pool.Broadcast(uid, InitError(err))
-				s.pool.WriteError(uid, ErrorTypeCritical, err.Error())
-				s.pool.StopDownload(uid)
-				d.Stop()
-			},
-			DownloadProgressHandler: func(hash string, nread int) {
-				uid := d.GetHash()
-				s.pool.Broadcast(uid, MakeResult(common.UPDATE_DOWNLOADING, &common.DownloadingResponse{
-					DownloadId: uid,
-					Action:     common.DownloadProgress,
-					Value:      int64(nread),
-					Hash:       hash,
-				}))
-			},
-			DownloadCompleteHandler: func(hash string, tread int64) {
-				uid := d.GetHash()
-				s.pool.Broadcast(uid, MakeResult(common.UPDATE_DOWNLOADING, &common.DownloadingResponse{
-					DownloadId: uid,
-					Action:     common.DownloadComplete,
-					Value:      tread,
-					Hash:       hash,
-				}))
-			},
-			DownloadStoppedHandler: func() {
-				uid := d.GetHash()
-				s.pool.Broadcast(uid, MakeResult(common.UPDATE_DOWNLOADING, &common.DownloadingResponse{
-					DownloadId: uid,
-					Action:     common.DownloadStopped,
-				}))
-			},
-			CompileStartHandler: func(hash string) {
-				uid := d.GetHash()
-				s.pool.Broadcast(uid, MakeResult(common.UPDATE_DOWNLOADING, &common.DownloadingResponse{
-					DownloadId: uid,
-					Action:     common.CompileStart,
-					Hash:       hash,
-				}))
-			},
-			CompileProgressHandler: func(hash string, nread int) {
-				uid := d.GetHash()
-				s.pool.Broadcast(uid, MakeResult(common.UPDATE_DOWNLOADING, &common.DownloadingResponse{
-					DownloadId: uid,
-					Action:     common.CompileProgress,
-					Value:      int64(nread),
-					Hash:       hash,
-				}))
-			},
-			CompileCompleteHandler: func(hash string, tread int64) {
-				uid := d.GetHash()
-				s.pool.Broadcast(uid, MakeResult(common.UPDATE_DOWNLOADING, &common.DownloadingResponse{
-					DownloadId: uid,
-					Action:     common.CompileComplete,
-					Value:      tread,
-					Hash:       hash,
-				}))
-			},
-		},
-	})
-	if err != nil {
-		return err
-	}
-	err = s.m.AddDownload(d, &warplib.AddDownloadOpts{
-		AbsoluteLocation: d.GetDownloadDirectory(),
-	})
+	d, err := s.download(s.pool, client, cd.Url, cd.Headers)
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,323 @@
+// Package webhook delivers the lifecycle events of downloads
+// to http endpoints as signed JSON payloads.
+package webhook
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/warpdl/warpdl/common"
+	"github.com/warpdl/warpdl/pkg/warplib"
+)
+
+const (
+	// DEF_MAX_RETRIES is the number of times a failed
+	// delivery is retried.
+	DEF_MAX_RETRIES = 5
+	// DEF_QUEUE_SIZE is the number of events queued per
+	// webhook, events are dropped once the queue is full.
+	DEF_QUEUE_SIZE = 256
+	// DEF_BACKOFF is the delay before the first retry, it
+	// doubles with each retry up to DEF_MAX_BACKOFF.
+	DEF_BACKOFF     = time.Second
+	DEF_MAX_BACKOFF = time.Minute
+	// DEF_TIMEOUT is the timeout of a delivery attempt.
+	DEF_TIMEOUT = 10 * time.Second
+)
+
+const (
+	// HeaderEvent is the action of the delivered event.
+	HeaderEvent = "X-Warpdl-Event"
+	// HeaderDelivery identifies a delivery, it is the
+	// same for all of its attempts.
+	HeaderDelivery = "X-Warpdl-Delivery"
+	// HeaderSignature is "sha256=" followed by the hex encoded
+	// HMAC-SHA256 of the body, keyed with the secret.
+	HeaderSignature = "X-Warpdl-Signature"
+)
+
+// LifecycleEvents are the events delivered if
+// Config.Events is empty.
+var LifecycleEvents = []common.DownloadingAction{
+	common.DownloadStarted,
+	common.DownloadComplete,
+	common.DownloadStopped,
+	common.DownloadFailed,
+}
+
+// ParseEvents parses a comma separated list of events, such as
+// "download_complete,download_failed". The "download_" prefix
+// of the events can be left out.
+func ParseEvents(s string) (events []common.DownloadingAction, err error) {
+	for _, e := range strings.Split(s, ",") {
+		e = strings.TrimSpace(e)
+		if e == "" {
+			continue
+		}
+		if !strings.HasPrefix(e, "download_") {
+			e = "download_" + e
+		}
+		action := common.DownloadingAction(e)
+		known := false
+		for _, le := range LifecycleEvents {
+			known = known || le == action
+		}
+		if !known {
+			return nil, fmt.Errorf("unknown webhook event %q", e)
+		}
+		events = append(events, action)
+	}
+	return
+}
+
+type Config struct {
+	// Urls are the endpoints the events are posted to.
+	Urls []string
+	// Secret signs the payloads if not empty, see HeaderSignature.
+	Secret string
+	// Events are the delivered events, LifecycleEvents if empty.
+	Events []common.DownloadingAction
+	// MaxRetries is the number of retries of a failed delivery,
+	// DEF_MAX_RETRIES is used if 0 and retries are disabled
+	// if negative.
+	MaxRetries int
+}
+
+// Payload is the body of a delivered event.
+type Payload struct {
+	common.DownloadingResponse
+	// Error is the error of a failed download.
+	Error string    `json:"error,omitempty"`
+	Time  time.Time `json:"time"`
+	Item  *ItemInfo `json:"item,omitempty"`
+}
+
+// ItemInfo is the metadata of the item of an event, it leaves
+// out the headers of the item as they may hold credentials.
+type ItemInfo struct {
+	Hash       string                `json:"hash"`
+	Name       string                `json:"name"`
+	Url        string                `json:"url"`
+	SavePath   string                `json:"save_path"`
+	TotalSize  warplib.ContentLength `json:"total_size"`
+	Downloaded warplib.ContentLength `json:"downloaded"`
+	DateAdded  time.Time             `json:"date_added"`
+	ParentHash string                `json:"parent_hash,omitempty"`
+	Resumable  bool                  `json:"resumable"`
+	Failure    string                `json:"failure,omitempty"`
+}
+
+// NewItemInfo returns the metadata of the item, nil if item is nil.
+func NewItemInfo(item *warplib.Item) *ItemInfo {
+	if item == nil {
+		return nil
+	}
+	return &ItemInfo{
+		Hash:       item.Hash,
+		Name:       item.Name,
+		Url:        item.Url,
+		SavePath:   item.GetAbsolutePath(),
+		TotalSize:  item.TotalSize,
+		Downloaded: item.Downloaded,
+		DateAdded:  item.DateAdded,
+		ParentHash: item.ParentHash,
+		Resumable:  item.Resumable,
+		Failure:    item.Failure,
+	}
+}
+
+// Dispatcher posts the events to the webhooks in the background,
+// the events of a webhook are delivered in order.
+type Dispatcher struct {
+	l       *log.Logger
+	client  *http.Client
+	secret  string
+	events  map[common.DownloadingAction]bool
+	retries int
+	backoff time.Duration
+	queues  []chan *delivery
+	wg      sync.WaitGroup
+	mu      sync.RWMutex
+	closed  bool
+}
+
+type delivery struct {
+	id     string
+	action common.DownloadingAction
+	body   []byte
+}
+
+// NewDispatcher starts delivering the events to the webhooks of c,
+// deliveries are logged to l. It returns nil if c has no webhooks,
+// Notify and Close of a nil dispatcher do nothing.
+func NewDispatcher(l *log.Logger, client *http.Client, c *Config) *Dispatcher {
+	if c == nil || len(c.Urls) == 0 {
+		return nil
+	}
+	if client == nil {
+		client = &http.Client{Timeout: DEF_TIMEOUT}
+	}
+	events := c.Events
+	if len(events) == 0 {
+		events = LifecycleEvents
+	}
+	d := &Dispatcher{
+		l:       l,
+		client:  client,
+		secret:  c.Secret,
+		events:  make(map[common.DownloadingAction]bool),
+		retries: c.MaxRetries,
+		backoff: DEF_BACKOFF,
+	}
+	switch {
+	case d.retries == 0:
+		d.retries = DEF_MAX_RETRIES
+	case d.retries < 0:
+		d.retries = 0
+	}
+	for _, e := range events {
+		d.events[e] = true
+	}
+	for _, url := range c.Urls {
+		q := make(chan *delivery, DEF_QUEUE_SIZE)
+		d.queues = append(d.queues, q)
+		d.wg.Add(1)
+		go d.run(url, q)
+	}
+	return d
+}
+
+// Notify queues the event for delivery to the webhooks,
+// unless it is filtered out.
+func (d *Dispatcher) Notify(p *Payload) {
+	if d == nil || !d.events[p.Action] {
+		return
+	}
+	if p.Time.IsZero() {
+		p.Time = time.Now()
+	}
+	body, err := json.Marshal(p)
+	if err != nil {
+		d.l.Printf("webhook: failed to encode %s of %s: %s\n", p.Action, p.DownloadId, err.Error())
+		return
+	}
+	dl := &delivery{
+		id:     newDeliveryId(),
+		action: p.Action,
+		body:   body,
+	}
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	if d.closed {
+		return
+	}
+	for _, q := range d.queues {
+		select {
+		case q <- dl:
+		default:
+			d.l.Printf("webhook: queue is full, dropped %s of %s\n", p.Action, p.DownloadId)
+		}
+	}
+}
+
+// Close stops accepting events and waits
+// for the queued ones to be delivered.
+func (d *Dispatcher) Close() {
+	if d == nil {
+		return
+	}
+	d.mu.Lock()
+	if !d.closed {
+		d.closed = true
+		for _, q := range d.queues {
+			close(q)
+		}
+	}
+	d.mu.Unlock()
+	d.wg.Wait()
+}
+
+func (d *Dispatcher) run(url string, q chan *delivery) {
+	defer d.wg.Done()
+	for dl := range q {
+		d.deliver(url, dl)
+	}
+}
+
+// deliver posts dl to url, retrying with an exponential backoff
+// on network errors, server errors and rate limiting.
+func (d *Dispatcher) deliver(url string, dl *delivery) {
+	backoff := d.backoff
+	for attempt := 1; ; attempt++ {
+		start := time.Now()
+		status, err := d.post(url, dl)
+		if err == nil {
+			d.l.Printf("webhook: delivered %s %s to %s (status %d, attempt %d, %s)\n",
+				dl.action, dl.id, url, status, attempt, time.Since(start).Round(time.Millisecond))
+			return
+		}
+		retry := errors.Is(err, errRetry) && attempt <= d.retries
+		d.l.Printf("webhook: failed to deliver %s %s to %s (attempt %d): %s\n",
+			dl.action, dl.id, url, attempt, err.Error())
+		if !retry {
+			return
+		}
+		time.Sleep(backoff)
+		backoff = min(2*backoff, DEF_MAX_BACKOFF)
+	}
+}
+
+var errRetry = errors.New("retryable")
+
+func (d *Dispatcher) post(url string, dl *delivery) (status int, err error) {
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(dl.body))
+	if err != nil {
+		return
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "warpdl-webhook")
+	req.Header.Set(HeaderEvent, string(dl.action))
+	req.Header.Set(HeaderDelivery, dl.id)
+	if d.secret != "" {
+		req.Header.Set(HeaderSignature, Sign(d.secret, dl.body))
+	}
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %w", errRetry, err)
+	}
+	resp.Body.Close()
+	status = resp.StatusCode
+	switch {
+	case status >= 200 && status < 300:
+		return
+	case status >= 500, status == http.StatusTooManyRequests, status == http.StatusRequestTimeout:
+		err = fmt.Errorf("%w: unexpected status %s", errRetry, resp.Status)
+	default:
+		err = fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return
+}
+
+// Sign returns the value of HeaderSignature for body.
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func newDeliveryId() string {
+	buf := make([]byte, 8)
+	rand.Read(buf)
+	return hex.EncodeToString(buf)
+}
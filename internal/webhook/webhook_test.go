@@ -0,0 +1,134 @@
+package webhook
+
+import (
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/warpdl/warpdl/common"
+)
+
+type receiver struct {
+	mu        sync.Mutex
+	payloads  []*Payload
+	attempts  map[string]int
+	failFirst int
+	status    int
+}
+
+func (rc *receiver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	id := r.Header.Get(HeaderDelivery)
+	rc.attempts[id]++
+	if rc.status != 0 {
+		w.WriteHeader(rc.status)
+		return
+	}
+	if rc.attempts[id] <= rc.failFirst {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+	body, _ := io.ReadAll(r.Body)
+	if got, want := r.Header.Get(HeaderSignature), Sign("secret", body); got != want {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	var p Payload
+	if err := json.Unmarshal(body, &p); err != nil || string(p.Action) != r.Header.Get(HeaderEvent) {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	rc.payloads = append(rc.payloads, &p)
+}
+
+func newTestDispatcher(t *testing.T, rc *receiver, c *Config) *Dispatcher {
+	srv := httptest.NewServer(rc)
+	t.Cleanup(srv.Close)
+	c.Urls = []string{srv.URL}
+	c.Secret = "secret"
+	d := NewDispatcher(log.New(io.Discard, "", 0), srv.Client(), c)
+	d.backoff = time.Millisecond
+	return d
+}
+
+func TestDispatcher(t *testing.T) {
+	rc := &receiver{attempts: make(map[string]int), failFirst: 2}
+	d := newTestDispatcher(t, rc, &Config{
+		Events: []common.DownloadingAction{common.DownloadComplete, common.DownloadFailed},
+	})
+	for _, action := range []common.DownloadingAction{
+		common.DownloadStarted,
+		common.DownloadComplete,
+		common.DownloadFailed,
+	} {
+		d.Notify(&Payload{
+			DownloadingResponse: common.DownloadingResponse{DownloadId: "abcd", Action: action},
+			Item:                &ItemInfo{Hash: "abcd", Name: "file.zip"},
+		})
+	}
+	d.Close()
+	if len(rc.payloads) != 2 {
+		t.Fatalf("received %d payloads, want 2", len(rc.payloads))
+	}
+	for i, want := range []common.DownloadingAction{common.DownloadComplete, common.DownloadFailed} {
+		p := rc.payloads[i]
+		if p.Action != want || p.DownloadId != "abcd" || p.Item == nil || p.Item.Name != "file.zip" || p.Time.IsZero() {
+			t.Errorf("payload %d = %+v, want action %s", i, p, want)
+		}
+	}
+	for id, n := range rc.attempts {
+		if n != 3 {
+			t.Errorf("delivery %s attempted %d times, want 3", id, n)
+		}
+	}
+}
+
+func TestDispatcherRetries(t *testing.T) {
+	tests := []struct {
+		name     string
+		rc       *receiver
+		retries  int
+		attempts int
+	}{
+		{"exhausted", &receiver{failFirst: 10}, 2, 3},
+		{"disabled", &receiver{failFirst: 10}, -1, 1},
+		{"client error", &receiver{status: http.StatusNotFound}, 2, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.rc.attempts = make(map[string]int)
+			d := newTestDispatcher(t, tt.rc, &Config{MaxRetries: tt.retries})
+			d.Notify(&Payload{
+				DownloadingResponse: common.DownloadingResponse{DownloadId: "abcd", Action: common.DownloadStopped},
+			})
+			d.Close()
+			if len(tt.rc.attempts) != 1 {
+				t.Fatalf("received %d deliveries, want 1", len(tt.rc.attempts))
+			}
+			for _, n := range tt.rc.attempts {
+				if n != tt.attempts {
+					t.Errorf("delivery attempted %d times, want %d", n, tt.attempts)
+				}
+			}
+		})
+	}
+}
+
+func TestParseEvents(t *testing.T) {
+	events, err := ParseEvents("complete, download_failed")
+	if err != nil {
+		t.Fatalf("ParseEvents() error = %v", err)
+	}
+	if len(events) != 2 || events[0] != common.DownloadComplete || events[1] != common.DownloadFailed {
+		t.Errorf("ParseEvents() = %v", events)
+	}
+	if _, err = ParseEvents("progress"); err == nil {
+		t.Errorf("ParseEvents() of an unknown event succeeded")
+	}
+}
@@ -1,6 +1,9 @@
 package warpcli
 
-import "net"
+import (
+	"io"
+	"net"
+)
 
 func intToBytes(v uint32) []byte {
 	b := make([]byte, 4)
@@ -17,12 +20,12 @@ func bytesToInt(b []byte) uint32 {
 
 func read(conn net.Conn) ([]byte, error) {
 	head := make([]byte, 4)
-	_, err := conn.Read(head)
+	_, err := io.ReadFull(conn, head)
 	if err != nil {
 		return nil, err
 	}
 	buf := make([]byte, bytesToInt(head))
-	_, err = conn.Read(buf)
+	_, err = io.ReadFull(conn, buf)
 	if err != nil {
 		return nil, err
 	}
@@ -73,23 +73,22 @@ func (c *Client) Disconnect() {
 	c.listen = false
 }
 
+// Close closes the connection to the daemon, it
+// ends Listen if the client is listening.
+func (c *Client) Close() error {
+	return c.conn.Close()
+}
+
 func (c *Client) invoke(method common.UpdateType, message any) (json.RawMessage, error) {
 	// block updates listener while invoking a method
 	// to retrieve the message update here instead
 	c.mu.Lock()
 	defer c.mu.Unlock()
-	buf, err := json.Marshal(&Request{
-		Method:  method,
-		Message: message,
-	})
+	err := c.send(method, message)
 	if err != nil {
-		return nil, fmt.Errorf("failed to invoke %s: %s", method, err.Error())
-	}
-	err = write(c.conn, buf)
-	if err != nil {
-		return nil, fmt.Errorf("failed to invoke %s: %s", method, err.Error())
+		return nil, err
 	}
-	buf, err = read(c.conn)
+	buf, err := read(c.conn)
 	if err != nil {
 		return nil, fmt.Errorf("failed to invoke %s: %s", method, err.Error())
 	}
@@ -103,3 +102,19 @@ func (c *Client) invoke(method common.UpdateType, message any) (json.RawMessage,
 	}
 	return res.Update.Message, nil
 }
+
+// send writes a request without reading its response.
+func (c *Client) send(method common.UpdateType, message any) error {
+	buf, err := json.Marshal(&Request{
+		Method:  method,
+		Message: message,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to invoke %s: %s", method, err.Error())
+	}
+	err = write(c.conn, buf)
+	if err != nil {
+		return fmt.Errorf("failed to invoke %s: %s", method, err.Error())
+	}
+	return nil
+}
@@ -10,6 +10,13 @@ type Handler interface {
 	Handle(json.RawMessage) error
 }
 
+// HandlerFunc adapts a function to the Handler interface.
+type HandlerFunc func(json.RawMessage) error
+
+func (f HandlerFunc) Handle(m json.RawMessage) error {
+	return f(m)
+}
+
 func NewDownloadingHandler(action common.DownloadingAction, callback func(*common.DownloadingResponse) error) *DownloadingHandler {
 	return &DownloadingHandler{
 		Action:   action,
@@ -24,6 +24,23 @@ type DownloadOpts struct {
 	ChildHash      string          `json:"child_hash,omitempty"`
 	IsHidden       bool            `json:"is_hidden,omitempty"`
 	IsChildren     bool            `json:"is_children,omitempty"`
+	// ConflictPolicy is used if a file already exists at the save path.
+	ConflictPolicy warplib.ConflictPolicy `json:"conflict_policy,omitempty"`
+	// DecodeContent decodes compressed responses on the fly.
+	DecodeContent bool `json:"decode_content,omitempty"`
+	// RaceTail races duplicate requests for the last segments.
+	RaceTail bool `json:"race_tail,omitempty"`
+	// RangeStart and RangeEnd limit the download to a byte range,
+	// see warplib.DownloaderOpts for their semantics.
+	RangeStart int64 `json:"range_start,omitempty"`
+	RangeEnd   int64 `json:"range_end,omitempty"`
+	// Schedule restricts when the download runs.
+	Schedule *warplib.Schedule `json:"schedule,omitempty"`
+	// Extract extracts the archive once downloaded.
+	Extract *warplib.ExtractOpts `json:"extract,omitempty"`
+	// Tags label the download, Note describes it.
+	Tags []string `json:"tags,omitempty"`
+	Note string   `json:"note,omitempty"`
 }
 
 func (c *Client) Download(url, fileName, downloadDirectory string, opts *DownloadOpts) (*common.DownloadResponse, error) {
@@ -41,14 +58,78 @@ func (c *Client) Download(url, fileName, downloadDirectory string, opts *Downloa
 		ChildHash:         opts.ChildHash,
 		IsHidden:          opts.IsHidden,
 		IsChildren:        opts.IsChildren,
+		ConflictPolicy:    opts.ConflictPolicy,
+		DecodeContent:     opts.DecodeContent,
+		RaceTail:          opts.RaceTail,
+		RangeStart:        opts.RangeStart,
+		RangeEnd:          opts.RangeEnd,
+		Schedule:          opts.Schedule,
+		Extract:           opts.Extract,
+		Tags:              opts.Tags,
+		Note:              opts.Note,
 	})
 }
 
+// Schedule sets the schedule of a download, a nil schedule removes it.
+func (c *Client) Schedule(downloadId string, schedule *warplib.Schedule) (*common.ScheduleResponse, error) {
+	return invoke[common.ScheduleResponse](c, common.UPDATE_SCHEDULE, &common.ScheduleParams{
+		DownloadId: downloadId,
+		Schedule:   schedule,
+	})
+}
+
+// Tag adds and removes tags of a download, its note
+// is set as well unless note is nil.
+func (c *Client) Tag(downloadId string, add, remove []string, note *string) (*common.TagResponse, error) {
+	return invoke[common.TagResponse](c, common.UPDATE_TAG, &common.TagParams{
+		DownloadId: downloadId,
+		Add:        add,
+		Remove:     remove,
+		Note:       note,
+	})
+}
+
+type BatchOpts struct {
+	// MaxParallel limits the number of downloads running
+	// at once, daemon's default is used if 0.
+	MaxParallel int `json:"max_parallel,omitempty"`
+	// NoGlob disables the expansion of url patterns.
+	NoGlob bool `json:"no_glob,omitempty"`
+}
+
+// Batch downloads all items and returns once every download
+// has either completed or failed. Urls of the items are expanded
+// as url patterns unless disabled by opts.
+func (c *Client) Batch(items []common.DownloadParams, opts *BatchOpts) (*common.BatchResponse, error) {
+	if opts == nil {
+		opts = &BatchOpts{}
+	}
+	return invoke[common.BatchResponse](c, common.UPDATE_BATCH, &common.BatchParams{
+		Items:       items,
+		MaxParallel: opts.MaxParallel,
+		NoGlob:      opts.NoGlob,
+	})
+}
+
+// Crawl crawls the directory index at params.Url and downloads the
+// files found, it returns once every download has either completed
+// or failed.
+func (c *Client) Crawl(params *common.CrawlParams) (*common.CrawlResponse, error) {
+	return invoke[common.CrawlResponse](c, common.UPDATE_CRAWL, params)
+}
+
 type ResumeOpts struct {
 	Headers        warplib.Headers `json:"headers,omitempty"`
 	ForceParts     bool            `json:"force_parts,omitempty"`
 	MaxConnections int32           `json:"max_connections,omitempty"`
 	MaxSegments    int32           `json:"max_segments,omitempty"`
+	// RaceTail races duplicate requests for the last segments.
+	RaceTail bool `json:"race_tail,omitempty"`
+	// Url replaces the download url before resuming.
+	Url string `json:"url,omitempty"`
+	// RefreshUrl re-extracts the download url using the
+	// matching extension before resuming.
+	RefreshUrl bool `json:"refresh_url,omitempty"`
 }
 
 func (c *Client) Resume(downloadId string, opts *ResumeOpts) (*common.ResumeResponse, error) {
@@ -61,6 +142,35 @@ func (c *Client) Resume(downloadId string, opts *ResumeOpts) (*common.ResumeResp
 		ForceParts:     opts.ForceParts,
 		MaxConnections: opts.MaxConnections,
 		MaxSegments:    opts.MaxSegments,
+		RaceTail:       opts.RaceTail,
+		Url:            opts.Url,
+		RefreshUrl:     opts.RefreshUrl,
+	})
+}
+
+// ResumeTag resumes the downloads with the tag in background,
+// the url of opts can't be replaced.
+func (c *Client) ResumeTag(tag string, opts *ResumeOpts) (*common.TaggedResponse, error) {
+	if opts == nil {
+		opts = &ResumeOpts{}
+	}
+	return invoke[common.TaggedResponse](c, common.UPDATE_RESUME, &common.ResumeParams{
+		Tag:            tag,
+		Headers:        opts.Headers,
+		ForceParts:     opts.ForceParts,
+		MaxConnections: opts.MaxConnections,
+		MaxSegments:    opts.MaxSegments,
+		RaceTail:       opts.RaceTail,
+	})
+}
+
+// RefreshUrl replaces the url of an incomplete download, an empty
+// url makes daemon re-extract it using the matching extension.
+func (c *Client) RefreshUrl(downloadId, url string, headers warplib.Headers) (*common.RefreshUrlResponse, error) {
+	return invoke[common.RefreshUrlResponse](c, common.UPDATE_REFRESH_URL, &common.RefreshUrlParams{
+		DownloadId: downloadId,
+		Url:        url,
+		Headers:    headers,
 	})
 }
 
@@ -68,7 +178,7 @@ type ListOpts common.ListParams
 
 func (c *Client) List(opts *ListOpts) (*common.ListResponse, error) {
 	if opts == nil {
-		opts = &ListOpts{false, true}
+		opts = &ListOpts{ShowPending: true}
 	}
 	return invoke[common.ListResponse](c, "list", opts)
 }
@@ -78,15 +188,48 @@ func (c *Client) Flush(downloadId string) (bool, error) {
 	return err == nil, err
 }
 
+// FlushTag flushes the downloads with the tag
+// which aren't running.
+func (c *Client) FlushTag(tag string) (*common.TaggedResponse, error) {
+	return invoke[common.TaggedResponse](c, common.UPDATE_FLUSH, &common.FlushParams{Tag: tag})
+}
+
 func (c *Client) AttachDownload(downloadId string) (*common.DownloadResponse, error) {
 	return invoke[common.DownloadResponse](c, "attach", &common.InputDownloadId{DownloadId: downloadId})
 }
 
 func (c *Client) StopDownload(downloadId string) (bool, error) {
-	_, err := c.invoke("stop", &common.InputDownloadId{DownloadId: downloadId})
+	_, err := c.invoke("stop", &common.StopParams{DownloadId: downloadId})
 	return err == nil, err
 }
 
+// StopTag stops the running downloads with the tag.
+func (c *Client) StopTag(tag string) (*common.TaggedResponse, error) {
+	return invoke[common.TaggedResponse](c, common.UPDATE_STOP, &common.StopParams{Tag: tag})
+}
+
+// SetMaxConnections caps the connections of a running download,
+// it returns the cap after clamping to the max connections.
+func (c *Client) SetMaxConnections(downloadId string, n int32) (*common.ConnectionsResponse, error) {
+	return invoke[common.ConnectionsResponse](c, common.UPDATE_CONNECTIONS, &common.ConnectionsParams{
+		DownloadId:     downloadId,
+		MaxConnections: n,
+	})
+}
+
+// Subscribe subscribes the client to the updates of all the
+// downloads, which are received by Listen along with the response
+// of the subscription. The client can't invoke other methods
+// once subscribed, use another client to do so.
+func (c *Client) Subscribe() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.d.AddHandler(common.UPDATE_SUBSCRIBE, HandlerFunc(func(json.RawMessage) error {
+		return nil
+	}))
+	return c.send(common.UPDATE_SUBSCRIBE, nil)
+}
+
 func (c *Client) LoadExtension(path string) (*common.ExtensionInfo, error) {
 	return invoke[common.ExtensionInfo](c, common.UPDATE_LOAD_EXT, &common.LoadExtensionParams{Path: path})
 }
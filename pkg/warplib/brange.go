@@ -0,0 +1,87 @@
+package warplib
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ParseByteRange parses a byte range in the format of http range
+// header values without the unit: "START-END" where END is inclusive,
+// "START-" for the content from START till the end and "-N" for the
+// last N bytes. It returns the range as accepted by RangeStart and
+// RangeEnd of DownloaderOpts.
+func ParseByteRange(s string) (start, end int64, err error) {
+	first, last, ok := strings.Cut(strings.TrimSpace(s), "-")
+	if !ok {
+		err = fmt.Errorf("%w: %q", ErrInvalidRange, s)
+		return
+	}
+	if first == "" {
+		// suffix range
+		start, err = strconv.ParseInt(last, 10, 64)
+		if err != nil || start <= 0 {
+			err = fmt.Errorf("%w: %q", ErrInvalidRange, s)
+			return
+		}
+		return -start, 0, nil
+	}
+	start, err = strconv.ParseInt(first, 10, 64)
+	if err != nil || start < 0 {
+		err = fmt.Errorf("%w: %q", ErrInvalidRange, s)
+		return
+	}
+	if last == "" {
+		return start, 0, nil
+	}
+	end, err = strconv.ParseInt(last, 10, 64)
+	if err != nil || end < start {
+		err = fmt.Errorf("%w: %q", ErrInvalidRange, s)
+		return
+	}
+	// RangeEnd is exclusive
+	return start, end + 1, nil
+}
+
+// applyRange limits the download to the requested byte range
+// of the remote file, content length of the downloader becomes
+// the length of the range.
+func (d *Downloader) applyRange(start, end int64) error {
+	if start == 0 && end == 0 {
+		return nil
+	}
+	if d.contentLength.IsUnknown() {
+		return fmt.Errorf("%w: size of the file is unknown", ErrRangeNotSupported)
+	}
+	if !d.probe.RangesSupported || d.decode && d.contentEncoding != "" {
+		return fmt.Errorf("%w: server doesn't accept range requests", ErrRangeNotSupported)
+	}
+	cl := d.contentLength.v()
+	if start < 0 {
+		// suffix range
+		start = max(cl+start, 0)
+		end = cl
+	}
+	if end == 0 || end > cl {
+		end = cl
+	}
+	if start >= end {
+		return fmt.Errorf("%w: %d-%d of %d bytes", ErrInvalidRange, start, end-1, cl)
+	}
+	d.rangeStart = start
+	d.ranged = true
+	d.contentLength = ContentLength(end - start)
+	return nil
+}
+
+// IsRanged reports whether only a byte range of
+// the remote file is downloaded.
+func (d *Downloader) IsRanged() bool {
+	return d.ranged
+}
+
+// GetRange returns the byte range of the remote file being
+// downloaded, end is exclusive.
+func (d *Downloader) GetRange() (start, end int64) {
+	return d.rangeStart, d.rangeStart + d.contentLength.v()
+}
@@ -0,0 +1,83 @@
+package warplib
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"os"
+	"testing"
+)
+
+func TestParseByteRange(t *testing.T) {
+	tests := []struct {
+		in         string
+		start, end int64
+		wantErr    bool
+	}{
+		{"0-1048575", 0, 1048576, false},
+		{"100-", 100, 0, false},
+		{"-65536", -65536, 0, false},
+		{"0-0", 0, 1, false},
+		{"10-5", 0, 0, true},
+		{"-0", 0, 0, true},
+		{"abc", 0, 0, true},
+		{"1-x", 0, 0, true},
+	}
+	for _, tt := range tests {
+		start, end, err := ParseByteRange(tt.in)
+		if tt.wantErr {
+			if !errors.Is(err, ErrInvalidRange) {
+				t.Errorf("ParseByteRange(%q) error = %v, want ErrInvalidRange", tt.in, err)
+			}
+			continue
+		}
+		if err != nil || start != tt.start || end != tt.end {
+			t.Errorf("ParseByteRange(%q) = %d, %d, %v, want %d, %d", tt.in, start, end, err, tt.start, tt.end)
+		}
+	}
+}
+
+func TestDownloader_Range(t *testing.T) {
+	content := bytes.Repeat([]byte("0123456789"), 200*1024)
+	srv := newTestServer(t, content)
+	tests := []struct {
+		name string
+		rng  string
+		want []byte
+	}{
+		{"window", "100000-1299999", content[100000:1300000]},
+		{"suffix", "-65536", content[len(content)-65536:]},
+		{"open ended", "1500000-", content[1500000:]},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start, end, err := ParseByteRange(tt.rng)
+			if err != nil {
+				t.Fatal(err)
+			}
+			d, err := NewDownloader(http.DefaultClient, srv.URL+"/file.bin", &DownloaderOpts{
+				DownloadDirectory: t.TempDir(),
+				MaxConnections:    4,
+				RangeStart:        start,
+				RangeEnd:          end,
+			})
+			if err != nil {
+				t.Fatalf("NewDownloader() error = %v", err)
+			}
+			defer os.RemoveAll(d.dlPath)
+			if got := d.GetContentLengthAsInt(); got != int64(len(tt.want)) {
+				t.Errorf("GetContentLength() = %d, want %d", got, len(tt.want))
+			}
+			if err = d.Start(); err != nil {
+				t.Fatalf("Start() error = %v", err)
+			}
+			b, err := os.ReadFile(d.GetSavePath())
+			if err != nil {
+				t.Fatalf("ReadFile() error = %v", err)
+			}
+			if !bytes.Equal(b, tt.want) {
+				t.Errorf("downloaded content mismatch: got %d bytes, want %d", len(b), len(tt.want))
+			}
+		})
+	}
+}
@@ -0,0 +1,158 @@
+package warplib
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// Category sends the downloads matching any of its rules to its
+// directory, the download directory has to be left empty for the
+// categories to be used.
+type Category struct {
+	Name string `json:"name"`
+	// Directory is where the downloads of the category are
+	// saved, a leading "~" is the home directory of the user.
+	Directory string `json:"directory"`
+	// Tags are added to the downloads of the category.
+	Tags []string `json:"tags,omitempty"`
+	// Extensions of the file names, such as "iso" or "tar.gz".
+	Extensions []string `json:"extensions,omitempty"`
+	// MimeTypes of the content, such as "application/pdf".
+	// "video/*" matches all the video types.
+	MimeTypes []string `json:"mime_types,omitempty"`
+	// Hosts of the urls, a host also matches its subdomains.
+	Hosts []string `json:"hosts,omitempty"`
+	// UrlRegex is a regular expression matched against the url.
+	UrlRegex string `json:"url_regex,omitempty"`
+
+	urlRe *regexp.Regexp
+}
+
+// Categories are matched in order, the first matching one is used.
+type Categories []*Category
+
+// LoadCategories reads the categories from the JSON file at path,
+// which holds a list of categories. It returns no categories if
+// the file doesn't exist.
+func LoadCategories(path string) (Categories, error) {
+	b, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var c Categories
+	err = json.Unmarshal(b, &c)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s: %w", ErrInvalidCategory, filepath.Base(path), err)
+	}
+	for _, cat := range c {
+		err = cat.init()
+		if err != nil {
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+func (c *Category) init() (err error) {
+	if c.Name == "" || c.Directory == "" {
+		return fmt.Errorf("%w: name and directory are required", ErrInvalidCategory)
+	}
+	c.Directory, err = expandHome(c.Directory)
+	if err != nil {
+		return
+	}
+	c.Directory, err = filepath.Abs(c.Directory)
+	if err != nil {
+		return
+	}
+	if c.UrlRegex != "" {
+		c.urlRe, err = regexp.Compile(c.UrlRegex)
+		if err != nil {
+			return fmt.Errorf("%w: %s: %w", ErrInvalidCategory, c.Name, err)
+		}
+	}
+	return
+}
+
+// Match returns the first category matching the download of
+// fileName from rawUrl with the content type, nil if none does.
+func (c Categories) Match(fileName, contentType, rawUrl string) *Category {
+	var host string
+	if u, err := url.Parse(rawUrl); err == nil {
+		host = strings.ToLower(u.Hostname())
+	}
+	mt, _, _ := mime.ParseMediaType(contentType)
+	fileName = strings.ToLower(fileName)
+	for _, cat := range c {
+		if cat.match(fileName, mt, host, rawUrl) {
+			return cat
+		}
+	}
+	return nil
+}
+
+func (c *Category) match(fileName, mt, host, rawUrl string) bool {
+	for _, ext := range c.Extensions {
+		ext = strings.ToLower(strings.TrimPrefix(ext, "."))
+		if ext != "" && strings.HasSuffix(fileName, "."+ext) {
+			return true
+		}
+	}
+	for _, pattern := range c.MimeTypes {
+		if matchMimeType(strings.ToLower(pattern), mt) {
+			return true
+		}
+	}
+	for _, h := range c.Hosts {
+		if matchHost(host, h) {
+			return true
+		}
+	}
+	return c.urlRe != nil && c.urlRe.MatchString(rawUrl)
+}
+
+// MatchHost reports whether the host of rawUrl
+// is host or one of its subdomains.
+func MatchHost(rawUrl, host string) bool {
+	u, err := url.Parse(rawUrl)
+	return err == nil && matchHost(strings.ToLower(u.Hostname()), host)
+}
+
+func matchHost(host, pattern string) bool {
+	pattern = strings.ToLower(strings.TrimSpace(pattern))
+	return host != "" && pattern != "" &&
+		(host == pattern || strings.HasSuffix(host, "."+pattern))
+}
+
+func matchMimeType(pattern, mt string) bool {
+	if mt == "" {
+		return false
+	}
+	if typ, ok := strings.CutSuffix(pattern, "/*"); ok {
+		return typ == "*" || strings.HasPrefix(mt, typ+"/")
+	}
+	return pattern == mt
+}
+
+// expandHome replaces a leading "~" of path
+// with the home directory of the user.
+func expandHome(path string) (string, error) {
+	if path != "~" && !strings.HasPrefix(path, "~/") && !strings.HasPrefix(path, `~\`) {
+		return path, nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, path[1:]), nil
+}
@@ -0,0 +1,71 @@
+package warplib
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCategoriesMatch(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "categories.json")
+	err := os.WriteFile(path, []byte(`[
+		{"name": "isos", "directory": "~/isos", "extensions": [".ISO", "img"]},
+		{"name": "archives", "directory": "archives", "extensions": ["tar.gz"]},
+		{"name": "videos", "directory": "/videos", "mime_types": ["video/*"], "tags": ["media"]},
+		{"name": "github", "directory": "/src", "hosts": ["github.com"]},
+		{"name": "nightly", "directory": "/nightly", "url_regex": "/nightly/[0-9]+/"}
+	]`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := LoadCategories(path)
+	if err != nil {
+		t.Fatalf("LoadCategories() error = %v", err)
+	}
+	home, _ := os.UserHomeDir()
+	if c[0].Directory != filepath.Join(home, "isos") || !filepath.IsAbs(c[1].Directory) {
+		t.Errorf("LoadCategories() directories = %s, %s", c[0].Directory, c[1].Directory)
+	}
+	tests := []struct {
+		fileName, contentType, url string
+		want                       string
+	}{
+		{"debian.iso", "application/octet-stream", "https://cdimage.debian.org/debian.iso", "isos"},
+		{"src.TAR.GZ", "", "https://example.com/src.tar.gz", "archives"},
+		{"clip.bin", "video/mp4; codecs=avc1", "https://example.com/clip", "videos"},
+		{"tool.zip", "application/zip", "https://objects.github.com/tool.zip", "github"},
+		{"tool.zip", "application/zip", "https://notgithub.com/tool.zip", ""},
+		{"app.zip", "", "https://example.com/nightly/20260101/app.zip", "nightly"},
+		{"notes.txt", "text/plain", "https://example.com/notes.txt", ""},
+	}
+	for _, tt := range tests {
+		var got string
+		if cat := c.Match(tt.fileName, tt.contentType, tt.url); cat != nil {
+			got = cat.Name
+		}
+		if got != tt.want {
+			t.Errorf("Match(%q, %q, %q) = %q, want %q", tt.fileName, tt.contentType, tt.url, got, tt.want)
+		}
+	}
+}
+
+func TestLoadCategoriesInvalid(t *testing.T) {
+	c, err := LoadCategories(filepath.Join(t.TempDir(), "missing.json"))
+	if err != nil || c != nil {
+		t.Errorf("LoadCategories() of a missing file = %v, %v", c, err)
+	}
+	for _, body := range []string{
+		`{"name": "isos"}`,
+		`[{"name": "isos", "extensions": ["iso"]}]`,
+		`[{"name": "bad", "directory": "/x", "url_regex": "("}]`,
+	} {
+		path := filepath.Join(t.TempDir(), "categories.json")
+		if err := os.WriteFile(path, []byte(body), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := LoadCategories(path); !errors.Is(err, ErrInvalidCategory) {
+			t.Errorf("LoadCategories(%s) error = %v, want %v", body, err, ErrInvalidCategory)
+		}
+	}
+}
@@ -42,6 +42,6 @@ func (c ContentLength) Format(sep string, sizeOpts ...SizeOption) (clen string)
 	return
 }
 
-func (c *ContentLength) IsUnknown() (unknown bool) {
+func (c ContentLength) IsUnknown() (unknown bool) {
 	return c.v() == -1
 }
@@ -0,0 +1,106 @@
+package warplib
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ConflictPolicy decides what happens when a file with
+// the same name already exists at the save path.
+type ConflictPolicy string
+
+const (
+	// ConflictOverwrite replaces the existing file.
+	ConflictOverwrite ConflictPolicy = "overwrite"
+	// ConflictRename saves the file as "name (N).ext".
+	ConflictRename ConflictPolicy = "rename"
+	// ConflictSkip skips the download if the existing file
+	// has the same size, otherwise it is overwritten. Only
+	// the sizes are compared, not the content of the files.
+	ConflictSkip ConflictPolicy = "skip"
+	// ConflictFail returns ErrFileExists.
+	ConflictFail ConflictPolicy = "fail"
+)
+
+const DEF_CONFLICT_POLICY = ConflictOverwrite
+
+// ParseConflictPolicy parses the string representation of
+// a conflict policy, empty string results in the default policy.
+func ParseConflictPolicy(s string) (ConflictPolicy, error) {
+	switch p := ConflictPolicy(strings.ToLower(s)); p {
+	case "":
+		return DEF_CONFLICT_POLICY, nil
+	case ConflictOverwrite, ConflictRename, ConflictSkip, ConflictFail:
+		return p, nil
+	default:
+		return "", fmt.Errorf("invalid conflict policy: %s", s)
+	}
+}
+
+// resolveConflict applies the conflict policy if a file already
+// exists at the save path of the downloader. A temporary file at the
+// save path belongs to another download of the same name, hence only
+// the rename policy can resolve it.
+func (d *Downloader) resolveConflict(policy ConflictPolicy, tmpSuffix string) error {
+	if fileExists(d.GetSavePath() + tmpSuffix) {
+		if policy == ConflictRename {
+			d.fileName = nextAvailableName(d.dlLoc, d.fileName, tmpSuffix)
+			return nil
+		}
+		return fmt.Errorf("%w: %s is being downloaded", ErrFileExists, d.GetSavePath())
+	}
+	fi, err := os.Stat(d.GetSavePath())
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if fi.IsDir() {
+		return fmt.Errorf("%w: %s is a directory", ErrFileExists, d.GetSavePath())
+	}
+	switch policy {
+	case "", ConflictOverwrite:
+		return nil
+	case ConflictRename:
+		d.fileName = nextAvailableName(d.dlLoc, d.fileName, tmpSuffix)
+		return nil
+	case ConflictSkip:
+		if cl := d.contentLength.v(); cl != -1 && cl == fi.Size() {
+			return fmt.Errorf("%w: %s", ErrDownloadSkipped, d.GetSavePath())
+		}
+		return nil
+	case ConflictFail:
+		return fmt.Errorf("%w: %s", ErrFileExists, d.GetSavePath())
+	default:
+		return fmt.Errorf("invalid conflict policy: %s", policy)
+	}
+}
+
+// reserveTempFile creates the temporary file of the downloader,
+// it fails with fs.ErrExist if a concurrent download of the same
+// name has created it since the conflict was resolved.
+func (d *Downloader) reserveTempFile() error {
+	f, err := os.OpenFile(d.tmpPath, os.O_RDWR|os.O_CREATE|os.O_EXCL, 0666)
+	if err != nil {
+		return err
+	}
+	return f.Close()
+}
+
+// nextAvailableName returns the first "name (N).ext" which
+// neither exists in dir nor has a temporary file in it.
+func nextAvailableName(dir, name, tmpSuffix string) string {
+	ext := filepath.Ext(name)
+	base := strings.TrimSuffix(name, ext)
+	for i := 1; ; i++ {
+		n := fmt.Sprintf("%s (%d)%s", base, i, ext)
+		path := GetPath(dir, n)
+		if fileExists(path) || fileExists(path+tmpSuffix) {
+			continue
+		}
+		return n
+	}
+}
@@ -0,0 +1,116 @@
+package warplib
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func Test_nextAvailableName(t *testing.T) {
+	dir := t.TempDir()
+	for _, n := range []string{"file.zip", "file (1).zip", "file (2).zip" + DEF_TEMP_SUFFIX} {
+		if err := os.WriteFile(filepath.Join(dir, n), nil, 0666); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got, want := nextAvailableName(dir, "file.zip", DEF_TEMP_SUFFIX), "file (3).zip"; got != want {
+		t.Errorf("nextAvailableName() = %v, want %v", got, want)
+	}
+}
+
+func TestDownloader_resolveConflict(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "file.bin"), make([]byte, 10), 0666); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name     string
+		policy   ConflictPolicy
+		size     int64
+		wantName string
+		wantErr  error
+	}{
+		{"overwrite", ConflictOverwrite, 10, "file.bin", nil},
+		{"rename", ConflictRename, 10, "file (1).bin", nil},
+		{"skip identical", ConflictSkip, 10, "file.bin", ErrDownloadSkipped},
+		{"skip different", ConflictSkip, 20, "file.bin", nil},
+		{"fail", ConflictFail, 10, "file.bin", ErrFileExists},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := &Downloader{dlLoc: dir, fileName: "file.bin", contentLength: ContentLength(tt.size)}
+			err := d.resolveConflict(tt.policy, DEF_TEMP_SUFFIX)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("resolveConflict() error = %v, want %v", err, tt.wantErr)
+			}
+			if d.fileName != tt.wantName {
+				t.Errorf("resolveConflict() file name = %v, want %v", d.fileName, tt.wantName)
+			}
+		})
+	}
+}
+
+func TestDownloader_resolveConflictTempFile(t *testing.T) {
+	dir := t.TempDir()
+	// another download of the same name is in progress
+	if err := os.WriteFile(filepath.Join(dir, "file.bin"+DEF_TEMP_SUFFIX), nil, 0666); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name     string
+		policy   ConflictPolicy
+		wantName string
+		wantErr  error
+	}{
+		{"overwrite", ConflictOverwrite, "file.bin", ErrFileExists},
+		{"rename", ConflictRename, "file (1).bin", nil},
+		{"skip", ConflictSkip, "file.bin", ErrFileExists},
+		{"fail", ConflictFail, "file.bin", ErrFileExists},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := &Downloader{dlLoc: dir, fileName: "file.bin", contentLength: 10}
+			err := d.resolveConflict(tt.policy, DEF_TEMP_SUFFIX)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("resolveConflict() error = %v, want %v", err, tt.wantErr)
+			}
+			if d.fileName != tt.wantName {
+				t.Errorf("resolveConflict() file name = %v, want %v", d.fileName, tt.wantName)
+			}
+		})
+	}
+}
+
+func TestNewDownloader_ReservesTempFile(t *testing.T) {
+	srv := newTestServer(t, []byte("warp"))
+	dir := t.TempDir()
+	newDownloader := func(policy ConflictPolicy) (*Downloader, error) {
+		d, err := NewDownloader(srv.Client(), srv.URL+"/file.bin", &DownloaderOpts{
+			DownloadDirectory: dir,
+			ConflictPolicy:    policy,
+		})
+		if err == nil {
+			t.Cleanup(func() { os.RemoveAll(d.dlPath) })
+		}
+		return d, err
+	}
+	d, err := newDownloader(ConflictOverwrite)
+	if err != nil {
+		t.Fatalf("NewDownloader() error = %v", err)
+	}
+	if !fileExists(d.GetTempPath()) {
+		t.Fatalf("temporary file %s wasn't reserved", d.GetTempPath())
+	}
+	// the first download hasn't started yet
+	if _, err = newDownloader(ConflictOverwrite); !errors.Is(err, ErrFileExists) {
+		t.Errorf("NewDownloader() error = %v, want %v", err, ErrFileExists)
+	}
+	d2, err := newDownloader(ConflictRename)
+	if err != nil {
+		t.Fatalf("NewDownloader() error = %v", err)
+	}
+	if got, want := d2.GetFileName(), "file (1).bin"; got != want {
+		t.Errorf("GetFileName() = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,194 @@
+package warplib
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"path"
+	"regexp"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+const (
+	// DEF_CRAWL_DEPTH is the default depth of subdirectories crawled.
+	DEF_CRAWL_DEPTH = 5
+	// DEF_CRAWL_MAX_PAGE_SIZE limits the size of an index page read.
+	DEF_CRAWL_MAX_PAGE_SIZE = 16 * MB
+)
+
+type CrawlOpts struct {
+	// MaxDepth limits how deep subdirectories are crawled,
+	// only the files of the index itself are collected if 0.
+	MaxDepth int
+	// OtherHosts also collects files linked from other hosts,
+	// directories are only crawled on the host of the index.
+	OtherHosts bool
+	// Include and Exclude filter the files by their url.
+	Include *regexp.Regexp
+	Exclude *regexp.Regexp
+	// Extensions filters the files by their extensions,
+	// such as "iso" or "tar.gz".
+	Extensions []string
+	Headers    Headers
+}
+
+// CrawledFile is a file found by Crawl.
+type CrawledFile struct {
+	Url string
+	// Dir is the directory of the file relative to the crawled index,
+	// it uses forward slashes and is empty for files of the index itself.
+	Dir string
+}
+
+type crawlPage struct {
+	url   *url.URL
+	dir   string
+	depth int
+}
+
+// Crawl fetches the html directory index (as served by Apache and
+// nginx autoindex) at url and collects the files it links to,
+// crawling the linked subdirectories up to opts.MaxDepth. It
+// returns the crawled files and the effective url of the index.
+func Crawl(ctx context.Context, client *http.Client, rawUrl string, opts *CrawlOpts) (files []CrawledFile, root *url.URL, err error) {
+	if opts == nil {
+		opts = &CrawlOpts{}
+	}
+	root, err = url.Parse(rawUrl)
+	if err != nil {
+		return
+	}
+	var (
+		queue   = []crawlPage{{url: root}}
+		visited = make(map[string]bool)
+		seen    = make(map[string]bool)
+	)
+	for len(queue) != 0 {
+		page := queue[0]
+		queue = queue[1:]
+		if visited[page.url.String()] {
+			continue
+		}
+		visited[page.url.String()] = true
+		links, final, er := fetchIndex(ctx, client, page.url, opts.Headers)
+		if er != nil {
+			if page.url == root {
+				err = er
+				return
+			}
+			// a broken subdirectory shouldn't fail the crawl
+			continue
+		}
+		if page.url == root {
+			// directory links are resolved relative to the
+			// index after redirects, e.g. "/pub" to "/pub/".
+			root = final
+			if !strings.HasSuffix(root.Path, "/") {
+				root.Path = path.Dir(root.Path) + "/"
+			}
+		}
+		for _, link := range links {
+			u, er := final.Parse(link)
+			if er != nil || u.Scheme != "http" && u.Scheme != "https" {
+				continue
+			}
+			u.Fragment = ""
+			if u.RawQuery != "" {
+				// sorting links of autoindex pages
+				continue
+			}
+			sameHost := u.Host == root.Host
+			if strings.HasSuffix(u.Path, "/") {
+				// parent directory and links outside of the
+				// crawled index aren't followed.
+				if !sameHost || page.depth >= opts.MaxDepth ||
+					len(u.Path) <= len(root.Path) || !strings.HasPrefix(u.Path, root.Path) {
+					continue
+				}
+				queue = append(queue, crawlPage{
+					url:   u,
+					dir:   strings.TrimSuffix(strings.TrimPrefix(path.Clean(u.Path), path.Clean(root.Path)+"/"), "/"),
+					depth: page.depth + 1,
+				})
+				continue
+			}
+			if !sameHost && !opts.OtherHosts || seen[u.String()] || !opts.match(u) {
+				continue
+			}
+			seen[u.String()] = true
+			files = append(files, CrawledFile{Url: u.String(), Dir: page.dir})
+		}
+	}
+	return
+}
+
+func (opts *CrawlOpts) match(u *url.URL) bool {
+	s := u.String()
+	if opts.Include != nil && !opts.Include.MatchString(s) {
+		return false
+	}
+	if opts.Exclude != nil && opts.Exclude.MatchString(s) {
+		return false
+	}
+	if len(opts.Extensions) == 0 {
+		return true
+	}
+	p := strings.ToLower(u.Path)
+	for _, ext := range opts.Extensions {
+		if strings.HasSuffix(p, "."+strings.ToLower(strings.TrimPrefix(ext, "."))) {
+			return true
+		}
+	}
+	return false
+}
+
+// fetchIndex returns the links of the html page at u
+// and its url after redirects.
+func fetchIndex(ctx context.Context, client *http.Client, u *url.URL, headers Headers) (links []string, final *url.URL, err error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return
+	}
+	headers.Set(req.Header)
+	resp, err := client.Do(req)
+	if err != nil {
+		return
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 400 {
+		err = fmt.Errorf("%w: %s", ErrUnexpectedStatus, resp.Status)
+		return
+	}
+	if mt, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type")); mt != "text/html" {
+		err = fmt.Errorf("%w: %s is not an html index", ErrNotSupported, u)
+		return
+	}
+	final = resp.Request.URL
+	z := html.NewTokenizer(io.LimitReader(resp.Body, DEF_CRAWL_MAX_PAGE_SIZE))
+	for {
+		switch z.Next() {
+		case html.ErrorToken:
+			if z.Err() != io.EOF {
+				err = z.Err()
+			}
+			return
+		case html.StartTagToken, html.SelfClosingTagToken:
+			name, hasAttr := z.TagName()
+			if string(name) != "a" {
+				continue
+			}
+			for hasAttr {
+				var key, val []byte
+				key, val, hasAttr = z.TagAttr()
+				if string(key) == "href" {
+					links = append(links, string(val))
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package warplib
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"regexp"
+	"testing"
+)
+
+func TestCrawl(t *testing.T) {
+	pages := map[string]string{
+		"/pub/": `<a href="../">Parent Directory</a> <a href="?C=N;O=D">Name</a>
+			<a href="a.iso">a.iso</a> <a href="b.txt">b.txt</a> <a href="sub/">sub/</a>
+			<a href="http://other.host/c.iso">c.iso</a>`,
+		"/pub/sub/":      `<a href="/pub/">Parent</a> <a href="d.iso">d.iso</a> <a href="deep/">deep/</a>`,
+		"/pub/sub/deep/": `<a href="e.iso">e.iso</a>`,
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/pub" {
+			http.Redirect(w, r, "/pub/", http.StatusMovedPermanently)
+			return
+		}
+		page, ok := pages[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write([]byte(page))
+	}))
+	defer srv.Close()
+	tests := []struct {
+		name string
+		opts *CrawlOpts
+		want []CrawledFile
+	}{
+		{"index only", &CrawlOpts{}, []CrawledFile{
+			{srv.URL + "/pub/a.iso", ""},
+			{srv.URL + "/pub/b.txt", ""},
+		}},
+		{"depth and extension", &CrawlOpts{MaxDepth: 1, Extensions: []string{"iso"}}, []CrawledFile{
+			{srv.URL + "/pub/a.iso", ""},
+			{srv.URL + "/pub/sub/d.iso", "sub"},
+		}},
+		{"exclude and other hosts", &CrawlOpts{MaxDepth: 5, OtherHosts: true, Exclude: regexp.MustCompile(`\.txt$`)}, []CrawledFile{
+			{srv.URL + "/pub/a.iso", ""},
+			{"http://other.host/c.iso", ""},
+			{srv.URL + "/pub/sub/d.iso", "sub"},
+			{srv.URL + "/pub/sub/deep/e.iso", "sub/deep"},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			files, root, err := Crawl(context.Background(), srv.Client(), srv.URL+"/pub", tt.opts)
+			if err != nil {
+				t.Fatalf("Crawl() error = %v", err)
+			}
+			if root.Path != "/pub/" {
+				t.Errorf("Crawl() root = %v, want /pub/", root)
+			}
+			if !reflect.DeepEqual(files, tt.want) {
+				t.Errorf("Crawl() = %v, want %v", files, tt.want)
+			}
+		})
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"io/fs"
 	"log"
 	"net/http"
 	"os"
@@ -24,8 +25,27 @@ type Downloader struct {
 	client *http.Client
 	// Url of the file to be downloaded
 	url string
+	// Url the original url resolved to after following
+	// redirects, parts download from this url if set.
+	effectiveUrl string
+	// Urls visited while resolving the original url.
+	redirects []string
+	// ETag of the remote file, if sent by server.
+	etag string
+	// Result of probing the remote file.
+	probe *ProbeResult
+	// Content encoding of the response, if any.
+	contentEncoding string
+	// Decode encoded content on the fly.
+	decode bool
 	// File name to be used while saving it
 	fileName string
+	// Path of the temporary file content is written to
+	// before being renamed to the final save path.
+	tmpPath string
+	// Optional verification to be run over the temporary
+	// file before renaming it.
+	verify VerifyFunc
 	// Size of file, wrapped inside ContentLength
 	contentLength ContentLength
 	// Download location (directory) of the file.
@@ -35,6 +55,32 @@ type Downloader struct {
 	chunk int
 	// Max connections and number of curr connections
 	maxConn, numConn int32
+	// Connection limit decided by the connection tuner,
+	// never greater than maxConn.
+	connLimit int32
+	// Connection cap set while downloading, it bounds the
+	// connection limit in place of maxConn if not 0.
+	connCap int32
+	// Number of pending requests for parts to split
+	// themselves, raised by the connection tuner.
+	splitReq int32
+	// Disables the connection tuner.
+	disableTuning bool
+	// Parts being downloaded, idle connections steal
+	// work from them. segMu guards the map along with
+	// the final offsets of the parts.
+	active map[string]*Part
+	segMu  sync.Mutex
+	// Race duplicate requests for the last segments.
+	raceTail bool
+	// Offset of the downloaded window in the remote file,
+	// ranged is set if only a part of the file is downloaded.
+	rangeStart int64
+	ranged     bool
+	// Limits the total speed of the download.
+	limiter *speedLimiter
+	// Measures the current speed of the download.
+	meter *speedMeter
 	// Max spawnable parts and number of curr parts
 	maxParts, numParts int32
 	// Initial number of parts to be spawned
@@ -47,18 +93,24 @@ type Downloader struct {
 	handlers *Handlers
 	// unique hash of this download
 	hash string
+	// category which picked the download directory, if any
+	category *Category
 	// headers to use for http requests
 	headers Headers
 	// total downloaded bytes
-	nread     int64
+	nread int64
+	// bytes received over network, used to
+	// measure the aggregate throughput.
+	dread     int64
 	dlPath    string
 	wg        *sync.WaitGroup
 	ohmap     VMap[int64, string]
 	l         *log.Logger
 	lw        io.WriteCloser
 	f         *os.File
-	stopped   bool
+	stopped   atomic.Bool
 	resumable bool
+	running   int32
 }
 
 // Optional fields of downloader
@@ -74,6 +126,10 @@ type DownloaderOpts struct {
 	// DownloadDirectory sets the download directory for
 	// file to be downloaded.
 	DownloadDirectory string
+	// Categories pick the download directory by the file name,
+	// content type and url if DownloadDirectory is empty. The
+	// current directory is used if none of them matches.
+	Categories Categories
 	// MaxConnections sets the maximum number of parallel
 	// network connections to be used for the downloading the file.
 	MaxConnections int32
@@ -86,8 +142,51 @@ type DownloaderOpts struct {
 	Handlers *Handlers
 
 	SkipSetup bool
+
+	// TempSuffix is appended to the file name to form the
+	// temporary file which is written to while downloading.
+	// The temporary file is renamed to its final name once
+	// all parts are compiled (default: ".warp.part").
+	TempSuffix string
+	// Verify, if set, is called with the temporary file path
+	// once download is complete. The file isn't renamed to its
+	// final name if it returns an error.
+	Verify VerifyFunc
+	// ConflictPolicy decides what to do if a file already
+	// exists at the save path (default: overwrite).
+	ConflictPolicy ConflictPolicy
+	// UseOriginalUrl makes parts request the original url
+	// instead of the url it resolved to after redirects.
+	UseOriginalUrl bool
+	// DisableTuning disables adjusting the number of
+	// connections based on aggregate throughput, parts are
+	// then only split when they run slow.
+	DisableTuning bool
+	// RaceTail makes idle connections download the last
+	// segments of the file in duplicate, using the bytes
+	// of whichever connection finishes first.
+	RaceTail bool
+	// RangeStart and RangeEnd limit the download to a byte
+	// range of the remote file, RangeEnd is exclusive and 0
+	// means the end of the file. A negative RangeStart is a
+	// suffix range of the last -RangeStart bytes.
+	// Use ParseByteRange to parse them from a string.
+	RangeStart, RangeEnd int64
+	// DecodeContent requests compressed content and decodes
+	// it on the fly. Encoded content is always downloaded in
+	// a single connection and can't be resumed.
+	//
+	// Note: Warplib requests identity encoding otherwise and
+	// stores the raw body if server still encodes it.
+	DecodeContent bool
+	// SpeedLimit limits the total download speed in bytes
+	// per second, unlimited if 0. See SetSpeedLimit.
+	SpeedLimit int64
 }
 
+// VerifyFunc verifies the downloaded content present at path.
+type VerifyFunc func(path string) error
+
 // NewDownloader creates a new downloader with provided arguments.
 // Use downloader.Start() to download the file.
 func NewDownloader(client *http.Client, url string, opts *DownloaderOpts) (d *Downloader, err error) {
@@ -104,11 +203,20 @@ func NewDownloader(client *http.Client, url string, opts *DownloaderOpts) (d *Do
 		opts.Headers = make(Headers, 0)
 	}
 	opts.Headers.InitOrUpdate(USER_AGENT_KEY, DEF_USER_AGENT)
+	// control the encoding explicitly as go's transport
+	// decompresses gzip content transparently otherwise,
+	// which breaks content length and range arithmetic.
+	if opts.DecodeContent {
+		opts.Headers.Update(ACCEPT_ENCODING_KEY, DEF_ACCEPT_ENCODING)
+	} else {
+		opts.Headers.Update(ACCEPT_ENCODING_KEY, ENCODING_IDENTITY)
+	}
 	// loc := opts.DownloadDirectory
 	// loc = strings.TrimSuffix(loc, "/")
 	// if loc == "" {
 	// 	loc = "."
 	// }
+	categorize := opts.DownloadDirectory == "" && len(opts.Categories) != 0
 	opts.DownloadDirectory, err = filepath.Abs(
 		opts.DownloadDirectory,
 	)
@@ -117,29 +225,73 @@ func NewDownloader(client *http.Client, url string, opts *DownloaderOpts) (d *Do
 	}
 	ctx, cancel := context.WithCancel(context.Background())
 	d = &Downloader{
-		ctx:       ctx,
-		cancel:    cancel,
-		wg:        &sync.WaitGroup{},
-		client:    client,
-		url:       url,
-		maxConn:   opts.MaxConnections,
-		chunk:     int(DEF_CHUNK_SIZE),
-		force:     opts.ForceParts,
-		handlers:  opts.Handlers,
-		fileName:  opts.FileName,
-		dlLoc:     opts.DownloadDirectory,
-		maxParts:  opts.MaxSegments,
-		headers:   opts.Headers,
-		resumable: true,
-	}
-	err = d.fetchInfo()
+		ctx:           ctx,
+		cancel:        cancel,
+		wg:            &sync.WaitGroup{},
+		client:        client,
+		url:           url,
+		maxConn:       opts.MaxConnections,
+		chunk:         int(DEF_CHUNK_SIZE),
+		force:         opts.ForceParts,
+		handlers:      opts.Handlers,
+		fileName:      opts.FileName,
+		dlLoc:         opts.DownloadDirectory,
+		maxParts:      opts.MaxSegments,
+		headers:       opts.Headers,
+		verify:        opts.Verify,
+		decode:        opts.DecodeContent,
+		disableTuning: opts.DisableTuning,
+		raceTail:      opts.RaceTail,
+		limiter:       &speedLimiter{rate: max(opts.SpeedLimit, 0)},
+		meter:         &speedMeter{},
+		resumable:     true,
+	}
+	err = d.fetchInfo(opts.RangeStart, opts.RangeEnd)
 	if err != nil {
 		return
 	}
+	if opts.UseOriginalUrl {
+		d.effectiveUrl = ""
+	}
+	if categorize {
+		d.category = opts.Categories.Match(d.fileName, d.probe.Header.Get("Content-Type"), d.url)
+		if d.category != nil {
+			d.dlLoc = d.category.Directory
+		}
+	}
 	if opts.SkipSetup {
 		// Skip setting up dl path and stuff for a general download lookup.
 		return
 	}
+	if d.category != nil {
+		// directories of categories are created on first use
+		err = os.MkdirAll(d.dlLoc, os.ModePerm)
+		if err != nil {
+			return
+		}
+	}
+	if opts.TempSuffix == "" {
+		opts.TempSuffix = DEF_TEMP_SUFFIX
+	}
+	for {
+		err = d.resolveConflict(opts.ConflictPolicy, opts.TempSuffix)
+		if err != nil {
+			return
+		}
+		d.tmpPath = d.GetSavePath() + opts.TempSuffix
+		err = d.reserveTempFile()
+		if !errors.Is(err, fs.ErrExist) {
+			break
+		}
+	}
+	if err != nil {
+		return
+	}
+	defer func() {
+		if err != nil {
+			_ = os.Remove(d.tmpPath)
+		}
+	}()
 	d.setHash()
 	err = d.setupDlPath()
 	if err != nil {
@@ -150,8 +302,20 @@ func NewDownloader(client *http.Client, url string, opts *DownloaderOpts) (d *Do
 		return
 	}
 	d.l.Println("GET:", d.url)
+	for _, u := range d.redirects {
+		d.l.Println("REDIRECT:", u)
+	}
+	d.l.Println("EFFECTIVE-URL:", d.getPartUrl())
 	d.l.Println("CONTENT-LENGTH:", d.contentLength.v(), "(", d.contentLength, ")")
+	if d.ranged {
+		start, end := d.GetRange()
+		d.l.Println("RANGE:", start, "-", end-1)
+	}
 	d.l.Println("FILE-NAME:", d.fileName)
+	if d.contentEncoding != "" {
+		d.l.Println("CONTENT-ENCODING:", d.contentEncoding, "( decode:", d.decode, ")")
+	}
+	d.l.Println("TEMP-PATH:", d.tmpPath)
 	d.handlers.setDefault(d.l)
 	if opts.NumBaseParts != 0 {
 		d.numBaseParts = opts.NumBaseParts
@@ -182,6 +346,13 @@ func initDownloader(client *http.Client, hash, url string, cLength ContentLength
 		opts.Headers = make(Headers, 0)
 	}
 	opts.Headers.InitOrUpdate(USER_AGENT_KEY, DEF_USER_AGENT)
+	// decoded downloads can't be resumed, but the ones
+	// which haven't started yet have to be decoded.
+	if opts.DecodeContent {
+		opts.Headers.Update(ACCEPT_ENCODING_KEY, DEF_ACCEPT_ENCODING)
+	} else {
+		opts.Headers.Update(ACCEPT_ENCODING_KEY, ENCODING_IDENTITY)
+	}
 	// loc := opts.DownloadDirectory
 	// loc = strings.TrimSuffix(loc, "/")
 	// if loc == "" {
@@ -201,6 +372,7 @@ func initDownloader(client *http.Client, hash, url string, cLength ContentLength
 		wg:            &sync.WaitGroup{},
 		client:        client,
 		url:           url,
+		headers:       opts.Headers,
 		maxConn:       opts.MaxConnections,
 		chunk:         int(DEF_CHUNK_SIZE),
 		force:         opts.ForceParts,
@@ -208,8 +380,14 @@ func initDownloader(client *http.Client, hash, url string, cLength ContentLength
 		fileName:      opts.FileName,
 		dlLoc:         opts.DownloadDirectory,
 		maxParts:      opts.MaxSegments,
+		decode:        opts.DecodeContent,
 		contentLength: cLength,
 		hash:          hash,
+		verify:        opts.Verify,
+		disableTuning: opts.DisableTuning,
+		raceTail:      opts.RaceTail,
+		limiter:       &speedLimiter{rate: max(opts.SpeedLimit, 0)},
+		meter:         &speedMeter{},
 		dlPath:        fmt.Sprintf("%s/%s/", DlDataDir, hash),
 	}
 	if !dirExists(d.dlPath) {
@@ -221,6 +399,9 @@ func initDownloader(client *http.Client, hash, url string, cLength ContentLength
 		return
 	}
 	d.handlers.setDefault(d.l)
+	if cLength.IsUnknown() {
+		d.setUnknownSize(true)
+	}
 	if d.maxParts != 0 && d.maxConn > d.maxParts {
 		d.maxConn = d.maxParts
 	}
@@ -230,22 +411,29 @@ func initDownloader(client *http.Client, hash, url string, cLength ContentLength
 // Start downloads the file and blocks current goroutine
 // until the downloading is complete.
 func (d *Downloader) Start() (err error) {
+	atomic.StoreInt32(&d.running, 1)
+	defer atomic.StoreInt32(&d.running, 0)
 	defer d.lw.Close()
-	err = d.openFile()
+	// truncate leftovers of an older temporary file
+	// as this is a fresh download.
+	err = d.openFile(os.O_TRUNC)
 	if err != nil {
 		return
 	}
-	defer func() {
-		d.f.Close()
-		// err = os.Rename(d.fName, d.GetSavePath())
-	}()
+	defer d.f.Close()
 	d.Log("Starting download...")
+	d.handlers.DownloadStartedHandler()
 	d.ohmap.Make()
+	d.segMu.Lock()
+	d.active = make(map[string]*Part)
+	d.segMu.Unlock()
 	partSize, rpartSize := d.getPartSize()
+	stopTuner := d.startTuner(d.numBaseParts)
+	defer stopTuner()
 	if partSize == -1 {
 		d.wg.Add(1)
 		d.Log("Unknown content length, downloading in a single connection...")
-		go d.downloadUnknownSizeFile()
+		go d.downloadUnknownSizeFile(0)
 	} else {
 		for i := int32(0); i < d.numBaseParts; i++ {
 			ioff := int64(i) * partSize
@@ -258,39 +446,79 @@ func (d *Downloader) Start() (err error) {
 		}
 	}
 	d.wg.Wait()
-	if d.stopped {
+	if d.stopped.Load() {
 		d.Log("Download stopped")
 		d.handlers.DownloadStoppedHandler()
 		return
 	}
-	if v := d.contentLength.v(); v != -1 && v != d.nread {
-		d.Log("Download might be corrupted | Expected bytes: %d Found bytes: %d", d.contentLength.v(), d.nread)
-		// return
+	if d.contentLength.IsUnknown() {
+		// size is known once the whole content is downloaded.
+		d.contentLength = ContentLength(d.nread)
+	}
+	if err = d.checkComplete(); err != nil {
+		return
+	}
+	err = d.finalize()
+	if err != nil {
+		d.handlers.ErrorHandler(MAIN_HASH, err)
+		return
 	}
 	d.handlers.DownloadCompleteHandler(MAIN_HASH, d.contentLength.v())
 	d.Log("All segments downloaded!")
 	return
 }
 
+// startFresh starts a download restored from an item which
+// hasn't started downloading yet.
+func (d *Downloader) startFresh(resumable bool) error {
+	if d.numBaseParts == 0 {
+		d.numBaseParts = DEF_BASE_PARTS
+		if !resumable || d.contentLength.v() < int64(d.chunk) {
+			d.numBaseParts = 1
+		}
+	}
+	return d.Start()
+}
+
 // TODO: fix concurrent write and iteration if any.
 
 // map[InitialOffset(int64)]ItemPart
 func (d *Downloader) Resume(parts map[int64]*ItemPart) (err error) {
+	atomic.StoreInt32(&d.running, 1)
+	defer atomic.StoreInt32(&d.running, 0)
 	defer d.lw.Close()
-	if len(parts) == 0 {
+	unknownSize := d.contentLength.IsUnknown()
+	if len(parts) == 0 && !unknownSize {
 		return errors.New("download is already complete")
 	}
-	err = d.openFile()
+	err = d.openFile(0)
 	if err != nil {
 		return
 	}
-	defer func() {
-		d.f.Close()
-		// err = os.Rename(d.fName, d.GetSavePath())
-	}()
+	defer d.f.Close()
 	d.Log("Resuming download...")
+	d.handlers.DownloadStartedHandler()
 	d.ohmap.Make()
-	espeed := 4 * MB / int64(len(parts))
+	d.segMu.Lock()
+	d.active = make(map[string]*Part)
+	d.segMu.Unlock()
+	if unknownSize {
+		// downloads of unknown size are written sequentially
+		// and hence the file size is the written offset.
+		var off int64
+		off, err = d.f.Seek(0, io.SeekEnd)
+		if err != nil {
+			return
+		}
+		d.Log("Unknown content length, resuming from offset %d in a single connection...", off)
+		d.handlers.ResumeProgressHandler(MAIN_HASH, int(off))
+		d.nread = off
+		d.wg.Add(1)
+		go d.downloadUnknownSizeFile(off)
+	}
+	stopTuner := d.startTuner(int32(len(parts)))
+	defer stopTuner()
+	espeed := 4 * MB / int64(max(len(parts), 1))
 	for ioff, ip := range parts {
 		if ip.Compiled {
 			d.handlers.CompileSkippedHandler(ip.Hash, ip.FinalOffset-ioff)
@@ -301,50 +529,103 @@ func (d *Downloader) Resume(parts map[int64]*ItemPart) (err error) {
 		go d.resumePartDownload(ip.Hash, ioff, ip.FinalOffset, espeed)
 	}
 	d.wg.Wait()
-	if d.stopped {
+	if d.stopped.Load() {
 		d.Log("Download stopped")
 		d.handlers.DownloadStoppedHandler()
 		return
 	}
-	if d.contentLength.v() != d.nread {
-		d.Log("Download might be corrupted | Expected bytes: %d Found bytes: %d", d.contentLength.v(), d.nread)
-		// return
+	if unknownSize {
+		d.contentLength = ContentLength(d.nread)
+	}
+	if err = d.checkComplete(); err != nil {
+		return
+	}
+	err = d.finalize()
+	if err != nil {
+		d.handlers.ErrorHandler(MAIN_HASH, err)
+		return
 	}
 	d.handlers.DownloadCompleteHandler(MAIN_HASH, d.contentLength.v())
 	d.Log("All segments downloaded!")
 	return
 }
 
-func (d *Downloader) openFile() (err error) {
-	// d.fName = d.dlPath + "warp.dl"
-	d.f, err = os.OpenFile(d.GetSavePath(),
-		os.O_RDWR|os.O_CREATE,
+// checkComplete reports ErrDownloadIncomplete to the error handler
+// if some bytes are missing, the temporary file is left as it is
+// so that the download can be resumed later.
+func (d *Downloader) checkComplete() error {
+	if v := d.contentLength.v(); v != d.nread {
+		d.Log("Download incomplete | Expected bytes: %d Found bytes: %d", v, d.nread)
+		err := fmt.Errorf("%w: expected %d bytes, found %d", ErrDownloadIncomplete, v, d.nread)
+		d.handlers.ErrorHandler(MAIN_HASH, err)
+		return err
+	}
+	return nil
+}
+
+func (d *Downloader) openFile(flag int) (err error) {
+	d.f, err = os.OpenFile(d.GetTempPath(),
+		os.O_RDWR|os.O_CREATE|flag,
 		0666,
 	)
 	return
 }
 
+// finalize verifies the downloaded content and atomically
+// renames the temporary file to its final save path.
+func (d *Downloader) finalize() (err error) {
+	tmpPath, svPath := d.GetTempPath(), d.GetSavePath()
+	// close the file before renaming it as windows doesn't
+	// allow renaming files with open handles.
+	err = d.f.Close()
+	if err != nil {
+		return
+	}
+	if d.verify != nil {
+		d.Log("Verifying downloaded content...")
+		err = d.verify(tmpPath)
+		if err != nil {
+			return fmt.Errorf("verify: %w", err)
+		}
+	}
+	if tmpPath == svPath {
+		return
+	}
+	err = os.Rename(tmpPath, svPath)
+	if err != nil {
+		return
+	}
+	d.Log("Renamed %s => %s", tmpPath, svPath)
+	return
+}
+
 func (d *Downloader) spawnPart(ioff, foff int64) (part *Part, err error) {
 	part, err = newPart(
 		d.ctx,
 		d.client,
-		d.url,
+		d.getPartUrl(),
 		partArgs{
 			int64(d.chunk),
 			d.dlPath,
 			d.handlers.ResumeProgressHandler,
-			d.handlers.DownloadProgressHandler,
+			d.progressHandler,
 			d.handlers.DownloadCompleteHandler,
 			d.handlers.CompileProgressHandler,
 			d.l,
 			ioff,
 			d.f,
+			d.url,
+			&d.splitReq,
+			d.rangeStart,
+			d.limiter,
 		},
 	)
 	if err != nil {
 		return
 	}
 	// part.offset = ioff
+	part.setFoff(foff)
+	d.addActivePart(part)
 	d.ohmap.Set(ioff, part.hash)
 	// d.numParts++
 	atomic.AddInt32(&d.numParts, 1)
@@ -358,22 +639,28 @@ func (d *Downloader) initPart(hash string, ioff, foff int64) (part *Part, err er
 		d.ctx,
 		d.client,
 		hash,
-		d.url,
+		d.getPartUrl(),
 		partArgs{
 			int64(d.chunk),
 			d.dlPath,
 			d.handlers.ResumeProgressHandler,
-			d.handlers.DownloadProgressHandler,
+			d.progressHandler,
 			d.handlers.DownloadCompleteHandler,
 			d.handlers.CompileProgressHandler,
 			d.l,
 			ioff,
 			d.f,
+			d.url,
+			&d.splitReq,
+			d.rangeStart,
+			d.limiter,
 		},
 	)
 	if err != nil {
 		return
 	}
+	part.setFoff(foff)
+	d.addActivePart(part)
 	d.ohmap.Set(ioff, hash)
 	// d.numParts++
 	atomic.AddInt32(&d.numParts, 1)
@@ -394,6 +681,7 @@ func (d *Downloader) resumePartDownload(hash string, ioff, foff, espeed int64) {
 	poff := part.offset + part.read
 	if poff >= foff {
 		d.Log("%s: part offset (%d) greater than final offset (%d)", hash, poff, foff)
+		d.removeActivePart(part)
 		_, _, err = part.compile()
 		if err != nil {
 			d.Log("%s: part compile failed: %s", hash, err.Error())
@@ -402,68 +690,57 @@ func (d *Downloader) resumePartDownload(hash string, ioff, foff, espeed int64) {
 	}
 	// CHANGE IMPL
 	err = d.runPart(part, poff, foff, espeed, false, nil)
+	d.removeActivePart(part)
 	if err != nil {
 		return
 	}
-	d.handlers.CompileStartHandler(part.hash)
-	defer d.handlers.CompileCompleteHandler(part.hash, part.read)
-
-	d.Log("%s: compiling part", hash)
-
-	var read, written int64
-	read, written, err = part.compile()
-	atomic.AddInt64(&d.nread, written)
-
-	// close part file
-	part.close()
-
-	if err != nil {
-		d.Log("%s: compile: %w", hash, err)
-		return
-	}
-	d.Log("%s: compilation complete: read %d bytes and wrote %d bytes", hash, read, written)
-
-	fName := getFileName(
-		d.dlPath,
-		hash,
-	)
-	err = os.Remove(fName)
-	if err == nil {
-		return
-	}
-	d.Log("%s: remove: %w", hash, err)
+	// connection of this part is free now.
+	d.stealWork(part, espeed)
+	d.compilePart(part)
 }
 
 func (d *Downloader) newPartDownload(ioff, foff, espeed int64) {
 	// d.numConn++
 	atomic.AddInt32(&d.numConn, 1)
+	defer func() { atomic.AddInt32(&d.numConn, -1); d.wg.Done() }()
 	part, err := d.spawnPart(ioff, foff)
 	if err != nil {
-		d.Log("failed to spawn new part: %w", err)
+		d.Log("failed to spawn new part: %s", err.Error())
 		return
 	}
-	hash := part.hash
-	defer func() { atomic.AddInt32(&d.numConn, -1); d.wg.Done() }()
+	poff := ioff
+	if ioff == 0 && d.rangeStart == 0 {
+		poff += d.reuseProbeHead(part, foff)
+	}
 	// CHANGE IMPL
-	err = d.runPart(part, ioff, foff, espeed, false, nil)
+	err = d.runPart(part, poff, foff, espeed, false, nil)
+	d.removeActivePart(part)
 	if err != nil {
 		return
 	}
+	// connection of this part is free now.
+	d.stealWork(part, espeed)
+
+	d.compilePart(part)
+}
 
+// compilePart writes the downloaded part into the main file
+// and removes the part file.
+func (d *Downloader) compilePart(part *Part) {
+	hash := part.hash
 	d.handlers.CompileStartHandler(part.hash)
 	defer d.handlers.CompileCompleteHandler(part.hash, part.read)
 
 	d.Log("%s: compiling part", hash)
 
-	var read, written int64
-	read, written, err = part.compile()
+	read, written, err := part.compile()
 	atomic.AddInt64(&d.nread, written)
 
 	// close part file
 	part.close()
 
 	if err != nil {
-		d.Log("%s: compile: %w", hash, err)
+		d.Log("%s: compile: %s", hash, err.Error())
 		return
 	}
 	d.Log("%s: compilation complete: read %d bytes and wrote %d bytes", hash, read, written)
@@ -476,7 +753,7 @@ func (d *Downloader) newPartDownload(ioff, foff, espeed int64) {
 	if err == nil {
 		return
 	}
-	d.Log("%s: remove: %w", hash, err)
+	d.Log("%s: remove: %s", hash, err.Error())
 }
 
 // runPart downloads the content starting from ioff till foff bytes
@@ -506,13 +783,16 @@ func (d *Downloader) runPart(part *Part, ioff, foff, espeed int64, repeated bool
 		// expected speed.
 		body, slow, err = part.download(d.headers, ioff, foff, force)
 	} else {
-		slow, err = part.copyBuffer(body, foff, force)
+		slow, err = part.copyBuffer(body, force)
 	}
 
 	if err != nil {
-		d.handlers.ErrorHandler(hash, err)
+		d.partError(hash, err)
 		return err
 	}
+	// final offset might have been moved by an
+	// idle connection stealing the tail of this part.
+	foff = part.getFoff()
 	if !slow {
 		expectedRead := foff - part.offset + 1
 		if part.read != expectedRead {
@@ -523,16 +803,16 @@ func (d *Downloader) runPart(part *Part, ioff, foff, espeed int64, repeated bool
 
 	// add read bytes to part offset to determine
 	// starting offset for a respawned part.
-	poff := part.offset + part.read
+	poff := part.position()
 
 	if foff-poff <= 2*MIN_PART_SIZE {
 		d.Log("%s: Detected part as running slow", hash)
 		// Min part size has been reached and hence
 		// don't spawn new part out of the current part.
 		d.Log("%s: Min part size reached, continuing as slow part...", hash)
-		_, err = part.copyBuffer(body, foff, true)
+		_, err = part.copyBuffer(body, true)
 		if err != nil {
-			d.handlers.ErrorHandler(hash, err)
+			d.partError(hash, err)
 		}
 		// return to prevent spawning further parts
 		return nil
@@ -544,15 +824,15 @@ func (d *Downloader) runPart(part *Part, ioff, foff, espeed int64, repeated bool
 		// don't spawn new parts and forcefully download
 		// rest of the content in slow part.
 		d.Log("%s: Max part limit reached, continuing slow part...", hash)
-		_, err = part.copyBuffer(body, foff, true)
+		_, err = part.copyBuffer(body, true)
 		if err != nil {
-			d.handlers.ErrorHandler(hash, err)
+			d.partError(hash, err)
 		}
 		// return to prevent spawning further parts
 		return nil
 	}
 
-	if d.maxConn != 0 && d.numConn >= d.maxConn {
+	if limit := d.getConnLimit(); limit != 0 && d.numConn >= limit {
 		// It waits until a connection is
 		// freed and spawns a new part once
 		// a slot is available.
@@ -565,7 +845,18 @@ func (d *Downloader) runPart(part *Part, ioff, foff, espeed int64, repeated bool
 	// divide the pending bytes of current slow
 	// part among the current part and a newly
 	// spawned part.
+	d.segMu.Lock()
+	poff, foff = part.position(), part.getFoff()
+	if foff-poff <= 2*MIN_PART_SIZE {
+		d.segMu.Unlock()
+		// tail of the part was stolen in the meantime.
+		return d.runPart(part, poff, foff, espeed, true, body)
+	}
 	div := (foff - poff) / 2
+	// current part will download the first half
+	// of pending bytes.
+	part.setFoff(poff + div - 1)
+	d.segMu.Unlock()
 
 	// spawn a new part and add its goroutine to
 	// waitgroup, new part will download the last
@@ -573,8 +864,6 @@ func (d *Downloader) runPart(part *Part, ioff, foff, espeed int64, repeated bool
 	d.wg.Add(1)
 	go d.newPartDownload(poff+div, foff, espeed/2)
 
-	// current part will download the first half
-	// of pending bytes.
 	foff = poff + div - 1
 
 	d.Log("%s: part respawned", hash)
@@ -583,15 +872,90 @@ func (d *Downloader) runPart(part *Part, ioff, foff, espeed int64, repeated bool
 	return d.runPart(part, poff, foff, espeed/2, false, body)
 }
 
+// partError reports the error of the part of hash unless
+// the download was stopped, which interrupts its parts.
+func (d *Downloader) partError(hash string, err error) {
+	if d.stopped.Load() {
+		return
+	}
+	d.handlers.ErrorHandler(hash, err)
+}
+
 func (d *Downloader) Stop() {
-	d.stopped = true
+	d.stopped.Store(true)
 	d.cancel()
 }
 
+// IsRunning reports whether the download is in progress.
+func (d *Downloader) IsRunning() bool {
+	return atomic.LoadInt32(&d.running) == 1
+}
+
+// IsStopped reports whether the download was stopped
+// before it could complete.
+func (d *Downloader) IsStopped() bool {
+	return d.stopped.Load()
+}
+
+// progressHandler records the bytes received by parts before
+// passing them to the download progress handler.
+func (d *Downloader) progressHandler(hash string, nread int) {
+	atomic.AddInt64(&d.dread, int64(nread))
+	d.meter.add(nread)
+	d.handlers.DownloadProgressHandler(hash, nread)
+}
+
+// SetSpeedLimit limits the total download speed to limit bytes
+// per second, 0 removes the limit. It can be called while the
+// file is being downloaded.
+func (d *Downloader) SetSpeedLimit(limit int64) {
+	d.limiter.setRate(limit)
+}
+
+// GetSpeed returns the current speed of the
+// download in bytes per second.
+func (d *Downloader) GetSpeed() int64 {
+	return d.meter.get()
+}
+
+// GetSpeedLimit returns the speed limit of the download in
+// bytes per second, 0 if unlimited.
+func (d *Downloader) GetSpeedLimit() int64 {
+	return d.limiter.getRate()
+}
+
+// GetConnectionLimit returns the number of connections
+// currently allowed by the connection tuner.
+func (d *Downloader) GetConnectionLimit() int32 {
+	return d.getConnLimit()
+}
+
 func (d *Downloader) GetMaxConnections() int32 {
 	return d.maxConn
 }
 
+// SetMaxConnections caps the connections of the download to n,
+// which is clamped between 1 and the max connections. It can be
+// called while the file is being downloaded, lowering the cap
+// retires connections once their parts finish.
+func (d *Downloader) SetMaxConnections(n int32) int32 {
+	n = min(max(n, 1), max(d.maxConn, 1))
+	atomic.StoreInt32(&d.connCap, n)
+	prev := atomic.SwapInt32(&d.connLimit, n)
+	if !d.IsRunning() || prev == n {
+		return n
+	}
+	if n > prev {
+		atomic.AddInt32(&d.splitReq, n-prev)
+		d.Log("Connections capped by user (%d => %d)", prev, n)
+	} else {
+		atomic.StoreInt32(&d.splitReq, 0)
+		d.Log("Connections capped by user, retiring connections (%d => %d)", prev, n)
+	}
+	d.handlers.ConnectionsTunedHandler(prev, n, d.GetSpeed())
+	return n
+}
+
 func (d *Downloader) GetMaxParts() int32 {
 	return d.maxParts
 }
@@ -609,6 +973,17 @@ func (d *Downloader) GetSavePath() (svPath string) {
 	return
 }
 
+// GetTempPath returns the path of the temporary file which
+// is written to while the download is in progress.
+func (d *Downloader) GetTempPath() string {
+	// downloads added before temporary files were introduced
+	// are written directly to their save path.
+	if d.tmpPath == "" {
+		return d.GetSavePath()
+	}
+	return d.tmpPath
+}
+
 func (d *Downloader) GetContentLength() ContentLength {
 	return d.contentLength
 }
@@ -621,6 +996,62 @@ func (d *Downloader) GetContentLengthAsString() string {
 	return d.contentLength.String()
 }
 
+// GetUrl returns the original url of the download.
+func (d *Downloader) GetUrl() string {
+	return d.url
+}
+
+// GetEffectiveUrl returns the url the original url resolved
+// to after following redirects.
+func (d *Downloader) GetEffectiveUrl() string {
+	return d.effectiveUrl
+}
+
+// GetRedirectChain returns the urls visited while resolving
+// the original url, excluding the effective url.
+func (d *Downloader) GetRedirectChain() []string {
+	return d.redirects
+}
+
+// contentEncodingOnDisk returns the encoding of the content
+// stored on disk, which is empty for decoded content.
+func (d *Downloader) contentEncodingOnDisk() string {
+	if d.decode {
+		return ""
+	}
+	return d.contentEncoding
+}
+
+func (d *Downloader) getPartUrl() string {
+	if d.effectiveUrl != "" {
+		return d.effectiveUrl
+	}
+	return d.url
+}
+
+// GetProbeResult returns the result of probing the remote
+// file, nil for resumed downloads.
+func (d *Downloader) GetProbeResult() *ProbeResult {
+	return d.probe
+}
+
+// GetContentEncoding returns the content encoding of the
+// remote file, empty if it isn't encoded.
+func (d *Downloader) GetContentEncoding() string {
+	return d.contentEncoding
+}
+
+// GetETag returns the ETag of the remote file.
+func (d *Downloader) GetETag() string {
+	return d.etag
+}
+
+// GetCategory returns the category which picked the
+// download directory, nil if none did.
+func (d *Downloader) GetCategory() *Category {
+	return d.category
+}
+
 func (d *Downloader) GetHash() string {
 	return d.hash
 }
@@ -649,30 +1080,39 @@ func (d *Downloader) getPartSize() (partSize, rpartSize int64) {
 }
 
 func (d *Downloader) setContentLength(cl int64) error {
-	switch cl {
-	case 0:
+	if cl == 0 {
 		return ErrContentLengthInvalid
-	case -1:
-		d.resumable = false
-		d.numBaseParts = 1
-		d.maxConn = 1
-		d.maxParts = 1
-		// 	return ErrContentLengthNotImplemented
 	}
+	// unknown content length (-1) is handled by prepareDownloader
+	// as the size might still be retrieved from a ranged request.
 	d.contentLength = ContentLength(cl)
 	return nil
 }
 
+// setUnknownSize prepares the downloader for a file of unknown
+// size which has to be downloaded sequentially in a single
+// connection. It can only be resumed if server supports ranges.
+func (d *Downloader) setUnknownSize(rangeable bool) {
+	d.resumable = rangeable
+	d.numBaseParts = 1
+	d.maxConn = 1
+	d.maxParts = 1
+}
+
 func (d *Downloader) setFileName(r *http.Request, h *http.Header) error {
 	if d.fileName != "" {
 		return nil
 	}
 	cd := h.Get("Content-Disposition")
-	d.fileName = parseFileName(r, cd)
-	if d.fileName != "" {
-		return nil
+	fn := sanitizeFileName(parseFileName(r, cd))
+	if fn == "" {
+		fn = DEF_FILE_NAME
 	}
-	return ErrFileNameNotFound
+	if !strings.Contains(fn, ".") {
+		fn += extensionByType(h.Get("Content-Type"))
+	}
+	d.fileName = truncateFileName(fn, maxFileNameLength)
+	return nil
 }
 
 func (d *Downloader) setHash() {
@@ -714,127 +1154,181 @@ func (d *Downloader) checkContentType(h *http.Header) (err error) {
 	return
 }
 
-func (d *Downloader) fetchInfo() (err error) {
-	resp, er := d.makeRequest(http.MethodGet)
-	if er != nil {
-		err = er
+func (d *Downloader) fetchInfo(rangeStart, rangeEnd int64) (err error) {
+	p, err := probe(d.client, d.url, d.headers, int64(d.chunk))
+	if err != nil {
 		return
 	}
-	defer resp.Body.Close()
-	h := resp.Header
+	if p.StatusCode >= 400 {
+		return fmt.Errorf("%w: %s", ErrUnexpectedStatus, http.StatusText(p.StatusCode))
+	}
+	d.probe = p
+	d.redirects = p.Redirects
+	if len(p.Redirects) != 0 {
+		d.effectiveUrl = p.EffectiveUrl
+	}
+	h := p.Header
+	d.etag = h.Get("ETag")
+	d.contentEncoding = p.ContentEncoding
 	err = d.checkContentType(&h)
 	if err != nil {
 		return
 	}
-	err = d.setContentLength(resp.ContentLength)
+	err = d.setContentLength(p.ContentLength.v())
 	if err != nil {
 		return
 	}
-	err = d.setFileName(resp.Request, &h)
+	err = d.setFileName(p.request, &h)
 	if err != nil {
 		return
 	}
-	return d.prepareDownloader()
-}
-
-func (d *Downloader) makeRequest(method string, hdrs ...Header) (*http.Response, error) {
-	req, err := http.NewRequest(method, d.url, nil)
-	if err != nil {
-		return nil, err
+	if d.decode && d.contentEncoding != "" {
+		// size of the decoded content is unknown and offsets
+		// of the decoded stream can't be requested.
+		if rangeStart != 0 || rangeEnd != 0 {
+			return fmt.Errorf("%w: decoded content can't be ranged", ErrRangeNotSupported)
+		}
+		d.contentLength = -1
+		d.setUnknownSize(false)
+		return
 	}
-	header := req.Header
-	for _, hdr := range hdrs {
-		hdr.Set(header)
+	err = d.applyRange(rangeStart, rangeEnd)
+	if err != nil {
+		return
 	}
-	d.headers.Set(header)
-	return d.client.Do(req)
+	d.prepareDownloader()
+	return
 }
 
-func (d *Downloader) prepareDownloader() (err error) {
-	resp, er := d.makeRequest(
-		http.MethodGet,
-		Header{
-			"Range", strings.Join(
-				[]string{"bytes=1", strconv.Itoa(d.chunk)},
-				"-",
-			),
-		},
-	)
-	if er != nil {
-		err = er
+// prepareDownloader decides the segmentation of download
+// using the probe results.
+func (d *Downloader) prepareDownloader() {
+	p := d.probe
+	if d.contentLength.IsUnknown() {
+		d.setUnknownSize(p.RangesSupported)
 		return
 	}
-	if !d.force && resp.Header.Get("Accept-Ranges") == "" {
+	if !d.force && !p.RangesSupported {
 		d.numBaseParts = 1
 		d.resumable = false
 		return
 	}
-	size := d.chunk
-	if d.contentLength.v() < int64(size) {
+	if d.contentLength.v() < int64(d.chunk) {
 		d.numBaseParts = 1
 		return
 	}
 	if d.numBaseParts != 0 {
 		return
 	}
-	te, es := getSpeed(func() (err error) {
-		buf := make([]byte, size)
-		r, er := resp.Body.Read(buf)
-		if er != nil {
-			err = er
-			return
-		}
-		if r < size {
-			size = r
-			return
-		}
+	// connection tuner adjusts the number of connections
+	// based on live throughput once download starts.
+	d.numBaseParts = DEF_BASE_PARTS
+}
+
+// reuseProbeHead writes the content received from offset 0 while
+// probing into part, so that it doesn't have to be requested again.
+// It returns the number of bytes written.
+func (d *Downloader) reuseProbeHead(part *Part, foff int64) int64 {
+	if d.probe == nil {
+		return 0
+	}
+	head := d.probe.head
+	d.probe.head = nil
+	// don't reuse head if it covers the whole part as
+	// part expects to request at least a single byte.
+	if len(head) == 0 || int64(len(head)) > foff {
+		return 0
+	}
+	n, err := part.pf.Write(head)
+	if err != nil {
+		d.Log("%s: failed to reuse probe content: %s", part.hash, err.Error())
+		_ = part.pf.Truncate(0)
+		_, _ = part.pf.Seek(0, io.SeekStart)
+		return 0
+	}
+	atomic.AddInt64(&part.read, int64(n))
+	part.pfunc(part.hash, n)
+	d.Log("%s: reused %d bytes received while probing", part.hash, n)
+	return int64(n)
+}
+
+// downloadUnknownSizeFile downloads the file of unknown size
+// sequentially, starting from offset off.
+func (d *Downloader) downloadUnknownSizeFile(off int64) {
+	defer d.wg.Done()
+	err := d.copyUnknownSizeFile(off)
+	if err == nil || d.stopped.Load() {
 		return
-	})
-	if es != nil {
-		err = es
-		return
-	}
-	switch {
-	case te > getDownloadTime(100*KB, int64(size)):
-		// chunk is downloaded at a speed less than 100KB/s
-		// very slow download
-		d.numBaseParts = 14
-	case te > getDownloadTime(MB, int64(size)):
-		// chunk is downloaded at a speed less than 1MB/s
-		// slow download
-		d.numBaseParts = 12
-	case te < getDownloadTime(10*MB, int64(size)):
-		// chunk is downloaded at a speed more than 10MB/s
-		// super fast download
-		d.numBaseParts = 8
-	case te < getDownloadTime(5*MB, int64(size)):
-		// chunk is downloaded at a speed more than 5MB/s
-		// fast download
-		d.numBaseParts = 10
 	}
-	return
+	d.handlers.ErrorHandler(MAIN_HASH, err)
+	// there isn't any other connection to continue
+	// downloading, hence stop the download.
+	d.Stop()
 }
 
-func (d *Downloader) downloadUnknownSizeFile() error {
-	defer d.wg.Done()
-	req, err := http.NewRequestWithContext(d.ctx, http.MethodGet, d.url, nil)
+func (d *Downloader) copyUnknownSizeFile(off int64) error {
+	return d.copyContent(off, io.NewOffsetWriter(d.f, off))
+}
+
+// copyContent copies the content starting at offset off to w
+// in a single connection, decoding it if requested.
+func (d *Downloader) copyContent(off int64, w io.Writer) error {
+	req, err := http.NewRequestWithContext(d.ctx, http.MethodGet, d.getPartUrl(), nil)
 	if err != nil {
 		return err
 	}
 	header := req.Header
 	d.headers.Set(header)
+	ranged := off > 0 || d.ranged
+	if d.ranged {
+		setRange(header, d.rangeStart+off, d.rangeStart+d.contentLength.v()-1)
+	} else if off > 0 {
+		setRange(header, off, 0)
+	}
 	resp, err := d.client.Do(req)
 	if err != nil {
 		return err
 	}
 	defer resp.Body.Close()
-	proxiedBody := NewCallbackProxyReader(resp.Body, func(n int) {
+	if ranged && resp.StatusCode != http.StatusPartialContent {
+		// appending a whole-file response would corrupt the file.
+		return fmt.Errorf("%w: server ignored range request (%s)", ErrDownloadNotResumable, resp.Status)
+	}
+	if resp.StatusCode >= 400 {
+		return fmt.Errorf("%w: %s", ErrUnexpectedStatus, resp.Status)
+	}
+	var body io.ReadCloser = resp.Body
+	if enc := resp.Header.Get(CONTENT_ENCODING_KEY); d.decode && isEncoded(enc) {
+		body, err = newDecoder(enc, resp.Body)
+		if err != nil {
+			return err
+		}
+		defer body.Close()
+	}
+	proxiedBody := NewCallbackProxyReader(&limitedReader{d.ctx, body, d.limiter}, func(n int) {
 		atomic.AddInt64(&d.nread, int64(n))
 		d.handlers.DownloadProgressHandler(MAIN_HASH, n)
 	})
-	_, err = io.Copy(d.f, proxiedBody)
+	_, err = io.Copy(w, proxiedBody)
+	return err
+}
+
+// contentRangeTotal returns the complete length of the file
+// present in Content-Range header of a partial response, or -1
+// if it is unknown.
+func contentRangeTotal(resp *http.Response) int64 {
+	if resp.StatusCode != http.StatusPartialContent {
+		return -1
+	}
+	cr := resp.Header.Get("Content-Range")
+	i := strings.LastIndexByte(cr, '/')
+	if i == -1 {
+		return -1
+	}
+	total, err := strconv.ParseInt(cr[i+1:], 10, 64)
 	if err != nil {
-		return err
+		// size is "*" if it's unknown to the server
+		return -1
 	}
-	return nil
+	return total
 }
@@ -0,0 +1,259 @@
+package warplib
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func newTestServer(t *testing.T, content []byte) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.ServeContent(w, r, "file.bin", time.Time{}, bytes.NewReader(content))
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestDownloader_StartRenamesTempFile(t *testing.T) {
+	content := bytes.Repeat([]byte("warp"), 64*1024)
+	srv := newTestServer(t, content)
+	dir := t.TempDir()
+	var verified string
+	d, err := NewDownloader(srv.Client(), srv.URL+"/file.bin", &DownloaderOpts{
+		DownloadDirectory: dir,
+		MaxConnections:    4,
+		Verify: func(path string) error {
+			verified = path
+			return nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("NewDownloader() error = %v", err)
+	}
+	defer os.RemoveAll(d.dlPath)
+	if got, want := d.GetTempPath(), d.GetSavePath()+DEF_TEMP_SUFFIX; got != want {
+		t.Errorf("GetTempPath() = %v, want %v", got, want)
+	}
+	if err = d.Start(); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	if verified != d.GetTempPath() {
+		t.Errorf("Verify called with %q, want %q", verified, d.GetTempPath())
+	}
+	if _, err = os.Stat(d.GetTempPath()); !os.IsNotExist(err) {
+		t.Errorf("temporary file still exists: %v", err)
+	}
+	b, err := os.ReadFile(d.GetSavePath())
+	if err != nil {
+		t.Fatalf("ReadFile() error = %v", err)
+	}
+	if !bytes.Equal(b, content) {
+		t.Errorf("downloaded content mismatch: got %d bytes, want %d", len(b), len(content))
+	}
+}
+
+func TestDownloader_RedirectChain(t *testing.T) {
+	content := []byte("redirected content")
+	var mux http.ServeMux
+	mux.HandleFunc("/a", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/b", http.StatusFound)
+	})
+	mux.HandleFunc("/b", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/file.bin", http.StatusMovedPermanently)
+	})
+	mux.HandleFunc("/file.bin", func(w http.ResponseWriter, r *http.Request) {
+		http.ServeContent(w, r, "file.bin", time.Time{}, bytes.NewReader(content))
+	})
+	srv := httptest.NewServer(&mux)
+	defer srv.Close()
+	d, err := NewDownloader(srv.Client(), srv.URL+"/a", &DownloaderOpts{SkipSetup: true})
+	if err != nil {
+		t.Fatalf("NewDownloader() error = %v", err)
+	}
+	if got, want := d.GetEffectiveUrl(), srv.URL+"/file.bin"; got != want {
+		t.Errorf("GetEffectiveUrl() = %v, want %v", got, want)
+	}
+	chain := d.GetRedirectChain()
+	if len(chain) != 2 || chain[0] != srv.URL+"/a" || chain[1] != srv.URL+"/b" {
+		t.Errorf("GetRedirectChain() = %v", chain)
+	}
+	if got := d.GetFileName(); got != "file.bin" {
+		t.Errorf("GetFileName() = %v, want file.bin", got)
+	}
+}
+
+// newChunkedTestServer serves content without Content-Length and
+// responds to ranged requests without revealing the total size.
+func newChunkedTestServer(t *testing.T, content []byte) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body := content
+		if rng := r.Header.Get("Range"); rng != "" {
+			var start, end int64 = 0, int64(len(content)) - 1
+			if _, err := fmt.Sscanf(rng, "bytes=%d-%d", &start, &end); err != nil {
+				fmt.Sscanf(rng, "bytes=%d-", &start)
+			}
+			end = min(end, int64(len(content))-1)
+			body = content[start : end+1]
+			w.Header().Set("Content-Range", fmt.Sprintf("bytes %d-%d/*", start, end))
+			w.WriteHeader(http.StatusPartialContent)
+		}
+		// flushing before writing the body forces chunked encoding
+		w.(http.Flusher).Flush()
+		w.Write(body)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestDownloader_ResumeUnknownSize(t *testing.T) {
+	content := bytes.Repeat([]byte("chunked"), 32*1024)
+	srv := newChunkedTestServer(t, content)
+	d, err := NewDownloader(srv.Client(), srv.URL+"/file.bin", &DownloaderOpts{
+		DownloadDirectory: t.TempDir(),
+		MaxConnections:    4,
+	})
+	if err != nil {
+		t.Fatalf("NewDownloader() error = %v", err)
+	}
+	defer os.RemoveAll(d.dlPath)
+	if !d.GetContentLength().IsUnknown() {
+		t.Fatalf("GetContentLength() = %d, want unknown", d.GetContentLengthAsInt())
+	}
+	if !d.resumable {
+		t.Fatal("download of unknown size should be resumable if server supports ranges")
+	}
+	// simulate an interrupted download
+	if err = os.WriteFile(d.GetTempPath(), content[:len(content)/3], 0666); err != nil {
+		t.Fatal(err)
+	}
+	if err = d.Resume(nil); err != nil {
+		t.Fatalf("Resume() error = %v", err)
+	}
+	b, err := os.ReadFile(d.GetSavePath())
+	if err != nil {
+		t.Fatalf("ReadFile() error = %v", err)
+	}
+	if !bytes.Equal(b, content) {
+		t.Errorf("downloaded content mismatch: got %d bytes, want %d", len(b), len(content))
+	}
+	if got := d.GetContentLengthAsInt(); got != int64(len(content)) {
+		t.Errorf("GetContentLength() = %d, want %d", got, len(content))
+	}
+}
+
+// slowWriter delays every write to throttle a response.
+type slowWriter struct {
+	http.ResponseWriter
+	delay time.Duration
+}
+
+func (w slowWriter) Write(b []byte) (int, error) {
+	time.Sleep(w.delay)
+	return w.ResponseWriter.Write(b)
+}
+
+func TestDownloader_StealsWorkFromSlowPart(t *testing.T) {
+	content := bytes.Repeat([]byte("steal"), 800*1024)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.Header.Get("Range"), "bytes=0-") {
+			// only the first part runs slow
+			w = slowWriter{w, 20 * time.Millisecond}
+		}
+		http.ServeContent(w, r, "file.bin", time.Time{}, bytes.NewReader(content))
+	}))
+	defer srv.Close()
+	for _, raceTail := range []bool{false, true} {
+		t.Run(fmt.Sprintf("race tail %v", raceTail), func(t *testing.T) {
+			var spawned int32
+			d, err := NewDownloader(srv.Client(), srv.URL+"/file.bin", &DownloaderOpts{
+				DownloadDirectory: t.TempDir(),
+				MaxConnections:    2,
+				DisableTuning:     true,
+				RaceTail:          raceTail,
+				Handlers: &Handlers{
+					SpawnPartHandler: func(hash string, ioff, foff int64) {
+						atomic.AddInt32(&spawned, 1)
+					},
+				},
+			})
+			if err != nil {
+				t.Fatalf("NewDownloader() error = %v", err)
+			}
+			defer os.RemoveAll(d.dlPath)
+			if err = d.Start(); err != nil {
+				t.Fatalf("Start() error = %v", err)
+			}
+			b, err := os.ReadFile(d.GetSavePath())
+			if err != nil {
+				t.Fatalf("ReadFile() error = %v", err)
+			}
+			if !bytes.Equal(b, content) {
+				t.Errorf("downloaded content mismatch: got %d bytes, want %d", len(b), len(content))
+			}
+			if n := atomic.LoadInt32(&spawned); n <= 2 {
+				t.Errorf("spawned %d parts, want work to be stolen from the slow part", n)
+			}
+			if d.nread != int64(len(content)) {
+				t.Errorf("compiled %d bytes, want %d", d.nread, len(content))
+			}
+		})
+	}
+}
+
+func TestDownloader_StartFailsIncompleteDownload(t *testing.T) {
+	content := bytes.Repeat([]byte("warp"), 64*1024)
+	failed := fmt.Sprintf("bytes=%d-", len(content)/2)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.Header.Get("Range"), failed) {
+			// the second part fails
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		http.ServeContent(w, r, "file.bin", time.Time{}, bytes.NewReader(content))
+	}))
+	defer srv.Close()
+	var completed, errored int32
+	d, err := NewDownloader(srv.Client(), srv.URL+"/file.bin", &DownloaderOpts{
+		DownloadDirectory: t.TempDir(),
+		MaxConnections:    2,
+		DisableTuning:     true,
+		Handlers: &Handlers{
+			ErrorHandler: func(hash string, err error) {
+				atomic.AddInt32(&errored, 1)
+			},
+			DownloadCompleteHandler: func(hash string, tread int64) {
+				if hash == MAIN_HASH {
+					atomic.AddInt32(&completed, 1)
+				}
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("NewDownloader() error = %v", err)
+	}
+	defer os.RemoveAll(d.dlPath)
+	if err = d.Start(); !errors.Is(err, ErrDownloadIncomplete) {
+		t.Fatalf("Start() error = %v, want %v", err, ErrDownloadIncomplete)
+	}
+	if atomic.LoadInt32(&completed) != 0 {
+		t.Error("DownloadCompleteHandler called for an incomplete download")
+	}
+	if atomic.LoadInt32(&errored) == 0 {
+		t.Error("ErrorHandler not called for an incomplete download")
+	}
+	if _, err = os.Stat(d.GetSavePath()); !os.IsNotExist(err) {
+		t.Errorf("incomplete download was moved to the save path: %v", err)
+	}
+	if _, err = os.Stat(d.GetTempPath()); err != nil {
+		t.Errorf("temporary file should be kept for resume: %v", err)
+	}
+}
@@ -0,0 +1,87 @@
+package warplib
+
+import (
+	"compress/gzip"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/zstd"
+)
+
+const (
+	ACCEPT_ENCODING_KEY  = "Accept-Encoding"
+	CONTENT_ENCODING_KEY = "Content-Encoding"
+
+	// ENCODING_IDENTITY asks server to send the content
+	// as is, which is required for range arithmetic.
+	ENCODING_IDENTITY = "identity"
+	// DEF_ACCEPT_ENCODING is requested while decoding
+	// content on the fly.
+	DEF_ACCEPT_ENCODING = "br, zstd, gzip, deflate"
+)
+
+// isEncoded reports whether the content encoding header
+// value refers to an encoded response.
+func isEncoded(encoding string) bool {
+	encoding = strings.TrimSpace(encoding)
+	return encoding != "" && !strings.EqualFold(encoding, ENCODING_IDENTITY)
+}
+
+// newDecoder wraps r with readers decoding the content
+// encodings present in the Content-Encoding header value.
+// Multiple encodings are decoded in the reverse order of
+// their application.
+func newDecoder(encoding string, r io.Reader) (io.ReadCloser, error) {
+	var (
+		encs    = strings.Split(encoding, ",")
+		closers []io.Closer
+	)
+	for i := len(encs) - 1; i >= 0; i-- {
+		var (
+			rc  io.ReadCloser
+			err error
+		)
+		switch enc := strings.ToLower(strings.TrimSpace(encs[i])); enc {
+		case "", ENCODING_IDENTITY:
+			continue
+		case "gzip", "x-gzip":
+			rc, err = gzip.NewReader(r)
+		case "deflate":
+			// http's deflate is zlib wrapped deflate.
+			rc, err = zlib.NewReader(r)
+		case "br":
+			rc = io.NopCloser(brotli.NewReader(r))
+		case "zstd":
+			var zr *zstd.Decoder
+			zr, err = zstd.NewReader(r)
+			if err == nil {
+				rc = zr.IOReadCloser()
+			}
+		default:
+			err = fmt.Errorf("%w: %s", ErrEncodingNotSupported, enc)
+		}
+		if err != nil {
+			return nil, err
+		}
+		closers = append(closers, rc)
+		r = rc
+	}
+	return &decoder{r, closers}, nil
+}
+
+type decoder struct {
+	io.Reader
+	closers []io.Closer
+}
+
+func (d *decoder) Close() (err error) {
+	for i := len(d.closers) - 1; i >= 0; i-- {
+		if er := d.closers[i].Close(); er != nil {
+			err = er
+		}
+	}
+	return
+}
@@ -0,0 +1,152 @@
+package warplib
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/zstd"
+)
+
+func Test_newDecoder(t *testing.T) {
+	content := bytes.Repeat([]byte("encoded content "), 1024)
+	encode := func(enc string) []byte {
+		var buf bytes.Buffer
+		var w io.WriteCloser
+		switch enc {
+		case "gzip":
+			w = gzip.NewWriter(&buf)
+		case "br":
+			w = brotli.NewWriter(&buf)
+		case "zstd":
+			w, _ = zstd.NewWriter(&buf)
+		}
+		w.Write(content)
+		w.Close()
+		return buf.Bytes()
+	}
+	for _, enc := range []string{"gzip", "br", "zstd"} {
+		t.Run(enc, func(t *testing.T) {
+			r, err := newDecoder(enc, bytes.NewReader(encode(enc)))
+			if err != nil {
+				t.Fatalf("newDecoder() error = %v", err)
+			}
+			defer r.Close()
+			b, err := io.ReadAll(r)
+			if err != nil {
+				t.Fatalf("ReadAll() error = %v", err)
+			}
+			if !bytes.Equal(b, content) {
+				t.Errorf("decoded content mismatch")
+			}
+		})
+	}
+	if _, err := newDecoder("compress", nil); err == nil {
+		t.Errorf("newDecoder() expected error for unsupported encoding")
+	}
+}
+
+func TestDownloader_DecodeContent(t *testing.T) {
+	content := bytes.Repeat([]byte("gzipped content "), 4096)
+	var gz bytes.Buffer
+	w := gzip.NewWriter(&gz)
+	w.Write(content)
+	w.Close()
+	var gotAE []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotAE = append(gotAE, r.Header.Get(ACCEPT_ENCODING_KEY))
+		// server encodes the response irrespective of the
+		// requested encoding.
+		w.Header().Set(CONTENT_ENCODING_KEY, "gzip")
+		w.Header().Set("Content-Type", "text/plain")
+		w.Write(gz.Bytes())
+	}))
+	defer srv.Close()
+
+	tests := []struct {
+		name   string
+		decode bool
+		want   []byte
+		wantAE string
+	}{
+		{"raw", false, gz.Bytes(), ENCODING_IDENTITY},
+		{"decode", true, content, DEF_ACCEPT_ENCODING},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotAE = nil
+			d, err := NewDownloader(srv.Client(), srv.URL+"/file.txt", &DownloaderOpts{
+				DownloadDirectory: t.TempDir(),
+				DecodeContent:     tt.decode,
+			})
+			if err != nil {
+				t.Fatalf("NewDownloader() error = %v", err)
+			}
+			defer os.RemoveAll(d.dlPath)
+			if d.GetContentEncoding() != "gzip" {
+				t.Errorf("GetContentEncoding() = %q, want gzip", d.GetContentEncoding())
+			}
+			if err = d.Start(); err != nil {
+				t.Fatalf("Start() error = %v", err)
+			}
+			b, err := os.ReadFile(d.GetSavePath())
+			if err != nil {
+				t.Fatalf("ReadFile() error = %v", err)
+			}
+			if !bytes.Equal(b, tt.want) {
+				t.Errorf("downloaded content mismatch: got %d bytes, want %d", len(b), len(tt.want))
+			}
+			for _, ae := range gotAE {
+				if ae != tt.wantAE {
+					t.Errorf("Accept-Encoding = %q, want %q", ae, tt.wantAE)
+				}
+			}
+		})
+	}
+}
+
+func TestManager_ResumeDecodedDownload(t *testing.T) {
+	content := bytes.Repeat([]byte("scheduled content "), 4096)
+	var gz bytes.Buffer
+	w := gzip.NewWriter(&gz)
+	w.Write(content)
+	w.Close()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set(CONTENT_ENCODING_KEY, "gzip")
+		w.Write(gz.Bytes())
+	}))
+	defer srv.Close()
+	d, err := NewDownloader(srv.Client(), srv.URL+"/file.txt", &DownloaderOpts{
+		DownloadDirectory: t.TempDir(),
+		DecodeContent:     true,
+	})
+	if err != nil {
+		t.Fatalf("NewDownloader() error = %v", err)
+	}
+	defer os.RemoveAll(d.dlPath)
+	m := newTestManager(t)
+	if err = m.AddDownload(d, nil); err != nil {
+		t.Fatalf("AddDownload() error = %v", err)
+	}
+	// the download is restored before it has started,
+	// as a scheduled one is.
+	item, err := m.ResumeDownload(srv.Client(), d.GetHash(), nil)
+	if err != nil {
+		t.Fatalf("ResumeDownload() error = %v", err)
+	}
+	if err = item.Resume(); err != nil {
+		t.Fatalf("Resume() error = %v", err)
+	}
+	b, err := os.ReadFile(item.GetSavePath())
+	if err != nil {
+		t.Fatalf("ReadFile() error = %v", err)
+	}
+	if !bytes.Equal(b, content) {
+		t.Errorf("downloaded content mismatch: got %d bytes, want %d decoded bytes", len(b), len(content))
+	}
+}
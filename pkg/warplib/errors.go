@@ -7,12 +7,32 @@ var (
 	ErrContentLengthInvalid        = errors.New("content length is invalid")
 	ErrContentLengthNotImplemented = errors.New("unknown size downloads not implemented yet")
 	ErrNotSupported                = errors.New("file you're trying to download is not supported yet")
+	ErrEncodingNotSupported        = errors.New("content encoding is not supported")
+	ErrUnexpectedStatus            = errors.New("unexpected response status")
+	ErrFileExists                  = errors.New("file already exists")
+	ErrDownloadSkipped             = errors.New("download skipped as an identical file already exists")
+	ErrInvalidRange                = errors.New("invalid byte range")
+	ErrRangeNotSupported           = errors.New("byte range can't be downloaded")
+	ErrInvalidUrlPattern           = errors.New("invalid url pattern")
+	ErrInvalidSchedule             = errors.New("invalid schedule")
+	ErrInvalidSize                 = errors.New("invalid size")
+	ErrArchiveNotSupported         = errors.New("archive format is not supported")
+	ErrUnsafeArchivePath           = errors.New("archive entry points outside of the extraction directory")
+	ErrInvalidCategory             = errors.New("invalid category")
+	ErrInvalidItemState            = errors.New("invalid download state")
+	ErrDownloadIncomplete          = errors.New("download is incomplete")
 
 	ErrItemDownloaderNotFound = errors.New("item downloader not found")
 
 	ErrDownloadNotFound     = errors.New("Item you are trying to download is not found")
 	ErrDownloadNotResumable = errors.New("Item you are trying to download is not resumable")
 
+	ErrRefreshItemDownloading = errors.New("Item you are trying to refresh is currently downloading")
+	ErrRefreshSizeMismatch    = errors.New("size of file at the new url doesn't match")
+	ErrRefreshETagMismatch    = errors.New("etag of file at the new url doesn't match")
+
+	ErrScheduleNotResumable = errors.New("time windows need a download which can be resumed")
+
 	ErrFlushHashNotFound    = errors.New("Item you are trying to flush is not found")
 	ErrFlushItemDownloading = errors.New("Item you are trying to flush is currently downloading")
 )
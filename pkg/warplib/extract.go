@@ -0,0 +1,352 @@
+package warplib
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/bzip2"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/ulikunitz/xz"
+)
+
+// archiveExts are the extensions of the supported archive formats,
+// longer extensions come first as they end with shorter ones.
+var archiveExts = []string{
+	".tar.gz", ".tar.zst", ".tar.xz", ".tar.bz2",
+	".tgz", ".tzst", ".txz", ".tbz2", ".tar", ".zip",
+}
+
+// ExtractOpts sets up the extraction of a download once it completes.
+type ExtractOpts struct {
+	// Directory is where the archive is extracted, paths relative
+	// to the download directory are accepted. A directory named
+	// after the archive is used next to it if empty.
+	Directory string `json:"directory,omitempty"`
+	// DeleteArchive deletes the archive once it is extracted.
+	DeleteArchive bool `json:"delete_archive,omitempty"`
+}
+
+// archiveExt returns the archive extension of name,
+// an empty string if it isn't a supported archive.
+func archiveExt(name string) string {
+	name = strings.ToLower(name)
+	for _, ext := range archiveExts {
+		if strings.HasSuffix(name, ext) {
+			return ext
+		}
+	}
+	return ""
+}
+
+// IsArchive reports whether name is the name of a supported
+// archive: zip or tar, optionally compressed with gzip, zstd,
+// xz or bzip2.
+func IsArchive(name string) bool {
+	return archiveExt(name) != ""
+}
+
+// DefaultExtractDir returns the directory an archive is extracted
+// to by default, its path without the archive extension.
+func DefaultExtractDir(path string) string {
+	dir := path[:len(path)-len(archiveExt(path))]
+	if dir == path {
+		dir += ".extracted"
+	}
+	return dir
+}
+
+// ExtractArchive extracts the archive at path into dir, creating it
+// if needed, and returns the number of extracted files. progress is
+// called with the number of bytes of the archive read, if not nil.
+// Entries which would be written outside of dir, directly or through
+// links, fail the extraction with ErrUnsafeArchivePath.
+func ExtractArchive(path, dir string, progress func(nread int)) (files int, err error) {
+	ext := archiveExt(path)
+	if ext == "" {
+		return 0, fmt.Errorf("%w: %s", ErrArchiveNotSupported, filepath.Base(path))
+	}
+	dir, err = filepath.Abs(dir)
+	if err != nil {
+		return
+	}
+	err = os.MkdirAll(dir, os.ModePerm)
+	if err != nil {
+		return
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+	fi, err := f.Stat()
+	if err != nil {
+		return
+	}
+	var nread int64
+	report := func(n int) {
+		nread += int64(n)
+		if progress != nil {
+			progress(n)
+		}
+	}
+	defer func() {
+		// trailers of archives, such as the central directory of
+		// zip files, aren't read but are part of the progress.
+		if err == nil && nread < fi.Size() {
+			report(int(fi.Size() - nread))
+		}
+	}()
+	if ext == ".zip" {
+		return extractZip(f, fi.Size(), dir, report)
+	}
+	var r io.Reader = &progressReader{r: f, progress: report}
+	switch ext {
+	case ".tar.gz", ".tgz":
+		var gr *gzip.Reader
+		gr, err = gzip.NewReader(r)
+		if err != nil {
+			return
+		}
+		defer gr.Close()
+		r = gr
+	case ".tar.zst", ".tzst":
+		var zr *zstd.Decoder
+		zr, err = zstd.NewReader(r)
+		if err != nil {
+			return
+		}
+		defer zr.Close()
+		r = zr
+	case ".tar.xz", ".txz":
+		r, err = xz.NewReader(r)
+		if err != nil {
+			return
+		}
+	case ".tar.bz2", ".tbz2":
+		r = bzip2.NewReader(r)
+	}
+	return extractTar(tar.NewReader(r), dir)
+}
+
+func extractTar(tr *tar.Reader, dir string) (files int, err error) {
+	for {
+		var hdr *tar.Header
+		hdr, err = tr.Next()
+		if err == io.EOF {
+			return files, nil
+		}
+		if err != nil {
+			return
+		}
+		var p string
+		p, err = entryPath(dir, hdr.Name)
+		if err != nil {
+			return
+		}
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			err = os.MkdirAll(p, os.ModePerm)
+		case tar.TypeReg:
+			err = writeEntry(p, tr, hdr.FileInfo().Mode())
+			files++
+		case tar.TypeSymlink:
+			err = writeSymlink(dir, p, hdr.Linkname)
+			files++
+		case tar.TypeLink:
+			var target string
+			target, err = entryPath(dir, hdr.Linkname)
+			if err == nil {
+				err = removeSymlink(p)
+			}
+			if err == nil {
+				err = os.Link(target, p)
+			}
+			files++
+		default:
+			// devices, fifos and the metadata
+			// of some tar formats are skipped.
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+func extractZip(f *os.File, size int64, dir string, progress func(nread int)) (files int, err error) {
+	zr, err := zip.NewReader(f, size)
+	if err != nil {
+		return
+	}
+	for _, zf := range zr.File {
+		var p string
+		p, err = entryPath(dir, zf.Name)
+		if err != nil {
+			return
+		}
+		mode := zf.Mode()
+		switch {
+		case mode.IsDir():
+			err = os.MkdirAll(p, os.ModePerm)
+		case mode&os.ModeSymlink != 0:
+			err = extractZipSymlink(zf, dir, p)
+			files++
+		default:
+			err = extractZipFile(zf, p)
+			files++
+		}
+		if err != nil {
+			return
+		}
+		progress(int(zf.CompressedSize64))
+	}
+	return
+}
+
+func extractZipFile(zf *zip.File, p string) error {
+	rc, err := zf.Open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	return writeEntry(p, rc, zf.Mode())
+}
+
+func extractZipSymlink(zf *zip.File, dir, p string) error {
+	rc, err := zf.Open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	target, err := io.ReadAll(io.LimitReader(rc, 4*KB))
+	if err != nil {
+		return err
+	}
+	return writeSymlink(dir, p, string(target))
+}
+
+// entryPath returns the path of the archive entry name in dir. It
+// fails with ErrUnsafeArchivePath if the path is outside of dir or
+// goes through a link, as links could point anywhere.
+func entryPath(dir, name string) (string, error) {
+	name = filepath.FromSlash(name)
+	if filepath.IsAbs(name) || filepath.VolumeName(name) != "" {
+		return "", fmt.Errorf("%w: %s", ErrUnsafeArchivePath, name)
+	}
+	p := filepath.Join(dir, name)
+	rel, err := filepath.Rel(dir, p)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("%w: %s", ErrUnsafeArchivePath, name)
+	}
+	// the entry itself may be a link replaced on extraction,
+	// but none of its parent directories.
+	parent := dir
+	for _, elem := range strings.Split(filepath.Dir(rel), string(filepath.Separator)) {
+		if elem == "." {
+			continue
+		}
+		parent = filepath.Join(parent, elem)
+		fi, err := os.Lstat(parent)
+		if err != nil {
+			// no link can be below a missing directory
+			break
+		}
+		if fi.Mode()&os.ModeSymlink != 0 {
+			return "", fmt.Errorf("%w: %s", ErrUnsafeArchivePath, name)
+		}
+	}
+	return p, nil
+}
+
+func writeEntry(p string, r io.Reader, mode os.FileMode) error {
+	err := os.MkdirAll(filepath.Dir(p), os.ModePerm)
+	if err != nil {
+		return err
+	}
+	// don't write through a link left by an earlier entry
+	err = removeSymlink(p)
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(p, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, mode.Perm()|0600)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(f, r)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// writeSymlink creates a link at p to target, which has to be
+// relative and point inside of dir.
+func writeSymlink(dir, p, target string) error {
+	if filepath.IsAbs(target) || filepath.VolumeName(target) != "" {
+		return fmt.Errorf("%w: %s -> %s", ErrUnsafeArchivePath, p, target)
+	}
+	rel, err := filepath.Rel(dir, filepath.Join(filepath.Dir(p), target))
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return fmt.Errorf("%w: %s -> %s", ErrUnsafeArchivePath, p, target)
+	}
+	err = os.MkdirAll(filepath.Dir(p), os.ModePerm)
+	if err != nil {
+		return err
+	}
+	err = removeSymlink(p)
+	if err != nil {
+		return err
+	}
+	return os.Symlink(target, p)
+}
+
+// removeSymlink removes p if it is a link.
+func removeSymlink(p string) error {
+	fi, err := os.Lstat(p)
+	if err != nil || fi.Mode()&os.ModeSymlink == 0 {
+		return nil
+	}
+	return os.Remove(p)
+}
+
+// progressReader reports the number of bytes read from r.
+type progressReader struct {
+	r        io.Reader
+	progress func(nread int)
+}
+
+func (r *progressReader) Read(p []byte) (n int, err error) {
+	n, err = r.r.Read(p)
+	if n > 0 {
+		r.progress(n)
+	}
+	return
+}
+
+// extract extracts the completed download of the item as set up
+// by its ExtractOpts, reporting the progress to the handlers.
+func (m *Manager) extract(h *Handlers, item *Item) (err error) {
+	path := item.GetAbsolutePath()
+	dir := item.GetExtractPath()
+	fi, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("extract: %w", err)
+	}
+	h.ExtractStartHandler(fi.Size())
+	_, err = ExtractArchive(path, dir, func(nread int) {
+		h.ExtractProgressHandler(nread)
+	})
+	if err == nil && item.Extract.DeleteArchive {
+		err = os.Remove(path)
+	}
+	if err != nil {
+		err = fmt.Errorf("extract: %w", err)
+	}
+	h.ExtractCompleteHandler(dir, err)
+	return
+}
@@ -0,0 +1,176 @@
+package warplib
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/ulikunitz/xz"
+)
+
+type testEntry struct {
+	name, body, link string
+	dir              bool
+}
+
+var testEntries = []testEntry{
+	{name: "pkg/", dir: true},
+	{name: "pkg/README", body: "readme"},
+	{name: "pkg/bin/tool", body: "#!/bin/sh"},
+	{name: "pkg/latest", link: "bin/tool"},
+}
+
+func writeTar(t *testing.T, w io.Writer, entries []testEntry) {
+	tw := tar.NewWriter(w)
+	for _, e := range entries {
+		hdr := &tar.Header{Name: e.name, Mode: 0644, Size: int64(len(e.body)), Typeflag: tar.TypeReg}
+		switch {
+		case e.dir:
+			hdr.Typeflag, hdr.Mode = tar.TypeDir, 0755
+		case e.link != "":
+			hdr.Typeflag, hdr.Linkname = tar.TypeSymlink, e.link
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(e.body)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func writeZip(t *testing.T, w io.Writer, entries []testEntry) {
+	zw := zip.NewWriter(w)
+	for _, e := range entries {
+		fh := &zip.FileHeader{Name: e.name, Method: zip.Deflate}
+		body := e.body
+		switch {
+		case e.dir:
+			fh.SetMode(os.ModeDir | 0755)
+		case e.link != "":
+			fh.SetMode(os.ModeSymlink | 0777)
+			body = e.link
+		default:
+			fh.SetMode(0644)
+		}
+		fw, err := zw.CreateHeader(fh)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err = fw.Write([]byte(body)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// createArchive writes an archive of the entries named after its
+// format in a temporary directory and returns its path.
+func createArchive(t *testing.T, name string, entries []testEntry) string {
+	var buf bytes.Buffer
+	switch archiveExt(name) {
+	case ".zip":
+		writeZip(t, &buf, entries)
+	case ".tar":
+		writeTar(t, &buf, entries)
+	case ".tar.gz":
+		gw := gzip.NewWriter(&buf)
+		writeTar(t, gw, entries)
+		gw.Close()
+	case ".tar.zst":
+		zw, err := zstd.NewWriter(&buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		writeTar(t, zw, entries)
+		zw.Close()
+	case ".tar.xz":
+		xw, err := xz.NewWriter(&buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		writeTar(t, xw, entries)
+		xw.Close()
+	default:
+		t.Fatalf("unexpected archive %s", name)
+	}
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestExtractArchive(t *testing.T) {
+	for _, name := range []string{"a.zip", "a.tar", "a.tar.gz", "a.tar.zst", "a.tar.xz"} {
+		t.Run(name, func(t *testing.T) {
+			path := createArchive(t, name, testEntries)
+			dir := DefaultExtractDir(path)
+			var nread int
+			files, err := ExtractArchive(path, dir, func(n int) { nread += n })
+			if err != nil {
+				t.Fatalf("ExtractArchive() error = %v", err)
+			}
+			if files != 3 {
+				t.Errorf("ExtractArchive() files = %d, want 3", files)
+			}
+			fi, _ := os.Stat(path)
+			if int64(nread) != fi.Size() {
+				t.Errorf("ExtractArchive() progress = %d, want %d", nread, fi.Size())
+			}
+			for _, p := range []string{"pkg/README", "pkg/latest"} {
+				b, err := os.ReadFile(filepath.Join(dir, p))
+				if err != nil {
+					t.Fatal(err)
+				}
+				if want := map[string]string{"pkg/README": "readme", "pkg/latest": "#!/bin/sh"}[p]; string(b) != want {
+					t.Errorf("%s = %q, want %q", p, b, want)
+				}
+			}
+		})
+	}
+}
+
+func TestExtractArchiveUnsafe(t *testing.T) {
+	tests := []struct {
+		name    string
+		entries []testEntry
+	}{
+		{"parent", []testEntry{{name: "../evil", body: "x"}}},
+		{"absolute", []testEntry{{name: "/tmp/evil", body: "x"}}},
+		{"absolute link", []testEntry{{name: "link", link: "/etc"}}},
+		{"parent link", []testEntry{{name: "a/link", link: "../../evil"}}},
+		{"through link", []testEntry{
+			{name: "self", link: "."},
+			{name: "self/up", link: ".."},
+			{name: "self/up/evil", body: "x"},
+		}},
+	}
+	for _, tt := range tests {
+		for _, name := range []string{"a.zip", "a.tar"} {
+			t.Run(tt.name+" "+name, func(t *testing.T) {
+				path := createArchive(t, name, tt.entries)
+				dir := filepath.Join(filepath.Dir(path), "out", "dir")
+				_, err := ExtractArchive(path, dir, nil)
+				if !errors.Is(err, ErrUnsafeArchivePath) {
+					t.Errorf("ExtractArchive() error = %v, want %v", err, ErrUnsafeArchivePath)
+				}
+				if _, err = os.Lstat(filepath.Join(dir, "..", "evil")); err == nil {
+					t.Errorf("ExtractArchive() wrote outside of the directory")
+				}
+			})
+		}
+	}
+}
@@ -0,0 +1,179 @@
+package warplib
+
+import (
+	"mime"
+	"net/url"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// maximum length of a file name in bytes supported by
+// most of the file systems.
+const maxFileNameLength = 255
+
+// DEF_FILE_NAME is used if no file name could be determined
+// from server's response.
+const DEF_FILE_NAME = "download"
+
+// parseContentDisposition returns the file name present in a
+// Content-Disposition header value. RFC 5987 extended notation
+// (filename*=UTF-8”...) is preferred over the plain filename
+// parameter and malformed headers are parsed leniently.
+func parseContentDisposition(cd string) (fn string) {
+	_, p, err := mime.ParseMediaType(cd)
+	if err == nil && p["filename"] != "" {
+		// mime decodes the extended filename* parameter
+		// into filename.
+		return unescapeFileName(p["filename"])
+	}
+	var ext string
+	for _, param := range strings.Split(cd, ";") {
+		k, v, ok := strings.Cut(strings.TrimSpace(param), "=")
+		if !ok {
+			continue
+		}
+		v = strings.Trim(strings.TrimSpace(v), `"`)
+		switch strings.ToLower(strings.TrimSpace(k)) {
+		case "filename":
+			fn = unescapeFileName(v)
+		case "filename*":
+			ext = decodeExtValue(v)
+		}
+	}
+	if ext != "" {
+		fn = ext
+	}
+	return
+}
+
+// decodeExtValue decodes a RFC 5987 ext-value of the form
+// charset'language'percent-encoded-value.
+func decodeExtValue(v string) string {
+	parts := strings.SplitN(v, "'", 3)
+	if len(parts) != 3 {
+		return ""
+	}
+	switch strings.ToLower(parts[0]) {
+	case "utf-8", "us-ascii", "":
+	default:
+		// unsupported charset
+		return ""
+	}
+	s, err := url.PathUnescape(parts[2])
+	if err != nil || !utf8.ValidString(s) {
+		return ""
+	}
+	return s
+}
+
+// unescapeFileName percent-decodes file names which some
+// servers send url encoded.
+func unescapeFileName(fn string) string {
+	if !strings.Contains(fn, "%") {
+		return fn
+	}
+	s, err := url.PathUnescape(fn)
+	if err != nil || !utf8.ValidString(s) {
+		return fn
+	}
+	return s
+}
+
+var preferredExtensions = map[string]string{
+	"application/gzip":             ".gz",
+	"application/json":             ".json",
+	"application/pdf":              ".pdf",
+	"application/x-tar":            ".tar",
+	"application/x-xz":             ".xz",
+	"application/zip":              ".zip",
+	"application/zstd":             ".zst",
+	"application/x-7z-compressed":  ".7z",
+	"application/x-iso9660-image":  ".iso",
+	"application/vnd.rar":          ".rar",
+	"application/x-rar-compressed": ".rar",
+	"audio/mpeg":                   ".mp3",
+	"image/gif":                    ".gif",
+	"image/jpeg":                   ".jpg",
+	"image/png":                    ".png",
+	"image/webp":                   ".webp",
+	"text/html":                    ".html",
+	"text/plain":                   ".txt",
+	"video/mp4":                    ".mp4",
+	"video/webm":                   ".webm",
+}
+
+// extensionByType returns the preferred file extension for
+// the provided Content-Type header value.
+func extensionByType(ct string) string {
+	mt, _, err := mime.ParseMediaType(ct)
+	if err != nil || mt == "application/octet-stream" {
+		return ""
+	}
+	if ext, ok := preferredExtensions[mt]; ok {
+		return ext
+	}
+	exts, err := mime.ExtensionsByType(mt)
+	if err != nil || len(exts) == 0 {
+		return ""
+	}
+	return exts[0]
+}
+
+// windows reserved device names which can't be used as
+// file names irrespective of their extension.
+var reservedFileNames = map[string]bool{
+	"CON": true, "PRN": true, "AUX": true, "NUL": true,
+	"COM1": true, "COM2": true, "COM3": true, "COM4": true, "COM5": true,
+	"COM6": true, "COM7": true, "COM8": true, "COM9": true,
+	"LPT1": true, "LPT2": true, "LPT3": true, "LPT4": true, "LPT5": true,
+	"LPT6": true, "LPT7": true, "LPT8": true, "LPT9": true,
+}
+
+// sanitizeFileName makes a file name received from a remote
+// server safe to be used as a single path component. Path
+// separators, control and reserved characters are replaced,
+// reserved names are prefixed and long names are truncated
+// while preserving the extension.
+func sanitizeFileName(fn string) string {
+	fn = strings.Map(func(r rune) rune {
+		switch r {
+		case '/', '\\', '<', '>', ':', '"', '|', '?', '*':
+			return '_'
+		}
+		if unicode.IsControl(r) || r == utf8.RuneError {
+			return '_'
+		}
+		return r
+	}, fn)
+	// trailing dots and spaces are stripped by windows and
+	// names like "." or ".." refer to directories, leading
+	// dots are kept for hidden files such as .bashrc.
+	fn = strings.TrimLeft(strings.TrimRight(fn, " ."), " ")
+	if fn == "" {
+		return ""
+	}
+	base, _, _ := strings.Cut(fn, ".")
+	if reservedFileNames[strings.ToUpper(base)] {
+		fn = "_" + fn
+	}
+	return truncateFileName(fn, maxFileNameLength)
+}
+
+// truncateFileName shortens fn to n bytes at most, keeping its
+// extension and not splitting multi-byte characters.
+func truncateFileName(fn string, n int) string {
+	if len(fn) <= n {
+		return fn
+	}
+	var ext string
+	if i := strings.LastIndexByte(fn, '.'); i > 0 && len(fn)-i <= 16 {
+		ext = fn[i:]
+		fn = fn[:i]
+	}
+	n -= len(ext)
+	for n > 0 && !utf8.RuneStart(fn[n]) {
+		n--
+	}
+	return fn[:n] + ext
+}
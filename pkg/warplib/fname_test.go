@@ -0,0 +1,52 @@
+package warplib
+
+import (
+	"strings"
+	"testing"
+)
+
+func Test_sanitizeFileName(t *testing.T) {
+	long := strings.Repeat("a", 300) + ".tar.gz"
+	tests := []struct {
+		name string
+		fn   string
+		want string
+	}{
+		{"plain", "file.zip", "file.zip"},
+		{"path traversal", "../../etc/passwd", ".._.._etc_passwd"},
+		{"windows separators", `..\..\boot.ini`, `.._.._boot.ini`},
+		{"dot dot", "..", ""},
+		{"dotfile", ".bashrc", ".bashrc"},
+		{"leading spaces", "  .env ", ".env"},
+		{"reserved name", "con.txt", "_con.txt"},
+		{"control characters", "a\x00b\nc.txt", "a_b_c.txt"},
+		{"trailing dots", "file.txt. . ", "file.txt"},
+		{"long name", long, strings.Repeat("a", 255-len(".gz")) + ".gz"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := sanitizeFileName(tt.fn); got != tt.want {
+				t.Errorf("sanitizeFileName() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_extensionByType(t *testing.T) {
+	tests := []struct {
+		ct   string
+		want string
+	}{
+		{"image/jpeg", ".jpg"},
+		{"application/zip; charset=binary", ".zip"},
+		{"application/octet-stream", ""},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.ct, func(t *testing.T) {
+			if got := extensionByType(tt.ct); got != tt.want {
+				t.Errorf("extensionByType() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
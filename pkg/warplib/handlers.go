@@ -14,6 +14,20 @@ type (
 	CompileSkippedHandlerFunc   func(hash string, tread int64)
 	CompileCompleteHandlerFunc  func(hash string, tread int64)
 	DownloadStoppedHandlerFunc  func()
+	// DownloadStartedHandlerFunc is called when the download
+	// starts or resumes transferring content.
+	DownloadStartedHandlerFunc func()
+	// ExtractStartHandlerFunc is called when the extraction of
+	// a completed download starts, with the size of the archive.
+	ExtractStartHandlerFunc    func(total int64)
+	ExtractProgressHandlerFunc func(nread int)
+	// ExtractCompleteHandlerFunc is called when the extraction
+	// into dir ends, err is the error of a failed extraction.
+	ExtractCompleteHandlerFunc func(dir string, err error)
+	// ConnectionsTunedHandlerFunc is called when the connection
+	// tuner changes the connection limit from prev to curr after
+	// observing throughput (bytes per second).
+	ConnectionsTunedHandlerFunc func(prev, curr int32, throughput int64)
 )
 
 type Handlers struct {
@@ -28,6 +42,11 @@ type Handlers struct {
 	CompileSkippedHandler   CompileSkippedHandlerFunc
 	CompileCompleteHandler  CompileCompleteHandlerFunc
 	DownloadStoppedHandler  DownloadStoppedHandlerFunc
+	ConnectionsTunedHandler ConnectionsTunedHandlerFunc
+	DownloadStartedHandler  DownloadStartedHandlerFunc
+	ExtractStartHandler     ExtractStartHandlerFunc
+	ExtractProgressHandler  ExtractProgressHandlerFunc
+	ExtractCompleteHandler  ExtractCompleteHandlerFunc
 }
 
 func (h *Handlers) setDefault(l *log.Logger) {
@@ -72,4 +91,19 @@ func (h *Handlers) setDefault(l *log.Logger) {
 	if h.DownloadStoppedHandler == nil {
 		h.DownloadStoppedHandler = func() {}
 	}
+	if h.ConnectionsTunedHandler == nil {
+		h.ConnectionsTunedHandler = func(prev, curr int32, throughput int64) {}
+	}
+	if h.DownloadStartedHandler == nil {
+		h.DownloadStartedHandler = func() {}
+	}
+	if h.ExtractStartHandler == nil {
+		h.ExtractStartHandler = func(total int64) {}
+	}
+	if h.ExtractProgressHandler == nil {
+		h.ExtractProgressHandler = func(nread int) {}
+	}
+	if h.ExtractCompleteHandler == nil {
+		h.ExtractCompleteHandler = func(dir string, err error) {}
+	}
 }
@@ -0,0 +1,243 @@
+package warplib
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"runtime"
+	"strconv"
+	"time"
+)
+
+const (
+	// DEF_HOOK_TIMEOUT is the time after which a hook is killed.
+	DEF_HOOK_TIMEOUT = 5 * time.Minute
+	// DEF_HOOK_MAX_OUTPUT is the maximum output of a hook
+	// kept in the log of the download.
+	DEF_HOOK_MAX_OUTPUT = 64 * KB
+	// maxHookRuns is the number of hook runs kept per item.
+	maxHookRuns = 10
+)
+
+// HookEvent is the event of a download which runs a hook.
+type HookEvent string
+
+const (
+	HookComplete HookEvent = "complete"
+	HookError    HookEvent = "error"
+)
+
+// Hooks are commands run by the system shell when a download
+// completes or fails, with the environment variables:
+//
+//	WARPDL_EVENT      complete or error
+//	WARPDL_STATUS     completed or failed
+//	WARPDL_HASH       hash of the download
+//	WARPDL_NAME       file name
+//	WARPDL_PATH       absolute path of the file
+//	WARPDL_DIRECTORY  absolute path of the download directory
+//	WARPDL_URL        url of the download
+//	WARPDL_SIZE       size of the file in bytes, -1 if unknown
+//	WARPDL_DOWNLOADED downloaded bytes
+//	WARPDL_CHECKSUM   SHA-256 of the completed file, in hex
+//	WARPDL_EXTRACTED  directory the archive was extracted to
+//	WARPDL_ERROR      error of the failed download
+type Hooks struct {
+	OnComplete string `json:"on_complete,omitempty"`
+	OnError    string `json:"on_error,omitempty"`
+	// Timeout is the time after which a hook is killed,
+	// DEF_HOOK_TIMEOUT is used if 0.
+	Timeout time.Duration `json:"timeout,omitempty"`
+}
+
+func (h *Hooks) command(event HookEvent) string {
+	if h == nil {
+		return ""
+	}
+	switch event {
+	case HookComplete:
+		return h.OnComplete
+	case HookError:
+		return h.OnError
+	}
+	return ""
+}
+
+// HookRun records a run of a hook of an item,
+// its output is added to the log of the download.
+type HookRun struct {
+	Event     HookEvent `json:"event"`
+	Command   string    `json:"command"`
+	StartedAt time.Time `json:"started_at"`
+	// ExitCode is the exit status of the command,
+	// -1 if it couldn't be run or was killed.
+	ExitCode int `json:"exit_code"`
+	// Error describes why the command failed to run.
+	Error string `json:"error,omitempty"`
+}
+
+// runHook runs the hook of the item for the event, the hook
+// of the item takes precedence over the default one. failure
+// is the error of a failed download.
+func (m *Manager) runHook(item *Item, event HookEvent, failure error) {
+	command := item.Hooks.command(event)
+	if command == "" {
+		command = m.hooks.command(event)
+	}
+	if command == "" {
+		return
+	}
+	timeout := DEF_HOOK_TIMEOUT
+	switch {
+	case item.Hooks != nil && item.Hooks.Timeout > 0:
+		timeout = item.Hooks.Timeout
+	case m.hooks != nil && m.hooks.Timeout > 0:
+		timeout = m.hooks.Timeout
+	}
+	run, output := execHook(command, timeout, hookEnv(item, event, failure))
+	run.Event = event
+	if err := logHook(item.Hash, run, output); err != nil {
+		log.Printf("hook: failed to write log of %s: %s\n", item.Hash, err.Error())
+	}
+	item.mu.Lock()
+	item.HookRuns = append(item.HookRuns, *run)
+	if n := len(item.HookRuns); n > maxHookRuns {
+		item.HookRuns = item.HookRuns[n-maxHookRuns:]
+	}
+	item.mu.Unlock()
+	m.UpdateItem(item)
+}
+
+// execHook runs command in the system shell, its combined
+// output is returned truncated to DEF_HOOK_MAX_OUTPUT.
+func execHook(command string, timeout time.Duration, env []string) (run *HookRun, output []byte) {
+	run = &HookRun{
+		Command:   command,
+		StartedAt: time.Now(),
+		ExitCode:  -1,
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "windows":
+		cmd = exec.CommandContext(ctx, "cmd", "/C", command)
+	default:
+		cmd = exec.CommandContext(ctx, "sh", "-c", command)
+	}
+	cmd.Env = append(os.Environ(), env...)
+	out := &limitedBuffer{max: int(DEF_HOOK_MAX_OUTPUT)}
+	cmd.Stdout = out
+	cmd.Stderr = out
+	// don't wait for the output of processes
+	// left behind by a killed command.
+	cmd.WaitDelay = time.Second
+	err := cmd.Run()
+	var exitErr *exec.ExitError
+	switch {
+	case ctx.Err() == context.DeadlineExceeded:
+		run.Error = fmt.Sprintf("killed after timeout of %s", timeout)
+	case errors.As(err, &exitErr):
+		run.ExitCode = exitErr.ExitCode()
+	case err != nil:
+		run.Error = err.Error()
+	default:
+		run.ExitCode = 0
+	}
+	return run, out.Bytes()
+}
+
+func hookEnv(item *Item, event HookEvent, failure error) []string {
+	status := "completed"
+	if event == HookError {
+		status = "failed"
+	}
+	env := []string{
+		"WARPDL_EVENT=" + string(event),
+		"WARPDL_STATUS=" + status,
+		"WARPDL_HASH=" + item.Hash,
+		"WARPDL_NAME=" + item.Name,
+		"WARPDL_PATH=" + item.GetAbsolutePath(),
+		"WARPDL_DIRECTORY=" + item.AbsoluteLocation,
+		"WARPDL_URL=" + item.Url,
+		"WARPDL_SIZE=" + strconv.FormatInt(item.TotalSize.v(), 10),
+		"WARPDL_DOWNLOADED=" + strconv.FormatInt(item.Downloaded.v(), 10),
+	}
+	if event == HookComplete {
+		// the archive might have been deleted once extracted
+		sum, err := fileChecksum(item.GetAbsolutePath())
+		if err == nil {
+			env = append(env, "WARPDL_CHECKSUM="+sum)
+		}
+		if item.Extract != nil {
+			env = append(env, "WARPDL_EXTRACTED="+item.GetExtractPath())
+		}
+	}
+	if failure != nil {
+		env = append(env, "WARPDL_ERROR="+failure.Error())
+	}
+	return env
+}
+
+// fileChecksum returns the hex encoded SHA-256 of the file at path.
+func fileChecksum(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err = io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// logHook adds the run and output of a hook to the
+// log of the download with the hash.
+func logHook(hash string, run *HookRun, output []byte) error {
+	f, err := os.OpenFile(
+		GetPath(GetPath(DlDataDir, hash), "logs.txt"),
+		os.O_WRONLY|os.O_CREATE|os.O_APPEND,
+		0666,
+	)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	l := log.New(f, "", log.LstdFlags)
+	wlog(l, "Hook on %s: running %q", run.Event, run.Command)
+	if len(output) != 0 {
+		wlog(l, "Hook output:\n%s", bytes.TrimRight(output, "\r\n"))
+	}
+	if run.Error != "" {
+		wlog(l, "Hook failed: %s", run.Error)
+		return nil
+	}
+	wlog(l, "Hook exited with status %d", run.ExitCode)
+	return nil
+}
+
+// limitedBuffer keeps the first max bytes written to it.
+type limitedBuffer struct {
+	buf bytes.Buffer
+	max int
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	if n := b.max - b.buf.Len(); n > 0 {
+		b.buf.Write(p[:min(n, len(p))])
+	}
+	return len(p), nil
+}
+
+func (b *limitedBuffer) Bytes() []byte {
+	return b.buf.Bytes()
+}
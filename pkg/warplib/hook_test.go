@@ -0,0 +1,52 @@
+package warplib
+
+import (
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestExecHook(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("hooks are run by sh in these tests")
+	}
+	tests := []struct {
+		name     string
+		command  string
+		exitCode int
+		output   string
+		failed   bool
+	}{
+		{"success", `echo "$WARPDL_NAME"`, 0, "file.zip\n", false},
+		{"exit status", "echo failed >&2; exit 3", 3, "failed\n", false},
+		{"timeout", "sleep 5", -1, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			run, output := execHook(tt.command, 500*time.Millisecond, []string{"WARPDL_NAME=file.zip"})
+			if run.ExitCode != tt.exitCode {
+				t.Errorf("execHook() exit code = %d, want %d", run.ExitCode, tt.exitCode)
+			}
+			if string(output) != tt.output {
+				t.Errorf("execHook() output = %q, want %q", output, tt.output)
+			}
+			if (run.Error != "") != tt.failed {
+				t.Errorf("execHook() error = %q, want failed %v", run.Error, tt.failed)
+			}
+		})
+	}
+}
+
+func TestExecHookOutputLimit(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("hooks are run by sh in these tests")
+	}
+	run, output := execHook("yes | head -c 200000", time.Minute, nil)
+	if run.ExitCode != 0 {
+		t.Fatalf("execHook() exit code = %d, error = %q", run.ExitCode, run.Error)
+	}
+	if len(output) != int(DEF_HOOK_MAX_OUTPUT) || !strings.HasPrefix(string(output), "y\n") {
+		t.Errorf("execHook() output length = %d, want %d", len(output), DEF_HOOK_MAX_OUTPUT)
+	}
+}
@@ -1,8 +1,12 @@
 package warplib
 
 import (
+	"fmt"
 	"path/filepath"
+	"slices"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -10,20 +14,60 @@ type Item struct {
 	Hash             string              `json:"hash"`
 	Name             string              `json:"name"`
 	Url              string              `json:"url"`
+	SourceUrl        string              `json:"source_url"`
+	ETag             string              `json:"etag"`
+	ContentEncoding  string              `json:"content_encoding"`
+	EffectiveUrl     string              `json:"effective_url"`
+	RedirectChain    []string            `json:"redirect_chain"`
 	Headers          Headers             `json:"headers"`
 	DateAdded        time.Time           `json:"date_added"`
 	TotalSize        ContentLength       `json:"total_size"`
 	Downloaded       ContentLength       `json:"downloaded"`
 	DownloadLocation string              `json:"download_location"`
 	AbsoluteLocation string              `json:"absolute_location"`
+	TempPath         string              `json:"temp_path"`
 	ChildHash        string              `json:"child_hash"`
 	Hidden           bool                `json:"hidden"`
 	Children         bool                `json:"children"`
 	Parts            map[int64]*ItemPart `json:"parts"`
 	Resumable        bool                `json:"resumable"`
-	mu               *sync.RWMutex
-	dAlloc           *Downloader
-	memPart          map[string]int64
+	// DecodeContent reports whether the content is decoded
+	// while downloading, see DownloaderOpts.DecodeContent.
+	DecodeContent bool `json:"decode_content"`
+	// Ranged reports whether only a byte range of the remote
+	// file is downloaded, the range starts at RangeStart in
+	// the remote file and TotalSize is its length.
+	Ranged     bool  `json:"ranged"`
+	RangeStart int64 `json:"range_start"`
+	// Group reports whether the item only groups downloads,
+	// such as the files of a crawled directory, and tracks
+	// their progress without any content of its own.
+	Group bool `json:"group"`
+	// ParentHash is the hash of the group of the item.
+	ParentHash string `json:"parent_hash"`
+	// Schedule restricts when the item is downloaded, the
+	// daemon starts and pauses the item accordingly.
+	Schedule *Schedule `json:"schedule"`
+	// Hooks are run when the download completes or fails,
+	// the default hooks of the manager are used if unset.
+	Hooks *Hooks `json:"hooks"`
+	// HookRuns records the latest runs of the hooks.
+	HookRuns []HookRun `json:"hook_runs"`
+	// Extract extracts the archive once downloaded if set.
+	Extract *ExtractOpts `json:"extract"`
+	// Category is the name of the category which picked
+	// the download directory, if any.
+	Category string `json:"category"`
+	// Tags label the item, see Category.Tags.
+	Tags []string `json:"tags"`
+	// Note is a free-form note of the user.
+	Note string `json:"note"`
+	// Failure describes why a download which can't be
+	// resumed has failed.
+	Failure string `json:"failure"`
+	mu      *sync.RWMutex
+	dAlloc  *Downloader
+	memPart map[string]int64
 }
 
 type ItemPart struct {
@@ -37,7 +81,23 @@ type ItemsMap map[string]*Item
 type itemOpts struct {
 	Hide, Child      bool
 	ChildHash        string
+	ParentHash       string
+	Schedule         *Schedule
+	Hooks            *Hooks
+	Extract          *ExtractOpts
+	Category         string
+	Tags             []string
+	Note             string
 	AbsoluteLocation string
+	TempPath         string
+	Ranged           bool
+	RangeStart       int64
+	SourceUrl        string
+	ETag             string
+	ContentEncoding  string
+	DecodeContent    bool
+	EffectiveUrl     string
+	RedirectChain    []string
 	Headers          []Header
 }
 
@@ -60,7 +120,23 @@ func newItem(mu *sync.RWMutex, name, url, dlloc, hash string, totalSize ContentL
 		TotalSize:        totalSize,
 		DownloadLocation: dlloc,
 		AbsoluteLocation: opts.AbsoluteLocation,
+		TempPath:         opts.TempPath,
+		Ranged:           opts.Ranged,
+		RangeStart:       opts.RangeStart,
+		SourceUrl:        opts.SourceUrl,
+		ETag:             opts.ETag,
+		ContentEncoding:  opts.ContentEncoding,
+		DecodeContent:    opts.DecodeContent,
+		EffectiveUrl:     opts.EffectiveUrl,
+		RedirectChain:    opts.RedirectChain,
 		ChildHash:        opts.ChildHash,
+		ParentHash:       opts.ParentHash,
+		Schedule:         opts.Schedule,
+		Hooks:            opts.Hooks,
+		Extract:          opts.Extract,
+		Category:         opts.Category,
+		Tags:             opts.Tags,
+		Note:             opts.Note,
 		Hidden:           opts.Hide,
 		Children:         opts.Child,
 		Resumable:        resumable,
@@ -95,7 +171,20 @@ func (i *Item) getPart(hash string) (offset int64, part *ItemPart) {
 	return
 }
 
+// addDownloaded and addTotalSize update the progress of a group,
+// its downloads can report progress concurrently.
+func (i *Item) addDownloaded(n ContentLength) {
+	atomic.AddInt64((*int64)(&i.Downloaded), int64(n))
+}
+
+func (i *Item) addTotalSize(n ContentLength) {
+	atomic.AddInt64((*int64)(&i.TotalSize), int64(n))
+}
+
 func (i *Item) GetPercentage() int64 {
+	if i.TotalSize <= 0 {
+		return 0
+	}
 	p := (i.Downloaded * 100) / i.TotalSize
 	return p.v()
 }
@@ -110,6 +199,29 @@ func (i *Item) GetAbsolutePath() (aPath string) {
 	return
 }
 
+// GetExtractPath returns the directory the item is extracted to,
+// an empty string if it isn't extracted.
+func (i *Item) GetExtractPath() string {
+	switch {
+	case i.Extract == nil:
+		return ""
+	case i.Extract.Directory == "":
+		return DefaultExtractDir(i.GetAbsolutePath())
+	case filepath.IsAbs(i.Extract.Directory):
+		return i.Extract.Directory
+	}
+	return filepath.Join(i.AbsoluteLocation, i.Extract.Directory)
+}
+
+// GetTempPath returns the path of the temporary file
+// the item is written to until its download completes.
+func (i *Item) GetTempPath() string {
+	if i.TempPath == "" {
+		return i.GetSavePath()
+	}
+	return i.TempPath
+}
+
 func (i *Item) GetMaxConnections() (int32, error) {
 	if i.dAlloc == nil {
 		return 0, ErrItemDownloaderNotFound
@@ -128,9 +240,119 @@ func (i *Item) Resume() error {
 	if i.dAlloc == nil {
 		return ErrItemDownloaderNotFound
 	}
+	if i.notStarted() {
+		// download was scheduled and hasn't started yet
+		return i.dAlloc.startFresh(i.Resumable)
+	}
 	return i.dAlloc.Resume(i.Parts)
 }
 
+// notStarted reports whether nothing of the item has been downloaded.
+func (i *Item) notStarted() bool {
+	return len(i.Parts) == 0 && i.Downloaded == 0 && i.Failure == ""
+}
+
+// IsDownloading reports whether the item is being downloaded.
+func (i *Item) IsDownloading() bool {
+	return i.dAlloc != nil && i.dAlloc.IsRunning()
+}
+
+// IsStopped reports whether the download of the item was stopped.
+func (i *Item) IsStopped() bool {
+	return i.dAlloc != nil && i.dAlloc.IsStopped()
+}
+
+// IsComplete reports whether the item is completely downloaded.
+func (i *Item) IsComplete() bool {
+	return !i.TotalSize.IsUnknown() && i.Downloaded >= i.TotalSize
+}
+
+// isRanged reports whether only a byte range is downloaded,
+// items saved before Ranged was added only have RangeStart.
+func (i *Item) isRanged() bool {
+	return i.Ranged || i.RangeStart != 0
+}
+
+// ItemState is the state of an item, see Item.State.
+type ItemState string
+
+const (
+	StateDownloading ItemState = "downloading"
+	StateCompleted   ItemState = "completed"
+	StateFailed      ItemState = "failed"
+	// StateScheduled items wait for their schedule.
+	StateScheduled ItemState = "scheduled"
+	// StatePaused items are incomplete and not running.
+	StatePaused ItemState = "paused"
+)
+
+// ItemStates are all the states of items.
+var ItemStates = []ItemState{
+	StateDownloading, StateCompleted, StateFailed, StateScheduled, StatePaused,
+}
+
+// ParseItemState parses the name of a state, case-insensitively.
+func ParseItemState(s string) (ItemState, error) {
+	state := ItemState(strings.ToLower(strings.TrimSpace(s)))
+	if !slices.Contains(ItemStates, state) {
+		return "", fmt.Errorf("%w: %s", ErrInvalidItemState, s)
+	}
+	return state, nil
+}
+
+// State returns the state of the item. Groups don't run
+// themselves, they are paused while their downloads run.
+func (i *Item) State() ItemState {
+	switch {
+	case i.IsDownloading():
+		return StateDownloading
+	case i.IsComplete():
+		return StateCompleted
+	case i.Failure != "":
+		return StateFailed
+	case i.Schedule != nil:
+		return StateScheduled
+	}
+	return StatePaused
+}
+
+// GetSpeed returns the current download speed of the
+// item in bytes per second, 0 if it isn't running.
+func (i *Item) GetSpeed() int64 {
+	if !i.IsDownloading() {
+		return 0
+	}
+	return i.dAlloc.GetSpeed()
+}
+
+// SetSpeedLimit limits the speed of the running download of the
+// item in bytes per second, 0 removes the limit.
+func (i *Item) SetSpeedLimit(limit int64) error {
+	if i.dAlloc == nil {
+		return ErrItemDownloaderNotFound
+	}
+	i.dAlloc.SetSpeedLimit(limit)
+	return nil
+}
+
+// SetMaxConnections caps the connections of the running
+// download of the item, see Downloader.SetMaxConnections.
+func (i *Item) SetMaxConnections(n int32) (int32, error) {
+	if i.dAlloc == nil {
+		return 0, ErrItemDownloaderNotFound
+	}
+	return i.dAlloc.SetMaxConnections(n), nil
+}
+
+// GetConnections returns the number of connections of the
+// running download of the item and its connection limit.
+func (i *Item) GetConnections() (conns, limit int32) {
+	if !i.IsDownloading() {
+		return 0, 0
+	}
+	return atomic.LoadInt32(&i.dAlloc.numConn), i.dAlloc.getConnLimit()
+}
+
 func (i *Item) StopDownload() error {
 	if i.dAlloc == nil {
 		return ErrItemDownloaderNotFound
@@ -0,0 +1,128 @@
+package warplib
+
+import (
+	"context"
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// speedLimiter paces the reads of all the parts of a download to
+// keep its total speed under a limit, it can be changed while the
+// download is running.
+type speedLimiter struct {
+	// limit in bytes per second, unlimited if 0
+	rate int64
+	mu   sync.Mutex
+	// time at which the bytes read so far are allowed
+	next time.Time
+}
+
+func (l *speedLimiter) setRate(rate int64) {
+	atomic.StoreInt64(&l.rate, max(rate, 0))
+}
+
+func (l *speedLimiter) getRate() int64 {
+	if l == nil {
+		return 0
+	}
+	return atomic.LoadInt64(&l.rate)
+}
+
+// active reports whether reads are being limited.
+func (l *speedLimiter) active() bool {
+	return l.getRate() > 0
+}
+
+// wait blocks until reading n more bytes keeps the speed under the
+// limit, or ctx is done.
+func (l *speedLimiter) wait(ctx context.Context, n int) {
+	rate := l.getRate()
+	if rate <= 0 || n <= 0 {
+		return
+	}
+	l.mu.Lock()
+	now := time.Now()
+	if l.next.Before(now) {
+		// unused bandwidth isn't saved for later
+		l.next = now
+	}
+	l.next = l.next.Add(time.Duration(int64(n) * int64(time.Second) / rate))
+	delay := l.next.Sub(now)
+	l.mu.Unlock()
+	if delay <= 0 {
+		return
+	}
+	t := time.NewTimer(delay)
+	defer t.Stop()
+	select {
+	case <-t.C:
+	case <-ctx.Done():
+	}
+}
+
+// limitedReader limits the speed of reading from r.
+type limitedReader struct {
+	ctx context.Context
+	r   io.Reader
+	l   *speedLimiter
+}
+
+func (r *limitedReader) Read(p []byte) (n int, err error) {
+	n, err = r.r.Read(p)
+	r.l.wait(r.ctx, n)
+	return
+}
+
+// DEF_SPEED_WINDOW is the period over which
+// the speed of a download is measured.
+const DEF_SPEED_WINDOW = time.Second
+
+// speedMeter measures the speed of a download
+// over its last complete window.
+type speedMeter struct {
+	mu sync.Mutex
+	// start of the current window and bytes read in it
+	start time.Time
+	n     int64
+	// speed in bytes per second
+	rate int64
+}
+
+func (s *speedMeter) add(n int) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.roll(time.Now())
+	s.n += int64(n)
+}
+
+func (s *speedMeter) get() int64 {
+	if s == nil {
+		return 0
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.roll(time.Now())
+	return s.rate
+}
+
+// roll starts a new window once the current one is
+// over, s.mu must be held.
+func (s *speedMeter) roll(now time.Time) {
+	if s.start.IsZero() {
+		s.start = now
+		return
+	}
+	elapsed := now.Sub(s.start)
+	if elapsed < DEF_SPEED_WINDOW {
+		return
+	}
+	// a window without reads stretches until the next one,
+	// lowering the speed as it should.
+	s.rate = s.n * _SECOND / int64(elapsed)
+	s.start, s.n = now, 0
+}
@@ -0,0 +1,30 @@
+package warplib
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSpeedMeterRoll(t *testing.T) {
+	var s speedMeter
+	now := time.Now()
+	s.roll(now)
+	s.n = 3 * MB
+	s.roll(now.Add(DEF_SPEED_WINDOW / 2))
+	if s.rate != 0 {
+		t.Errorf("rate = %d before the window is over, want 0", s.rate)
+	}
+	s.roll(now.Add(2 * DEF_SPEED_WINDOW))
+	if want := 3 * MB * _SECOND / int64(2*DEF_SPEED_WINDOW); s.rate != want {
+		t.Errorf("rate = %d, want %d", s.rate, want)
+	}
+	s.roll(now.Add(4 * DEF_SPEED_WINDOW))
+	if s.rate != 0 {
+		t.Errorf("rate = %d after an idle window, want 0", s.rate)
+	}
+	var nilMeter *speedMeter
+	nilMeter.add(10)
+	if nilMeter.get() != 0 {
+		t.Error("nil meter has a speed")
+	}
+}
@@ -1,11 +1,15 @@
 package warplib
 
 import (
+	"crypto/rand"
 	"encoding/gob"
+	"encoding/hex"
 	"errors"
+	"fmt"
 	"net/http"
 	"os"
 	"sync"
+	"sync/atomic"
 )
 
 var __USERDATA_FILE_NAME = ConfigDir + "/userdata.warp"
@@ -14,6 +18,8 @@ type Manager struct {
 	items ItemsMap
 	f     *os.File
 	mu    *sync.RWMutex
+	// hooks are run for the items without hooks of their own
+	hooks *Hooks
 }
 
 func InitManager() (m *Manager, err error) {
@@ -40,10 +46,29 @@ type AddDownloadOpts struct {
 	IsChildren       bool
 	ChildHash        string
 	AbsoluteLocation string
+	// SourceUrl is the url submitted by the user before
+	// being extracted by an extension, if any.
+	SourceUrl string
+	// ParentHash adds the download to the group
+	// with the hash, see AddGroup.
+	ParentHash string
+	// Schedule restricts when the download runs.
+	Schedule *Schedule
+	// Hooks are run when the download completes or fails.
+	Hooks *Hooks
+	// Extract extracts the archive once downloaded.
+	Extract *ExtractOpts
+	// Tags label the download, tags of its category
+	// are added to them.
+	Tags []string
+	// Note is a free-form note of the download.
+	Note string
 }
 
 func (m *Manager) populateMemPart() {
 	for _, item := range m.items {
+		// items are listed without GetItem as well.
+		item.mu = m.mu
 		if item.memPart == nil {
 			item.memPart = make(map[string]int64)
 		}
@@ -57,6 +82,15 @@ func (m *Manager) AddDownload(d *Downloader, opts *AddDownloadOpts) (err error)
 	if opts == nil {
 		opts = &AddDownloadOpts{}
 	}
+	if opts.Extract != nil && !IsArchive(d.fileName) {
+		return fmt.Errorf("%w: %s", ErrArchiveNotSupported, d.fileName)
+	}
+	var category string
+	tags := mergeTags(nil, opts.Tags...)
+	if d.category != nil {
+		category = d.category.Name
+		tags = mergeTags(tags, d.category.Tags...)
+	}
 	item, err := newItem(
 		m.mu,
 		d.fileName,
@@ -70,6 +104,22 @@ func (m *Manager) AddDownload(d *Downloader, opts *AddDownloadOpts) (err error)
 			Child:            opts.IsChildren,
 			Hide:             opts.IsHidden,
 			ChildHash:        opts.ChildHash,
+			ParentHash:       opts.ParentHash,
+			Schedule:         opts.Schedule,
+			Hooks:            opts.Hooks,
+			Extract:          opts.Extract,
+			Category:         category,
+			Tags:             tags,
+			Note:             opts.Note,
+			TempPath:         d.tmpPath,
+			Ranged:           d.ranged,
+			RangeStart:       d.rangeStart,
+			EffectiveUrl:     d.effectiveUrl,
+			RedirectChain:    d.redirects,
+			SourceUrl:        opts.SourceUrl,
+			ETag:             d.etag,
+			ContentEncoding:  d.contentEncodingOnDisk(),
+			DecodeContent:    d.decode,
 			Headers:          d.headers,
 		},
 	)
@@ -77,11 +127,39 @@ func (m *Manager) AddDownload(d *Downloader, opts *AddDownloadOpts) (err error)
 		return err
 	}
 	item.dAlloc = d
+	if parent := m.GetItem(opts.ParentHash); parent != nil && !d.contentLength.IsUnknown() {
+		parent.addTotalSize(d.contentLength)
+	}
 	m.UpdateItem(item)
 	m.patchHandlers(d, item)
 	return
 }
 
+// AddGroup adds an item which groups the downloads added with its
+// hash as AddDownloadOpts.ParentHash. The group has no content of
+// its own, its size and progress are the sum of its downloads.
+func (m *Manager) AddGroup(name, url, dlloc string, opts *AddDownloadOpts) (item *Item, err error) {
+	if opts == nil {
+		opts = &AddDownloadOpts{}
+	}
+	buf := make([]byte, 4)
+	rand.Read(buf)
+	item, err = newItem(m.mu, name, url, dlloc, hex.EncodeToString(buf), 0, false, &itemOpts{
+		AbsoluteLocation: opts.AbsoluteLocation,
+		Hide:             opts.IsHidden,
+		ChildHash:        opts.ChildHash,
+		SourceUrl:        opts.SourceUrl,
+		Tags:             mergeTags(nil, opts.Tags...),
+		Note:             opts.Note,
+	})
+	if err != nil {
+		return
+	}
+	item.Group = true
+	m.UpdateItem(item)
+	return
+}
+
 func (m *Manager) patchHandlers(d *Downloader, item *Item) {
 	oSPH := d.handlers.SpawnPartHandler
 	d.handlers.SpawnPartHandler = func(hash string, ioff, foff int64) {
@@ -95,9 +173,13 @@ func (m *Manager) patchHandlers(d *Downloader, item *Item) {
 		m.UpdateItem(item)
 		oRPH(hash, partIoff, ioffNew, foffNew)
 	}
+	parent := m.GetItem(item.ParentHash)
 	oPH := d.handlers.DownloadProgressHandler
 	d.handlers.DownloadProgressHandler = func(hash string, nread int) {
 		item.Downloaded += ContentLength(nread)
+		if parent != nil {
+			parent.addDownloaded(ContentLength(nread))
+		}
 		m.UpdateItem(item)
 		oPH(hash, nread)
 	}
@@ -112,16 +194,73 @@ func (m *Manager) patchHandlers(d *Downloader, item *Item) {
 		item.savePart(off, part)
 		oCCH(hash, tread)
 	}
+	oEH := d.handlers.ErrorHandler
+	var failed int32
+	d.handlers.ErrorHandler = func(hash string, err error) {
+		if !item.Resumable && item.Failure == "" {
+			item.Failure = err.Error()
+		}
+		// errors of the other parts follow the first one
+		// and parts interrupted by a stop aren't failures.
+		if !d.IsStopped() && atomic.CompareAndSwapInt32(&failed, 0, 1) {
+			go m.runHook(item, HookError, err)
+		}
+		oEH(hash, err)
+	}
+	oDSH := d.handlers.DownloadStoppedHandler
+	d.handlers.DownloadStoppedHandler = func() {
+		if !item.Resumable {
+			m.markFailed(item)
+		}
+		oDSH()
+	}
 	oDCH := d.handlers.DownloadCompleteHandler
 	d.handlers.DownloadCompleteHandler = func(hash string, tread int64) {
 		if hash != MAIN_HASH {
 			return
 		}
 		item.Parts = nil
+		if item.TotalSize.IsUnknown() {
+			item.TotalSize = ContentLength(tread)
+			if parent != nil {
+				parent.addTotalSize(item.TotalSize)
+			}
+		}
 		item.Downloaded = item.TotalSize
 		m.UpdateItem(item)
 		oDCH(hash, tread)
+		go m.postDownload(d.handlers, item)
+	}
+}
+
+// postDownload extracts the completed download of the
+// item if requested, then runs the hook of the outcome.
+func (m *Manager) postDownload(h *Handlers, item *Item) {
+	if item.Extract != nil {
+		if err := m.extract(h, item); err != nil {
+			m.runHook(item, HookError, err)
+			return
+		}
 	}
+	m.runHook(item, HookComplete, nil)
+}
+
+// SetDefaultHooks sets the hooks run for the downloads
+// which don't have hooks of their own.
+func (m *Manager) SetDefaultHooks(h *Hooks) {
+	m.hooks = h
+}
+
+// markFailed records the failure of an interrupted download
+// which can't be resumed and deletes its partial content, as
+// it would otherwise be left behind as a misleading item.
+func (m *Manager) markFailed(item *Item) {
+	if item.Failure == "" {
+		item.Failure = "download was interrupted and server doesn't support resuming it"
+	}
+	_ = os.Remove(item.GetTempPath())
+	item.Downloaded = 0
+	m.UpdateItem(item)
 }
 
 func (m *Manager) encode(e any) (err error) {
@@ -217,8 +356,13 @@ type ResumeDownloadOpts struct {
 	// MaxSegments sets the maximum number of file segments
 	// to be created for the downloading the file.
 	MaxSegments int32
-	Headers     Headers
-	Handlers    *Handlers
+	// RaceTail races duplicate requests for the last
+	// segments of the file.
+	RaceTail bool
+	// SpeedLimit limits the download speed in bytes per second.
+	SpeedLimit int64
+	Headers    Headers
+	Handlers   *Handlers
 }
 
 func (m *Manager) ResumeDownload(client *http.Client, hash string, opts *ResumeDownloadOpts) (item *Item, err error) {
@@ -230,10 +374,20 @@ func (m *Manager) ResumeDownload(client *http.Client, hash string, opts *ResumeD
 		err = ErrDownloadNotFound
 		return
 	}
-	if !item.Resumable {
+	if !item.Resumable && !item.notStarted() {
 		err = ErrDownloadNotResumable
+		if item.Failure != "" {
+			err = fmt.Errorf("%w: %s", err, item.Failure)
+		}
 		return
 	}
+	if item.TotalSize.IsUnknown() {
+		// downloads of unknown size are resumed from the end
+		// of the content written to disk.
+		if fi, er := os.Stat(item.GetTempPath()); er == nil {
+			item.Downloaded = ContentLength(fi.Size())
+		}
+	}
 	if item.Headers == nil {
 		item.Headers = make(Headers, 0)
 	}
@@ -255,17 +409,93 @@ func (m *Manager) ResumeDownload(client *http.Client, hash string, opts *ResumeD
 		FileName:          item.Name,
 		DownloadDirectory: item.DownloadLocation,
 		Headers:           item.Headers,
+		RaceTail:          opts.RaceTail,
+		SpeedLimit:        opts.SpeedLimit,
+		DecodeContent:     item.DecodeContent,
 	})
 	if er != nil {
 		err = er
 		return
 	}
+	d.tmpPath = item.TempPath
+	d.ranged = item.isRanged()
+	d.rangeStart = item.RangeStart
+	d.effectiveUrl = item.EffectiveUrl
+	d.redirects = item.RedirectChain
 	m.patchHandlers(d, item)
 	item.dAlloc = d
 	// m.UpdateItem(item)
 	return
 }
 
+// SetSchedule restricts when the item with the hash is downloaded,
+// a nil schedule removes the restrictions.
+func (m *Manager) SetSchedule(hash string, s *Schedule) (item *Item, err error) {
+	item = m.GetItem(hash)
+	if item == nil {
+		err = ErrDownloadNotFound
+		return
+	}
+	if s != nil && len(s.Windows) != 0 && !item.Resumable {
+		// pausing at the end of a window would fail it
+		err = ErrScheduleNotResumable
+		return
+	}
+	item.Schedule = s
+	m.UpdateItem(item)
+	return
+}
+
+// RefreshUrl replaces the url of an incomplete download with a
+// fresh one, useful for expired signed urls. The new url is
+// probed and must serve a file of identical size (and ETag, if
+// known) for the already downloaded parts to stay valid.
+func (m *Manager) RefreshUrl(client *http.Client, hash, url string, headers Headers) (item *Item, err error) {
+	item = m.GetItem(hash)
+	if item == nil {
+		err = ErrDownloadNotFound
+		return
+	}
+	if item.dAlloc != nil && item.dAlloc.IsRunning() {
+		err = ErrRefreshItemDownloading
+		return
+	}
+	if headers == nil {
+		headers = item.Headers
+	}
+	var rangeStart, rangeEnd int64
+	if item.isRanged() {
+		rangeStart, rangeEnd = item.RangeStart, item.RangeStart+item.TotalSize.v()
+	}
+	d, err := NewDownloader(client, url, &DownloaderOpts{
+		Headers:    headers,
+		SkipSetup:  true,
+		RangeStart: rangeStart,
+		RangeEnd:   rangeEnd,
+	})
+	if err != nil {
+		return
+	}
+	if d.contentLength != item.TotalSize {
+		err = fmt.Errorf("%w: expected %d bytes, found %d bytes", ErrRefreshSizeMismatch, item.TotalSize, d.contentLength)
+		return
+	}
+	if item.ETag != "" && d.etag != "" && item.ETag != d.etag {
+		err = fmt.Errorf("%w: expected %s, found %s", ErrRefreshETagMismatch, item.ETag, d.etag)
+		return
+	}
+	item.mu.Lock()
+	item.Url = url
+	item.EffectiveUrl = d.effectiveUrl
+	item.RedirectChain = d.redirects
+	if item.ETag == "" {
+		item.ETag = d.etag
+	}
+	item.mu.Unlock()
+	m.UpdateItem(item)
+	return
+}
+
 func (m *Manager) Flush() {
 	// add a write lock to prevent data modification while flushing
 	m.mu.Lock()
@@ -276,6 +506,7 @@ func (m *Manager) Flush() {
 		}
 		delete(m.items, hash)
 		_ = os.RemoveAll(GetPath(DlDataDir, hash))
+		removeTempFile(item)
 	}
 	m.f.Seek(0, 0)
 	gob.NewEncoder(m.f).Encode(m.items)
@@ -294,9 +525,19 @@ func (m *Manager) FlushOne(hash string) error {
 	}
 	m.deleteItem(hash)
 	m.encode(m.items)
+	removeTempFile(item)
 	return os.RemoveAll(GetPath(DlDataDir, hash))
 }
 
+// removeTempFile deletes the temporary file of an
+// incomplete item.
+func removeTempFile(item *Item) {
+	if item.TotalSize == item.Downloaded || item.TempPath == "" {
+		return
+	}
+	_ = os.Remove(item.TempPath)
+}
+
 func (m *Manager) Close() error {
 	return m.f.Close()
 }
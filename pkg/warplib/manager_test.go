@@ -0,0 +1,98 @@
+package warplib
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+func newTestManager(t *testing.T) *Manager {
+	t.Helper()
+	f, err := os.Create(filepath.Join(t.TempDir(), "userdata.warp"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { f.Close() })
+	return &Manager{
+		items: make(ItemsMap),
+		f:     f,
+		mu:    new(sync.RWMutex),
+	}
+}
+
+func TestManager_RefreshUrl(t *testing.T) {
+	content := []byte("refreshed content")
+	srv := newTestServer(t, content)
+	m := newTestManager(t)
+	item, err := newItem(m.mu, "file.bin", "http://expired.invalid/file.bin", ".", "abcd", ContentLength(len(content)), true, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.UpdateItem(item)
+
+	if _, err = m.RefreshUrl(srv.Client(), "dcba", srv.URL+"/file.bin", nil); !errors.Is(err, ErrDownloadNotFound) {
+		t.Errorf("RefreshUrl() error = %v, want %v", err, ErrDownloadNotFound)
+	}
+	item.TotalSize++
+	if _, err = m.RefreshUrl(srv.Client(), "abcd", srv.URL+"/file.bin", nil); !errors.Is(err, ErrRefreshSizeMismatch) {
+		t.Errorf("RefreshUrl() error = %v, want %v", err, ErrRefreshSizeMismatch)
+	}
+	item.TotalSize--
+	if _, err = m.RefreshUrl(srv.Client(), "abcd", srv.URL+"/file.bin", nil); err != nil {
+		t.Fatalf("RefreshUrl() error = %v", err)
+	}
+	if got := m.GetItem("abcd").Url; got != srv.URL+"/file.bin" {
+		t.Errorf("Item.Url = %v, want %v", got, srv.URL+"/file.bin")
+	}
+}
+
+func TestManager_RefreshUrlRangeAtStart(t *testing.T) {
+	content := []byte("refreshed content of a ranged download")
+	srv := newTestServer(t, content)
+	m := newTestManager(t)
+	// first 9 bytes of the file
+	item, err := newItem(m.mu, "file.bin", "http://expired.invalid/file.bin", ".", "abcd", 9, true, &itemOpts{
+		Ranged: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.UpdateItem(item)
+
+	if _, err = m.RefreshUrl(srv.Client(), "abcd", srv.URL+"/file.bin", nil); err != nil {
+		t.Fatalf("RefreshUrl() error = %v", err)
+	}
+	if got := m.GetItem("abcd").Url; got != srv.URL+"/file.bin" {
+		t.Errorf("Item.Url = %v, want %v", got, srv.URL+"/file.bin")
+	}
+}
+
+func TestManager_UpdateTags(t *testing.T) {
+	m := newTestManager(t)
+	item, err := newItem(m.mu, "file.bin", "http://example.com/file.bin", ".", "abcd", 1, true, &itemOpts{
+		Tags: mergeTags(nil, "work", " Work ", ""),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.UpdateItem(item)
+
+	if _, err = m.UpdateTags("dcba", []string{"x"}, nil); !errors.Is(err, ErrDownloadNotFound) {
+		t.Errorf("UpdateTags() error = %v, want %v", err, ErrDownloadNotFound)
+	}
+	item, err = m.UpdateTags("abcd", []string{"iso", "WORK", "lab"}, []string{"Iso", "missing"})
+	if err != nil {
+		t.Fatalf("UpdateTags() error = %v", err)
+	}
+	if len(item.Tags) != 2 || item.Tags[0] != "work" || item.Tags[1] != "lab" {
+		t.Errorf("Item.Tags = %v, want [work lab]", item.Tags)
+	}
+	if !item.HasTag("LAB") || item.HasTag("iso") {
+		t.Errorf("Item.HasTag() mismatch for tags %v", item.Tags)
+	}
+	if item, err = m.SetNote("abcd", "for the lab"); err != nil || item.Note != "for the lab" {
+		t.Errorf("SetNote() = %q, %v", item.Note, err)
+	}
+}
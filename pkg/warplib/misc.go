@@ -3,7 +3,6 @@ package warplib
 import (
 	"fmt"
 	"log"
-	"mime"
 	"net/http"
 	"os"
 	"runtime"
@@ -24,9 +23,13 @@ const (
 )
 
 const (
-	DEF_MAX_CONNS  = 1
-	DEF_CHUNK_SIZE = 32 * KB
-	DEF_USER_AGENT = "Warp/1.0"
+	DEF_MAX_CONNS   = 1
+	DEF_CHUNK_SIZE  = 32 * KB
+	DEF_USER_AGENT  = "Warp/1.0"
+	DEF_TEMP_SUFFIX = ".warp.part"
+	// DEF_BASE_PARTS is the number of initial parts, the
+	// connection tuner adjusts it once download starts.
+	DEF_BASE_PARTS = 4
 
 	MIN_PART_SIZE = 512 * KB
 )
@@ -53,12 +56,12 @@ func getSpeed(op func() error) (te time.Duration, err error) {
 	return
 }
 
+// parseFileName extracts the file name from Content-Disposition
+// header, falling back to the last element of the request's url
+// path. req should be the final request made after redirects.
 func parseFileName(req *http.Request, cd string) (fn string) {
 	if cd != "" {
-		_, p, err := mime.ParseMediaType(cd)
-		if err == nil {
-			fn = p["filename"]
-		}
+		fn = parseContentDisposition(cd)
 	}
 	if fn != "" {
 		return
@@ -93,6 +96,11 @@ func dirExists(name string) bool {
 	return !os.IsNotExist(err)
 }
 
+func fileExists(name string) bool {
+	_, err := os.Stat(name)
+	return err == nil
+}
+
 func Place[t any](src []t, e t, index int) (dst []t) {
 	dst = make([]t, len(src)+1)
 	var o int
@@ -31,6 +31,22 @@ func Test_parseFileName(t *testing.T) {
 			},
 			wantFn: "world.jpg",
 		},
+		{
+			name: "RFC 5987 Content Disposition",
+			args: args{
+				req: &http.Request{URL: &url.URL{Path: "hello/world.jpg"}},
+				cd:  `attachment; filename="fallback.jpg"; filename*=UTF-8''%E2%82%AC%20rates.jpg`,
+			},
+			wantFn: "€ rates.jpg",
+		},
+		{
+			name: "Malformed Content Disposition",
+			args: args{
+				req: &http.Request{URL: &url.URL{Path: "hello/world.jpg"}},
+				cd:  `attachment; filename=my file%20name.zip; size=20`,
+			},
+			wantFn: "my file name.zip",
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
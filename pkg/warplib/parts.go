@@ -5,6 +5,7 @@ import (
 	"crypto/rand"
 	"encoding/hex"
 	"errors"
+	"fmt"
 	"io"
 	"log"
 	"net/http"
@@ -18,8 +19,12 @@ import (
 
 type Part struct {
 	ctx context.Context
+	// cancels requests of this part only
+	cancel context.CancelFunc
 	// URL
 	url string
+	// original URL to fall back to if url expires
+	ourl string
 	// size of a bytes chunk to be used for copying
 	chunk int64
 	// unique hash for this part
@@ -40,6 +45,11 @@ type Part struct {
 	pf *os.File
 	// offset of part
 	offset int64
+	// final offset of part, it shrinks if the tail of
+	// the part is stolen by another connection.
+	foff int64
+	// part racing for the same bytes, if any.
+	rival *Part
 	// expected speed
 	etime time.Duration
 	// logger
@@ -47,6 +57,13 @@ type Part struct {
 	pwg sync.WaitGroup
 	// main download file
 	f *os.File
+	// pending split requests of the downloader
+	splitReq *int32
+	// offset of the downloaded window in the remote
+	// file, added to offsets of range requests.
+	rbase int64
+	// speed limiter of the downloader
+	limiter *speedLimiter
 }
 
 type partArgs struct {
@@ -59,22 +76,32 @@ type partArgs struct {
 	logger    *log.Logger
 	offset    int64
 	f         *os.File
+	ourl      string
+	splitReq  *int32
+	rbase     int64
+	limiter   *speedLimiter
 }
 
 func initPart(ctx context.Context, client *http.Client, hash, url string, args partArgs) (*Part, error) {
+	ctx, cancel := context.WithCancel(ctx)
 	p := Part{
-		ctx:     ctx,
-		url:     url,
-		client:  client,
-		chunk:   args.copyChunk,
-		preName: args.preName,
-		pfunc:   args.pHandler,
-		ofunc:   args.oHandler,
-		cfunc:   args.cpHandler,
-		l:       args.logger,
-		offset:  args.offset,
-		hash:    hash,
-		f:       args.f,
+		ctx:      ctx,
+		cancel:   cancel,
+		url:      url,
+		client:   client,
+		chunk:    args.copyChunk,
+		preName:  args.preName,
+		pfunc:    args.pHandler,
+		ofunc:    args.oHandler,
+		cfunc:    args.cpHandler,
+		l:        args.logger,
+		offset:   args.offset,
+		hash:     hash,
+		f:        args.f,
+		ourl:     args.ourl,
+		splitReq: args.splitReq,
+		rbase:    args.rbase,
+		limiter:  args.limiter,
 	}
 	err := p.openPartFile()
 	if err != nil {
@@ -88,18 +115,24 @@ func initPart(ctx context.Context, client *http.Client, hash, url string, args p
 }
 
 func newPart(ctx context.Context, client *http.Client, url string, args partArgs) (*Part, error) {
+	ctx, cancel := context.WithCancel(ctx)
 	p := Part{
-		ctx:     ctx,
-		url:     url,
-		client:  client,
-		chunk:   args.copyChunk,
-		preName: args.preName,
-		pfunc:   args.pHandler,
-		ofunc:   args.oHandler,
-		cfunc:   args.cpHandler,
-		l:       args.logger,
-		offset:  args.offset,
-		f:       args.f,
+		ctx:      ctx,
+		cancel:   cancel,
+		url:      url,
+		client:   client,
+		chunk:    args.copyChunk,
+		preName:  args.preName,
+		pfunc:    args.pHandler,
+		ofunc:    args.oHandler,
+		cfunc:    args.cpHandler,
+		l:        args.logger,
+		offset:   args.offset,
+		f:        args.f,
+		ourl:     args.ourl,
+		splitReq: args.splitReq,
+		rbase:    args.rbase,
+		limiter:  args.limiter,
 	}
 	p.setHash()
 	return &p, p.createPartFile()
@@ -110,61 +143,68 @@ func (p *Part) setEpeed(espeed int64) {
 }
 
 func (p *Part) download(headers Headers, ioff, foff int64, force bool) (body io.ReadCloser, slow bool, err error) {
-	req, er := http.NewRequestWithContext(p.ctx, http.MethodGet, p.url, nil)
-	if er != nil {
-		err = er
-		return
-	}
-	header := req.Header
-	headers.Set(header)
-	if foff != -1 {
-		setRange(header, ioff, foff)
-	} else {
+	roff, rfoff := p.rbase+ioff, p.rbase+foff
+	if foff == -1 {
+		rfoff = -1
 		force = true
 	}
-	resp, er := p.client.Do(req)
+	// whole file in response would corrupt the window.
+	resp, url, er := getRange(p.ctx, p.client, headers, p.url, p.ourl, roff, rfoff, p.rbase == 0)
+	if url != p.url {
+		p.log("%s: effective url rejected, falling back to original url", p.hash)
+		p.url = url
+	}
 	if er != nil {
+		if p.truncated() {
+			p.ofunc(p.hash, p.read)
+			return
+		}
 		err = er
 		return
 	}
-	slow, err = p.copyBuffer(resp.Body, foff, force)
+	slow, err = p.copyBuffer(resp.Body, force)
 	body = resp.Body
 	return
 }
 
-func (p *Part) copyBuffer(src io.ReadCloser, foff int64, force bool) (slow bool, err error) {
-	var (
-		// number of bytes this part should read
-		tread  = foff + 1 - p.offset
-		chunk  = p.chunk
-		lchunk = tread - p.read
-	)
-	if lchunk > 0 && lchunk < chunk {
-		chunk = lchunk
-	}
+func (p *Part) copyBuffer(src io.ReadCloser, force bool) (slow bool, err error) {
 	var (
-		buf = make([]byte, chunk)
+		buf = make([]byte, p.chunk)
 		n   int
 	)
 	for {
+		// number of bytes left to be read, it is computed
+		// on every iteration as final offset of the part
+		// can shrink while it is being downloaded.
+		lchunk := p.remaining()
+		if lchunk <= 0 {
+			err = io.EOF
+			break
+		}
+		if lchunk < int64(len(buf)) {
+			buf = buf[:lchunk]
+		}
 		n++
-		slow, err = p.copyBufferChunkWithTime(src, p.pf, buf, !force && n%10 == 0)
+		// limited parts are slow on purpose and aren't split.
+		timed := !force && n%10 == 0 && !p.limiter.active()
+		slow, err = p.copyBufferChunkWithTime(src, p.pf, buf, timed)
 		if err != nil {
+			if p.truncated() {
+				// part was cancelled after its rival
+				// finished downloading the same bytes.
+				err = io.EOF
+			}
 			break
 		}
 		if slow {
 			return
 		}
-		lchunk = tread - p.read
-		if lchunk == 0 {
-			err = io.EOF
-			break
-		}
-		if lchunk < 1 {
-			panic("MYSTERIOUS CORRUPTION! Report to github.com/warpdl/warpdl")
-		}
-		if lchunk < chunk {
-			buf = make([]byte, lchunk)
+		if !force && lchunk > 2*MIN_PART_SIZE && p.splitReq != nil && takeSplitRequest(p.splitReq) {
+			// report the part as slow so that it gets
+			// split to make use of an added connection.
+			p.log("%s: split requested by connection tuner", p.hash)
+			slow = true
+			return
 		}
 	}
 	// wait for all part progress to be sent via progress handlers
@@ -173,7 +213,6 @@ func (p *Part) copyBuffer(src io.ReadCloser, foff int64, force bool) (slow bool,
 	if err == io.EOF {
 		err = nil
 		p.log("%s: part download complete", p.hash)
-		// fmt.Print("[", p.hash, "]: ", "lchunk: ", tread-p.read, " p.read: ", p.read, " ioff: ", p.offset, " foff: ", foff, " p.chunk: ", p.chunk, " n: ", n, "\n")
 		p.ofunc(p.hash, p.read)
 	}
 	return
@@ -198,6 +237,7 @@ func (p *Part) copyBufferChunkWithTime(src io.Reader, dst io.Writer, buf []byte,
 
 func (p *Part) copyBufferChunk(src io.Reader, dst io.Writer, buf []byte) (err error) {
 	nr, er := src.Read(buf)
+	p.limiter.wait(p.ctx, nr)
 	if nr > 0 {
 		nw, ew := dst.Write(buf[0:nr])
 		if nw < 0 || nr < nw {
@@ -229,10 +269,13 @@ func (p *Part) compile() (read, written int64, err error) {
 	// take the reader to origin from end
 	p.pf.Seek(0, 0)
 
+	// bytes read past the final offset belong to
+	// another part and are not compiled.
+	src := io.LimitReader(p.pf, max(p.getFoff()+1-p.offset, 0))
 	buf := make([]byte, p.chunk)
 	off := p.offset
 	for {
-		nr, er := p.pf.Read(buf)
+		nr, er := src.Read(buf)
 		atomic.AddInt64(&read, int64(nr))
 		if nr > 0 {
 			nw, ew := p.f.WriteAt(buf[0:nr], off)
@@ -269,6 +312,40 @@ func (p *Part) compile() (read, written int64, err error) {
 	return
 }
 
+// getRange requests the content of url from ioff to foff, all of
+// it if foff is -1. A resolved url might expire, hence if url is
+// rejected with 403 or 410 the original url ourl is requested
+// instead, rurl is the url requested last. Whole content in
+// response to a range request is only accepted if whole is set.
+func getRange(ctx context.Context, client *http.Client, headers Headers, url, ourl string, ioff, foff int64, whole bool) (resp *http.Response, rurl string, err error) {
+	rurl = url
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return
+	}
+	headers.Set(req.Header)
+	if foff != -1 {
+		setRange(req.Header, ioff, foff)
+	}
+	resp, err = client.Do(req)
+	if err != nil {
+		return
+	}
+	switch code := resp.StatusCode; {
+	case (code == http.StatusForbidden || code == http.StatusGone) && ourl != "" && url != ourl:
+		resp.Body.Close()
+		return getRange(ctx, client, headers, ourl, ourl, ioff, foff, whole)
+	case code >= 400:
+		err = fmt.Errorf("%w: %s", ErrUnexpectedStatus, resp.Status)
+	case foff != -1 && !whole && code != http.StatusPartialContent:
+		err = fmt.Errorf("%w: server ignored range request (%s)", ErrRangeNotSupported, resp.Status)
+	default:
+		return
+	}
+	resp.Body.Close()
+	return nil, rurl, err
+}
+
 func setRange(header http.Header, ioff, foff int64) {
 	str := func(i int64) string {
 		return strconv.FormatInt(i, 10)
@@ -311,11 +388,36 @@ func (p *Part) seek(rpFunc ResumeProgressHandlerFunc) (err error) {
 	return
 }
 
+func (p *Part) getFoff() int64 {
+	return atomic.LoadInt64(&p.foff)
+}
+
+func (p *Part) setFoff(foff int64) {
+	atomic.StoreInt64(&p.foff, foff)
+}
+
+// position returns the offset of the next byte to be read.
+func (p *Part) position() int64 {
+	return p.offset + atomic.LoadInt64(&p.read)
+}
+
+// remaining returns the number of bytes left to be read.
+func (p *Part) remaining() int64 {
+	return p.getFoff() + 1 - p.position()
+}
+
+// truncated reports whether the part was cancelled because
+// its remaining bytes were downloaded by a rival.
+func (p *Part) truncated() bool {
+	return p.remaining() <= 0 && p.ctx.Err() != nil
+}
+
 func (p *Part) getFileName() string {
 	return getFileName(p.preName, p.hash)
 }
 
 func (p *Part) close() error {
+	p.cancel()
 	return p.pf.Close()
 }
 
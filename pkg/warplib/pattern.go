@@ -0,0 +1,118 @@
+package warplib
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// DEF_MAX_PATTERN_URLS is the maximum number of urls
+// a url pattern is allowed to expand to.
+const DEF_MAX_PATTERN_URLS = 10000
+
+// ExpandUrl expands numeric sequences and brace alternatives
+// in the path and query of the url:
+//
+//	"[1-10]"     1, 2, ..., 10
+//	"[001-250]"  001, 002, ..., 250 (zero padded to the width of the start)
+//	"[0-100:10]" 0, 10, ..., 100
+//	"{a,b,c}"    a, b, c
+//
+// Multiple patterns expand to every combination, leftmost
+// pattern changing the slowest. A url without patterns is
+// returned as is.
+func ExpandUrl(url string) ([]string, error) {
+	// brackets of ipv6 hosts aren't patterns
+	var host string
+	if i := strings.Index(url, "://"); i != -1 {
+		j := strings.IndexByte(url[i+3:], '/')
+		if j == -1 {
+			return []string{url}, nil
+		}
+		host, url = url[:i+3+j], url[i+3+j:]
+	}
+	urls := []string{host}
+	for url != "" {
+		i := strings.IndexAny(url, "[{")
+		if i == -1 {
+			urls = appendAll(urls, url)
+			break
+		}
+		urls = appendAll(urls, url[:i])
+		closing := "]"
+		if url[i] == '{' {
+			closing = "}"
+		}
+		j := strings.Index(url[i:], closing)
+		if j == -1 {
+			return nil, fmt.Errorf("%w: unclosed %q", ErrInvalidUrlPattern, url[i])
+		}
+		alts, err := expandPattern(url[i : i+j+1])
+		if err != nil {
+			return nil, err
+		}
+		if len(urls)*len(alts) > DEF_MAX_PATTERN_URLS {
+			return nil, fmt.Errorf("%w: expands to more than %d urls", ErrInvalidUrlPattern, DEF_MAX_PATTERN_URLS)
+		}
+		next := make([]string, 0, len(urls)*len(alts))
+		for _, u := range urls {
+			for _, a := range alts {
+				next = append(next, u+a)
+			}
+		}
+		urls = next
+		url = url[i+j+1:]
+	}
+	return urls, nil
+}
+
+func appendAll(urls []string, s string) []string {
+	for i := range urls {
+		urls[i] += s
+	}
+	return urls
+}
+
+// expandPattern expands a single "[...]" or "{...}" pattern.
+func expandPattern(p string) ([]string, error) {
+	body := p[1 : len(p)-1]
+	if p[0] == '{' {
+		alts := strings.Split(body, ",")
+		if len(alts) < 2 {
+			return nil, fmt.Errorf("%w: %q needs at least two alternatives", ErrInvalidUrlPattern, p)
+		}
+		return alts, nil
+	}
+	seq, sstep, hasStep := strings.Cut(body, ":")
+	first, last, ok := strings.Cut(seq, "-")
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrInvalidUrlPattern, p)
+	}
+	start, err := strconv.ParseInt(first, 10, 64)
+	if err != nil || start < 0 {
+		return nil, fmt.Errorf("%w: %q", ErrInvalidUrlPattern, p)
+	}
+	end, err := strconv.ParseInt(last, 10, 64)
+	if err != nil || end < start {
+		return nil, fmt.Errorf("%w: %q", ErrInvalidUrlPattern, p)
+	}
+	var step int64 = 1
+	if hasStep {
+		step, err = strconv.ParseInt(sstep, 10, 64)
+		if err != nil || step <= 0 {
+			return nil, fmt.Errorf("%w: %q", ErrInvalidUrlPattern, p)
+		}
+	}
+	if (end-start)/step >= DEF_MAX_PATTERN_URLS {
+		return nil, fmt.Errorf("%w: expands to more than %d urls", ErrInvalidUrlPattern, DEF_MAX_PATTERN_URLS)
+	}
+	var width int
+	if len(first) > 1 && first[0] == '0' {
+		width = len(first)
+	}
+	var alts []string
+	for n := start; n <= end; n += step {
+		alts = append(alts, fmt.Sprintf("%0*d", width, n))
+	}
+	return alts, nil
+}
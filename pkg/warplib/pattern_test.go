@@ -0,0 +1,38 @@
+package warplib
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestExpandUrl(t *testing.T) {
+	tests := []struct {
+		url     string
+		want    []string
+		wantErr error
+	}{
+		{"http://x/file.bin", []string{"http://x/file.bin"}, nil},
+		{"http://x/part-[8-10].tar", []string{"http://x/part-8.tar", "http://x/part-9.tar", "http://x/part-10.tar"}, nil},
+		{"http://x/part-[008-010].tar", []string{"http://x/part-008.tar", "http://x/part-009.tar", "http://x/part-010.tar"}, nil},
+		{"http://x/[0-10:5]", []string{"http://x/0", "http://x/5", "http://x/10"}, nil},
+		{"http://x/img{a,b}[1-2].png", []string{"http://x/imga1.png", "http://x/imga2.png", "http://x/imgb1.png", "http://x/imgb2.png"}, nil},
+		{"http://[::1]:8080/file.bin", []string{"http://[::1]:8080/file.bin"}, nil},
+		{"http://x/[1-2", nil, ErrInvalidUrlPattern},
+		{"http://x/[a-z]", nil, ErrInvalidUrlPattern},
+		{"http://x/[5-1]", nil, ErrInvalidUrlPattern},
+		{"http://x/{a}", nil, ErrInvalidUrlPattern},
+		{"http://x/[1-100000]", nil, ErrInvalidUrlPattern},
+	}
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			got, err := ExpandUrl(tt.url)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("ExpandUrl() error = %v, want %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ExpandUrl() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
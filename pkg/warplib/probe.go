@@ -0,0 +1,155 @@
+package warplib
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ProbeResult holds the information about a remote file
+// retrieved before downloading it.
+type ProbeResult struct {
+	// Method of the request which the result was
+	// retrieved from, HEAD or GET.
+	Method string
+	// StatusCode of the probe response.
+	StatusCode int
+	// Header of the probe response.
+	Header http.Header
+	// Url is the probed url.
+	Url string
+	// EffectiveUrl is the url the response was served
+	// from after following redirects.
+	EffectiveUrl string
+	// Redirects holds the urls visited while resolving Url,
+	// excluding the EffectiveUrl.
+	Redirects []string
+	// ContentLength is the size of the remote file,
+	// -1 if it is unknown.
+	ContentLength ContentLength
+	// RangesSupported reports whether server accepts
+	// byte range requests.
+	RangesSupported bool
+	// ContentEncoding of the response, empty if content
+	// isn't encoded.
+	ContentEncoding string
+	// Speed sample in bytes per second measured while
+	// receiving the GET probe's body, 0 if not measured.
+	Speed int64
+
+	// final request after redirects
+	request *http.Request
+	// content received from offset 0 with a ranged
+	// GET probe, reused as beginning of the first part.
+	head []byte
+}
+
+// Probe retrieves information about the remote file at url while
+// transferring as little as possible. A HEAD request is made first
+// and a ranged GET request (Range: bytes=0-0) is used as fallback
+// for servers which don't handle HEAD requests properly or don't
+// advertise range support in response to them.
+func Probe(client *http.Client, url string, headers Headers) (*ProbeResult, error) {
+	return probe(client, url, headers, 1)
+}
+
+// probe is like Probe but the fallback GET request asks for sample
+// number of bytes which are used to measure the speed.
+func probe(client *http.Client, url string, headers Headers, sample int64) (p *ProbeResult, err error) {
+	head, err := probeRequest(client, http.MethodHead, url, headers, 0)
+	usable := err == nil && head.StatusCode < 400 && head.ContentLength > 0
+	if usable && head.RangesSupported {
+		return head, nil
+	}
+	// some servers don't support HEAD requests, send a
+	// different response for them (e.g. urls signed for
+	// GET requests only), omit the content length or
+	// don't advertise ranges they accept.
+	p, err = probeRequest(client, http.MethodGet, url, headers, max(sample, 1))
+	if err != nil && usable {
+		return head, nil
+	}
+	return
+}
+
+func probeRequest(client *http.Client, method, url string, headers Headers, sample int64) (p *ProbeResult, err error) {
+	req, err := http.NewRequest(method, url, nil)
+	if err != nil {
+		return
+	}
+	headers.Set(req.Header)
+	if sample > 0 {
+		// setRange treats final offset 0 as open ended range.
+		req.Header.Set("Range", "bytes=0-"+strconv.FormatInt(sample-1, 10))
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return
+	}
+	defer resp.Body.Close()
+	p = &ProbeResult{
+		Method:        method,
+		StatusCode:    resp.StatusCode,
+		Header:        resp.Header,
+		Url:           url,
+		EffectiveUrl:  resp.Request.URL.String(),
+		Redirects:     redirectChain(resp),
+		ContentLength: ContentLength(resp.ContentLength),
+		request:       resp.Request,
+	}
+	if enc := resp.Header.Get(CONTENT_ENCODING_KEY); isEncoded(enc) {
+		p.ContentEncoding = enc
+	}
+	switch {
+	case resp.StatusCode == http.StatusPartialContent:
+		p.RangesSupported = true
+		// content length of a partial response is the
+		// length of the range.
+		p.ContentLength = ContentLength(contentRangeTotal(resp))
+	case method == http.MethodHead:
+		p.RangesSupported = strings.EqualFold(resp.Header.Get("Accept-Ranges"), "bytes")
+	}
+	if method != http.MethodGet || resp.StatusCode >= 400 {
+		return
+	}
+	buf := make([]byte, sample)
+	var n int
+	te, er := getSpeed(func() (err error) {
+		n, err = io.ReadFull(resp.Body, buf)
+		if errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, io.EOF) {
+			err = nil
+		}
+		return
+	})
+	if er != nil {
+		// the probe has served its purpose even if the
+		// sample couldn't be read.
+		return
+	}
+	p.Speed = getSpeedSample(int64(n), te)
+	if p.RangesSupported {
+		p.head = buf[:n]
+	}
+	return
+}
+
+// getSpeedSample returns the speed in bytes per second for n
+// bytes transferred in te duration.
+func getSpeedSample(n int64, te time.Duration) int64 {
+	if te <= 0 {
+		te = 1
+	}
+	return n * _SECOND / int64(te)
+}
+
+// redirectChain returns the urls visited before resp was
+// received, excluding the final url.
+func redirectChain(resp *http.Response) (chain []string) {
+	for r := resp.Request; r.Response != nil; r = r.Response.Request {
+		chain = append([]string{r.Response.Request.URL.String()}, chain...)
+	}
+	return
+}
@@ -0,0 +1,135 @@
+package warplib
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type recordedRequest struct {
+	method, rng string
+}
+
+// newProbeTestServer serves content with range support and
+// records the requests made to it.
+func newProbeTestServer(t *testing.T, content []byte, allowHead bool) (*httptest.Server, func() []recordedRequest) {
+	t.Helper()
+	var (
+		mu   sync.Mutex
+		reqs []recordedRequest
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		reqs = append(reqs, recordedRequest{r.Method, r.Header.Get("Range")})
+		mu.Unlock()
+		if r.Method == http.MethodHead && !allowHead {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		http.ServeContent(w, r, "file.bin", time.Time{}, bytes.NewReader(content))
+	}))
+	t.Cleanup(srv.Close)
+	return srv, func() []recordedRequest {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]recordedRequest(nil), reqs...)
+	}
+}
+
+func TestProbe(t *testing.T) {
+	content := bytes.Repeat([]byte("probe"), 1024)
+	tests := []struct {
+		name       string
+		allowHead  bool
+		wantMethod string
+		wantReqs   int
+	}{
+		{"head", true, http.MethodHead, 1},
+		{"get fallback", false, http.MethodGet, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv, reqs := newProbeTestServer(t, content, tt.allowHead)
+			p, err := Probe(srv.Client(), srv.URL+"/file.bin", nil)
+			if err != nil {
+				t.Fatalf("Probe() error = %v", err)
+			}
+			if p.Method != tt.wantMethod {
+				t.Errorf("Probe() method = %v, want %v", p.Method, tt.wantMethod)
+			}
+			if p.ContentLength.v() != int64(len(content)) {
+				t.Errorf("Probe() content length = %d, want %d", p.ContentLength, len(content))
+			}
+			if !p.RangesSupported {
+				t.Errorf("Probe() ranges should be supported")
+			}
+			if got := reqs(); len(got) != tt.wantReqs {
+				t.Errorf("Probe() made %d requests, want %d: %v", len(got), tt.wantReqs, got)
+			} else if tt.wantMethod == http.MethodGet && got[1].rng != "bytes=0-0" {
+				t.Errorf("Probe() fallback range = %v, want bytes=0-0", got[1].rng)
+			}
+		})
+	}
+}
+
+func TestProbe_HeadWithoutRanges(t *testing.T) {
+	content := bytes.Repeat([]byte("probe"), 1024)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodHead {
+			// range support isn't advertised
+			w.Header().Set("Content-Length", strconv.Itoa(len(content)))
+			return
+		}
+		http.ServeContent(w, r, "file.bin", time.Time{}, bytes.NewReader(content))
+	}))
+	defer srv.Close()
+	p, err := Probe(srv.Client(), srv.URL+"/file.bin", nil)
+	if err != nil {
+		t.Fatalf("Probe() error = %v", err)
+	}
+	if p.Method != http.MethodGet || !p.RangesSupported {
+		t.Errorf("Probe() = %s with ranges %v, want ranged GET fallback", p.Method, p.RangesSupported)
+	}
+	if p.ContentLength.v() != int64(len(content)) {
+		t.Errorf("Probe() content length = %d, want %d", p.ContentLength, len(content))
+	}
+}
+
+func TestDownloader_ReusesProbeHead(t *testing.T) {
+	content := bytes.Repeat([]byte("0123456789"), 64*1024)
+	srv, reqs := newProbeTestServer(t, content, false)
+	d, err := NewDownloader(srv.Client(), srv.URL+"/file.bin", &DownloaderOpts{
+		DownloadDirectory: t.TempDir(),
+		MaxConnections:    4,
+		NumBaseParts:      2,
+	})
+	if err != nil {
+		t.Fatalf("NewDownloader() error = %v", err)
+	}
+	defer os.RemoveAll(d.dlPath)
+	if p := d.GetProbeResult(); p.Method != http.MethodGet || p.Speed == 0 {
+		t.Errorf("GetProbeResult() = %+v, want a GET probe with speed sample", p)
+	}
+	if err = d.Start(); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	// skip the HEAD and GET probes
+	for _, r := range reqs()[2:] {
+		if strings.HasPrefix(r.rng, "bytes=0-") {
+			t.Errorf("part 0 re-requested the probed content: %v", r.rng)
+		}
+	}
+	b, err := os.ReadFile(d.GetSavePath())
+	if err != nil {
+		t.Fatalf("ReadFile() error = %v", err)
+	}
+	if !bytes.Equal(b, content) {
+		t.Errorf("downloaded content mismatch: got %d bytes, want %d", len(b), len(content))
+	}
+}
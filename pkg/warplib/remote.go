@@ -0,0 +1,326 @@
+package warplib
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+)
+
+const (
+	// DEF_REMOTE_BLOCK_SIZE is the size of blocks a RemoteFile
+	// fetches and caches.
+	DEF_REMOTE_BLOCK_SIZE = 256 * KB
+	// DEF_REMOTE_CACHE_BLOCKS is the number of blocks
+	// a RemoteFile keeps in memory.
+	DEF_REMOTE_CACHE_BLOCKS = 64
+	// DEF_REMOTE_READ_AHEAD is the number of blocks fetched
+	// ahead of sequential reads.
+	DEF_REMOTE_READ_AHEAD = 4
+	// DEF_REMOTE_CONNECTIONS is the maximum number of parallel
+	// connections of a RemoteFile.
+	DEF_REMOTE_CONNECTIONS = 4
+)
+
+// Optional fields of RemoteFile
+type RemoteFileOpts struct {
+	Headers Headers
+	// BlockSize is the size of each ranged request.
+	BlockSize int64
+	// CacheBlocks is the maximum number of blocks
+	// kept in memory, least recently used blocks are
+	// evicted first.
+	CacheBlocks int
+	// ReadAhead is the number of blocks fetched in the
+	// background after a block is read sequentially,
+	// negative value disables read-ahead.
+	ReadAhead int
+	// MaxConnections limits the number of blocks
+	// fetched in parallel.
+	MaxConnections int32
+}
+
+// RemoteFile provides random access to a remote file by fetching
+// the requested ranges on demand. It implements io.ReaderAt,
+// io.ReadSeeker and io.Closer, ReadAt is safe for concurrent use.
+//
+// Example:
+//
+//	f, err := warplib.OpenRemoteFile(client, url, nil)
+//	if err != nil {
+//		return err
+//	}
+//	defer f.Close()
+//	zr, err := zip.NewReader(f, f.Size())
+type RemoteFile struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	client *http.Client
+	// url to request and the original url to
+	// fall back to if url expires.
+	url, ourl string
+	headers   Headers
+	size      int64
+	blockSize int64
+	readAhead int
+	maxBlocks int
+	// limits the number of parallel requests
+	sem chan struct{}
+
+	mu     sync.Mutex
+	blocks map[int64]*list.Element
+	// recently used blocks at front
+	lru *list.List
+	// index of the last read block, used to
+	// detect sequential reads.
+	last int64
+	// offset for Read and Seek
+	off int64
+}
+
+type remoteBlock struct {
+	idx  int64
+	data []byte
+	err  error
+	done chan struct{}
+}
+
+// OpenRemoteFile probes the file at url and returns a RemoteFile
+// for it. Size of the file has to be known and server has to
+// accept range requests.
+func OpenRemoteFile(client *http.Client, url string, opts *RemoteFileOpts) (*RemoteFile, error) {
+	if opts == nil {
+		opts = &RemoteFileOpts{}
+	}
+	if opts.Headers == nil {
+		opts.Headers = make(Headers, 0)
+	}
+	opts.Headers.InitOrUpdate(USER_AGENT_KEY, DEF_USER_AGENT)
+	// offsets are of the content as stored on server.
+	opts.Headers.Update(ACCEPT_ENCODING_KEY, ENCODING_IDENTITY)
+	if opts.BlockSize <= 0 {
+		opts.BlockSize = DEF_REMOTE_BLOCK_SIZE
+	}
+	if opts.CacheBlocks <= 0 {
+		opts.CacheBlocks = DEF_REMOTE_CACHE_BLOCKS
+	}
+	if opts.ReadAhead == 0 {
+		opts.ReadAhead = DEF_REMOTE_READ_AHEAD
+	}
+	if opts.MaxConnections <= 0 {
+		opts.MaxConnections = DEF_REMOTE_CONNECTIONS
+	}
+	p, err := Probe(client, url, opts.Headers)
+	if err != nil {
+		return nil, err
+	}
+	if p.StatusCode >= 400 {
+		return nil, fmt.Errorf("%w: %s", ErrUnexpectedStatus, http.StatusText(p.StatusCode))
+	}
+	if p.ContentLength.IsUnknown() {
+		return nil, fmt.Errorf("%w: size of the file is unknown", ErrRangeNotSupported)
+	}
+	if !p.RangesSupported {
+		return nil, fmt.Errorf("%w: server doesn't accept range requests", ErrRangeNotSupported)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	f := &RemoteFile{
+		ctx:       ctx,
+		cancel:    cancel,
+		client:    client,
+		url:       p.EffectiveUrl,
+		ourl:      url,
+		headers:   opts.Headers,
+		size:      p.ContentLength.v(),
+		blockSize: opts.BlockSize,
+		readAhead: max(opts.ReadAhead, 0),
+		maxBlocks: opts.CacheBlocks,
+		sem:       make(chan struct{}, opts.MaxConnections),
+		blocks:    make(map[int64]*list.Element),
+		lru:       list.New(),
+		last:      -1,
+	}
+	return f, nil
+}
+
+// Size returns the size of the remote file.
+func (f *RemoteFile) Size() int64 {
+	return f.size
+}
+
+// ReadAt reads len(b) bytes of the remote file starting at off.
+func (f *RemoteFile) ReadAt(b []byte, off int64) (n int, err error) {
+	if off < 0 {
+		return 0, errors.New("warplib: negative offset")
+	}
+	if off >= f.size {
+		return 0, io.EOF
+	}
+	end := min(off+int64(len(b)), f.size)
+	first, last := off/f.blockSize, (end-1)/f.blockSize
+	// request all blocks before waiting for any of
+	// them to fetch them in parallel.
+	blocks := make([]*remoteBlock, 0, last-first+1)
+	for i := first; i <= last; i++ {
+		blocks = append(blocks, f.getBlock(i))
+	}
+	f.prefetch(first, last)
+	for _, blk := range blocks {
+		select {
+		case <-blk.done:
+		case <-f.ctx.Done():
+			return n, f.ctx.Err()
+		}
+		if blk.err != nil {
+			return n, blk.err
+		}
+		n += copy(b[n:], blk.data[off+int64(n)-blk.idx*f.blockSize:])
+	}
+	if n < len(b) {
+		err = io.EOF
+	}
+	return
+}
+
+// Read reads up to len(b) bytes from the current offset.
+func (f *RemoteFile) Read(b []byte) (n int, err error) {
+	f.mu.Lock()
+	off := f.off
+	f.mu.Unlock()
+	n, err = f.ReadAt(b, off)
+	if err == io.EOF && n > 0 {
+		err = nil
+	}
+	f.mu.Lock()
+	f.off = off + int64(n)
+	f.mu.Unlock()
+	return
+}
+
+// Seek sets the offset for the next Read.
+func (f *RemoteFile) Seek(offset int64, whence int) (int64, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += f.off
+	case io.SeekEnd:
+		offset += f.size
+	default:
+		return 0, errors.New("warplib: invalid whence")
+	}
+	if offset < 0 {
+		return 0, errors.New("warplib: negative position")
+	}
+	f.off = offset
+	return offset, nil
+}
+
+// Close cancels pending requests and drops the cache.
+func (f *RemoteFile) Close() error {
+	f.cancel()
+	f.mu.Lock()
+	f.blocks = make(map[int64]*list.Element)
+	f.lru.Init()
+	f.mu.Unlock()
+	return nil
+}
+
+// prefetch fetches blocks following last in the background
+// if blocks are being read sequentially.
+func (f *RemoteFile) prefetch(first, last int64) {
+	f.mu.Lock()
+	sequential := first == f.last || first == f.last+1
+	f.last = last
+	f.mu.Unlock()
+	if !sequential {
+		return
+	}
+	nblocks := (f.size + f.blockSize - 1) / f.blockSize
+	for i := last + 1; i <= last+int64(f.readAhead) && i < nblocks; i++ {
+		f.getBlock(i)
+	}
+}
+
+// getBlock returns the block at index idx from cache or starts
+// fetching it.
+func (f *RemoteFile) getBlock(idx int64) *remoteBlock {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if e, ok := f.blocks[idx]; ok {
+		f.lru.MoveToFront(e)
+		return e.Value.(*remoteBlock)
+	}
+	blk := &remoteBlock{idx: idx, done: make(chan struct{})}
+	f.blocks[idx] = f.lru.PushFront(blk)
+	f.evict()
+	go f.fetchBlock(blk)
+	return blk
+}
+
+// evict drops least recently used blocks which aren't
+// being fetched once cache is full.
+func (f *RemoteFile) evict() {
+	for e := f.lru.Back(); e != nil && f.lru.Len() > f.maxBlocks; {
+		prev := e.Prev()
+		blk := e.Value.(*remoteBlock)
+		select {
+		case <-blk.done:
+			f.lru.Remove(e)
+			delete(f.blocks, blk.idx)
+		default:
+		}
+		e = prev
+	}
+}
+
+func (f *RemoteFile) fetchBlock(blk *remoteBlock) {
+	defer close(blk.done)
+	select {
+	case f.sem <- struct{}{}:
+		defer func() { <-f.sem }()
+	case <-f.ctx.Done():
+		blk.err = f.ctx.Err()
+		return
+	}
+	ioff := blk.idx * f.blockSize
+	foff := min(ioff+f.blockSize, f.size) - 1
+	f.mu.Lock()
+	url := f.url
+	f.mu.Unlock()
+	blk.data, blk.err = f.fetch(url, ioff, foff)
+	if blk.err == nil {
+		return
+	}
+	// don't cache failed blocks so that they are
+	// requested again on next read.
+	f.mu.Lock()
+	if e, ok := f.blocks[blk.idx]; ok && e.Value == blk {
+		f.lru.Remove(e)
+		delete(f.blocks, blk.idx)
+	}
+	f.mu.Unlock()
+}
+
+// fetch requests content from ioff to foff, it falls back to the
+// original url if the resolved one has expired like parts do.
+func (f *RemoteFile) fetch(url string, ioff, foff int64) ([]byte, error) {
+	resp, rurl, err := getRange(f.ctx, f.client, f.headers, url, f.ourl, ioff, foff, ioff == 0 && foff == f.size-1)
+	if rurl != url {
+		// the original url is used from now on.
+		f.mu.Lock()
+		f.url = rurl
+		f.mu.Unlock()
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	buf := make([]byte, foff-ioff+1)
+	_, err = io.ReadFull(resp.Body, buf)
+	return buf, err
+}
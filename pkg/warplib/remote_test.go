@@ -0,0 +1,156 @@
+package warplib
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRemoteFile_ReadAt(t *testing.T) {
+	content := make([]byte, 3*MB+123)
+	for i := range content {
+		content[i] = byte(i * 7)
+	}
+	var requests int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			atomic.AddInt32(&requests, 1)
+		}
+		http.ServeContent(w, r, "file.bin", time.Time{}, bytes.NewReader(content))
+	}))
+	defer srv.Close()
+	f, err := OpenRemoteFile(srv.Client(), srv.URL, &RemoteFileOpts{
+		BlockSize:   64 * KB,
+		CacheBlocks: 8,
+		ReadAhead:   -1,
+	})
+	if err != nil {
+		t.Fatalf("OpenRemoteFile() error = %v", err)
+	}
+	defer f.Close()
+	if f.Size() != int64(len(content)) {
+		t.Fatalf("Size() = %d, want %d", f.Size(), len(content))
+	}
+	for _, off := range []int64{0, 100, 64*KB - 10, MB + 5, int64(len(content)) - 50} {
+		b := make([]byte, 100)
+		n, err := f.ReadAt(b, off)
+		want := content[off:min(off+100, int64(len(content)))]
+		if n != len(want) || !bytes.Equal(b[:n], want) {
+			t.Errorf("ReadAt(%d) = %d bytes, want %d matching bytes", off, n, len(want))
+		}
+		if n < len(b) && err != io.EOF {
+			t.Errorf("ReadAt(%d) error = %v, want EOF", off, err)
+		}
+	}
+	before := atomic.LoadInt32(&requests)
+	if _, err = f.ReadAt(make([]byte, 10), 200); err != nil {
+		t.Fatalf("ReadAt() error = %v", err)
+	}
+	if got := atomic.LoadInt32(&requests); got != before {
+		t.Errorf("cached block requested again: %d requests, want %d", got, before)
+	}
+	// sequential reads through Read
+	if _, err = f.Seek(0, io.SeekStart); err != nil {
+		t.Fatal(err)
+	}
+	b, err := io.ReadAll(f)
+	if err != nil {
+		t.Fatalf("ReadAll() error = %v", err)
+	}
+	if !bytes.Equal(b, content) {
+		t.Errorf("ReadAll() = %d bytes, want %d matching bytes", len(b), len(content))
+	}
+}
+
+func TestRemoteFile_Zip(t *testing.T) {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, name := range []string{"a.txt", "b.txt"} {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write(bytes.Repeat([]byte(name), 100*1024))
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	srv := newTestServer(t, buf.Bytes())
+	f, err := OpenRemoteFile(srv.Client(), srv.URL, &RemoteFileOpts{BlockSize: 32 * KB})
+	if err != nil {
+		t.Fatalf("OpenRemoteFile() error = %v", err)
+	}
+	defer f.Close()
+	zr, err := zip.NewReader(f, f.Size())
+	if err != nil {
+		t.Fatalf("zip.NewReader() error = %v", err)
+	}
+	rc, err := zr.File[1].Open()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rc.Close()
+	b, err := io.ReadAll(rc)
+	if err != nil {
+		t.Fatalf("ReadAll() error = %v", err)
+	}
+	if !bytes.Equal(b, bytes.Repeat([]byte("b.txt"), 100*1024)) {
+		t.Errorf("content of %s mismatch", zr.File[1].Name)
+	}
+}
+
+func TestOpenRemoteFile_RangesNotSupported(t *testing.T) {
+	content := bytes.Repeat([]byte("norange"), 1024)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// ignores the range header
+		w.Header().Set("Content-Length", strconv.Itoa(len(content)))
+		w.Write(content)
+	}))
+	defer srv.Close()
+	if _, err := OpenRemoteFile(srv.Client(), srv.URL, nil); !errors.Is(err, ErrRangeNotSupported) {
+		t.Errorf("OpenRemoteFile() error = %v, want %v", err, ErrRangeNotSupported)
+	}
+}
+
+func TestRemoteFile_FallsBackToOriginalUrl(t *testing.T) {
+	content := bytes.Repeat([]byte("expired"), 64*1024)
+	// the original url redirects to a signed url which
+	// expires once a newer one is handed out.
+	var signed atomic.Int32
+	var mux http.ServeMux
+	mux.HandleFunc("/file.bin", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/signed?v="+strconv.Itoa(int(signed.Add(1))), http.StatusFound)
+	})
+	mux.HandleFunc("/signed", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("v") != strconv.Itoa(int(signed.Load())) {
+			http.Error(w, "expired", http.StatusForbidden)
+			return
+		}
+		http.ServeContent(w, r, "file.bin", time.Time{}, bytes.NewReader(content))
+	})
+	srv := httptest.NewServer(&mux)
+	defer srv.Close()
+	f, err := OpenRemoteFile(srv.Client(), srv.URL+"/file.bin", &RemoteFileOpts{ReadAhead: -1})
+	if err != nil {
+		t.Fatalf("OpenRemoteFile() error = %v", err)
+	}
+	defer f.Close()
+	signed.Add(1)
+	b := make([]byte, 10)
+	if _, err = f.ReadAt(b, 100); err != nil {
+		t.Fatalf("ReadAt() error = %v", err)
+	}
+	if !bytes.Equal(b, content[100:110]) {
+		t.Errorf("ReadAt() = %q, want %q", b, content[100:110])
+	}
+	if f.url != srv.URL+"/file.bin" {
+		t.Errorf("url after fallback = %v, want the original url", f.url)
+	}
+}
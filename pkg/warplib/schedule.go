@@ -0,0 +1,128 @@
+package warplib
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schedule restricts when a download is allowed to run.
+type Schedule struct {
+	// StartAt is the time before which the download
+	// doesn't start, ignored if zero.
+	StartAt time.Time `json:"start_at"`
+	// Windows are the daily time windows the download is
+	// allowed to run in, it can run anytime if empty.
+	Windows []TimeWindow `json:"windows"`
+}
+
+// TimeWindow is a daily time window, in minutes since midnight
+// of the local time. A window ending before its start spans
+// midnight, such as 22:00-06:00.
+type TimeWindow struct {
+	Start int `json:"start"`
+	End   int `json:"end"`
+	// SpeedLimit limits the download speed during the window
+	// in bytes per second, unlimited if 0.
+	SpeedLimit int64 `json:"speed_limit"`
+}
+
+// Allowed reports whether the download is allowed to run at t
+// and the speed limit it has to respect at that time.
+func (s *Schedule) Allowed(t time.Time) (ok bool, speedLimit int64) {
+	if s == nil {
+		return true, 0
+	}
+	if t.Before(s.StartAt) {
+		return false, 0
+	}
+	if len(s.Windows) == 0 {
+		return true, 0
+	}
+	for _, w := range s.Windows {
+		if w.contains(t) {
+			return true, w.SpeedLimit
+		}
+	}
+	return false, 0
+}
+
+func (w TimeWindow) contains(t time.Time) bool {
+	m := t.Hour()*60 + t.Minute()
+	if w.Start <= w.End {
+		return m >= w.Start && m < w.End
+	}
+	return m >= w.Start || m < w.End
+}
+
+func (w TimeWindow) String() string {
+	clock := func(m int) string {
+		return fmt.Sprintf("%02d:%02d", m/60, m%60)
+	}
+	s := clock(w.Start) + "-" + clock(w.End)
+	if w.SpeedLimit > 0 {
+		s += "@" + FormatSize(w.SpeedLimit)
+	}
+	return s
+}
+
+// ParseTimeWindow parses a daily time window in the format
+// "HH:MM-HH:MM", optionally followed by a speed limit in bytes
+// per second, such as "22:00-06:00@2MB".
+func ParseTimeWindow(s string) (w TimeWindow, err error) {
+	span, limit, hasLimit := strings.Cut(strings.TrimSpace(s), "@")
+	start, end, ok := strings.Cut(span, "-")
+	if !ok {
+		err = fmt.Errorf("%w: time window %q", ErrInvalidSchedule, s)
+		return
+	}
+	if w.Start, err = parseClock(start); err != nil {
+		return
+	}
+	if w.End, err = parseClock(end); err != nil {
+		return
+	}
+	if w.Start == w.End {
+		err = fmt.Errorf("%w: time window %q is empty", ErrInvalidSchedule, s)
+		return
+	}
+	if hasLimit {
+		w.SpeedLimit, err = ParseSize(strings.TrimSuffix(limit, "/s"))
+	}
+	return
+}
+
+// ParseStartTime parses the time a download should start at,
+// "HH:MM" is its next occurrence after now, dates are accepted
+// in the "2006-01-02 15:04" and RFC 3339 formats.
+func ParseStartTime(s string, now time.Time) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	if m, err := parseClock(s); err == nil {
+		y, mo, d := now.Date()
+		t := time.Date(y, mo, d, m/60, m%60, 0, 0, now.Location())
+		if !t.After(now) {
+			t = t.AddDate(0, 0, 1)
+		}
+		return t, nil
+	}
+	if t, err := time.ParseInLocation("2006-01-02 15:04", s, now.Location()); err == nil {
+		return t, nil
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("%w: start time %q", ErrInvalidSchedule, s)
+}
+
+// parseClock parses "HH:MM" as minutes since midnight,
+// "24:00" is accepted as the end of a day.
+func parseClock(s string) (int, error) {
+	h, m, ok := strings.Cut(strings.TrimSpace(s), ":")
+	hh, err1 := strconv.Atoi(h)
+	mm, err2 := strconv.Atoi(m)
+	if !ok || err1 != nil || err2 != nil || hh < 0 || mm < 0 || mm > 59 || hh > 24 || hh == 24 && mm != 0 {
+		return 0, fmt.Errorf("%w: time %q", ErrInvalidSchedule, s)
+	}
+	return hh*60 + mm, nil
+}
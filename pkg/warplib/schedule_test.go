@@ -0,0 +1,132 @@
+package warplib
+
+import (
+	"errors"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestParseTimeWindow(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    TimeWindow
+		wantErr error
+	}{
+		{"09:00-17:30", TimeWindow{Start: 540, End: 1050}, nil},
+		{"22:00-06:00@2MB", TimeWindow{Start: 1320, End: 360, SpeedLimit: 2 * MB}, nil},
+		{"00:00-24:00@512KB/s", TimeWindow{Start: 0, End: 1440, SpeedLimit: 512 * KB}, nil},
+		{"10:00", TimeWindow{}, ErrInvalidSchedule},
+		{"10:00-10:00", TimeWindow{}, ErrInvalidSchedule},
+		{"25:00-10:00", TimeWindow{}, ErrInvalidSchedule},
+		{"10:00-11:00@fast", TimeWindow{}, ErrInvalidSize},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := ParseTimeWindow(tt.in)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("ParseTimeWindow() error = %v, want %v", err, tt.wantErr)
+			}
+			if err == nil && got != tt.want {
+				t.Errorf("ParseTimeWindow() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+	if got := (TimeWindow{Start: 1320, End: 360, SpeedLimit: 2 * MB}).String(); got != "22:00-06:00@2MB" {
+		t.Errorf("String() = %v", got)
+	}
+}
+
+func TestSchedule_Allowed(t *testing.T) {
+	at := func(h, m int) time.Time {
+		return time.Date(2024, 1, 2, h, m, 0, 0, time.Local)
+	}
+	s := &Schedule{
+		StartAt: at(1, 0),
+		Windows: []TimeWindow{
+			{Start: 1320, End: 360, SpeedLimit: MB},
+			{Start: 720, End: 780},
+		},
+	}
+	tests := []struct {
+		t         time.Time
+		wantOk    bool
+		wantLimit int64
+	}{
+		{at(0, 30), false, 0},
+		{at(2, 0), true, MB},
+		{at(6, 0), false, 0},
+		{at(12, 59), true, 0},
+		{at(23, 0), true, MB},
+	}
+	for _, tt := range tests {
+		ok, limit := s.Allowed(tt.t)
+		if ok != tt.wantOk || limit != tt.wantLimit {
+			t.Errorf("Allowed(%v) = %v, %d, want %v, %d", tt.t.Format("15:04"), ok, limit, tt.wantOk, tt.wantLimit)
+		}
+	}
+}
+
+func TestParseStartTime(t *testing.T) {
+	now := time.Date(2024, 1, 2, 10, 0, 0, 0, time.Local)
+	tests := []struct {
+		in   string
+		want time.Time
+	}{
+		{"12:30", time.Date(2024, 1, 2, 12, 30, 0, 0, time.Local)},
+		{"02:00", time.Date(2024, 1, 3, 2, 0, 0, 0, time.Local)},
+		{"2024-02-01 03:04", time.Date(2024, 2, 1, 3, 4, 0, 0, time.Local)},
+	}
+	for _, tt := range tests {
+		got, err := ParseStartTime(tt.in, now)
+		if err != nil {
+			t.Fatalf("ParseStartTime(%q) error = %v", tt.in, err)
+		}
+		if !got.Equal(tt.want) {
+			t.Errorf("ParseStartTime(%q) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+	if _, err := ParseStartTime("tomorrow", now); !errors.Is(err, ErrInvalidSchedule) {
+		t.Errorf("ParseStartTime() error = %v, want %v", err, ErrInvalidSchedule)
+	}
+}
+
+func TestParseSize(t *testing.T) {
+	tests := map[string]int64{
+		"100":   100,
+		"100B":  100,
+		"512kb": 512 * KB,
+		"1.5M":  3 * MB / 2,
+		"2GB":   2 * GB,
+	}
+	for in, want := range tests {
+		if got, err := ParseSize(in); err != nil || got != want {
+			t.Errorf("ParseSize(%q) = %d, %v, want %d", in, got, err, want)
+		}
+	}
+	if _, err := ParseSize("-1"); !errors.Is(err, ErrInvalidSize) {
+		t.Errorf("ParseSize() error = %v, want %v", err, ErrInvalidSize)
+	}
+}
+
+func TestDownloader_SpeedLimit(t *testing.T) {
+	content := make([]byte, 256*KB)
+	srv := newTestServer(t, content)
+	d, err := NewDownloader(srv.Client(), srv.URL+"/file.bin", &DownloaderOpts{
+		DownloadDirectory: t.TempDir(),
+		MaxConnections:    4,
+		SpeedLimit:        512 * KB,
+	})
+	if err != nil {
+		t.Fatalf("NewDownloader() error = %v", err)
+	}
+	defer os.RemoveAll(d.dlPath)
+	start := time.Now()
+	if err = d.Start(); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	// the probe may deliver a bit of the content unthrottled
+	if elapsed := time.Since(start); elapsed < 300*time.Millisecond {
+		t.Errorf("downloaded 256KB in %v with a limit of 512KB/s", elapsed)
+	}
+}
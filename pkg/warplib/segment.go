@@ -0,0 +1,73 @@
+package warplib
+
+import (
+	"cmp"
+	"fmt"
+	"os"
+	"slices"
+	"sync/atomic"
+)
+
+// Segment is a byte range of a download downloaded by a single
+// part, offsets are relative to the downloaded range and End is
+// inclusive.
+type Segment struct {
+	Start int64 `json:"start"`
+	End   int64 `json:"end"`
+	// Read is the number of bytes downloaded from Start.
+	Read int64 `json:"read"`
+	// Active reports whether a connection is downloading
+	// the segment currently.
+	Active bool `json:"active,omitempty"`
+}
+
+// GetSegments returns the segments of the item sorted by offset.
+// A complete item has a single segment, an item which isn't
+// segmented, such as a download of unknown size, has none.
+func (i *Item) GetSegments() []Segment {
+	if i.IsComplete() {
+		return []Segment{{End: i.TotalSize.v() - 1, Read: i.TotalSize.v()}}
+	}
+	if i.TotalSize.IsUnknown() || i.Group {
+		return nil
+	}
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+	segs := make([]Segment, 0, len(i.Parts))
+	dir := fmt.Sprintf("%s/%s/", DlDataDir, i.Hash)
+	for ioff, part := range i.Parts {
+		seg := Segment{Start: ioff, End: part.FinalOffset}
+		switch {
+		case part.Compiled:
+			seg.Read = seg.End - seg.Start + 1
+		case i.dAlloc != nil && i.dAlloc.partProgress(part.Hash, &seg):
+		default:
+			// bytes of a part which isn't running
+			// are the ones in its part file.
+			if fi, err := os.Stat(getFileName(dir, part.Hash)); err == nil {
+				seg.Read = fi.Size()
+			}
+		}
+		seg.Read = min(max(seg.Read, 0), seg.End-seg.Start+1)
+		segs = append(segs, seg)
+	}
+	slices.SortFunc(segs, func(a, b Segment) int {
+		return cmp.Compare(a.Start, b.Start)
+	})
+	return segs
+}
+
+// partProgress sets the progress of the part of hash to seg
+// and reports whether the part is being downloaded.
+func (d *Downloader) partProgress(hash string, seg *Segment) bool {
+	d.segMu.Lock()
+	defer d.segMu.Unlock()
+	p, ok := d.active[hash]
+	if !ok {
+		return false
+	}
+	seg.End = p.getFoff()
+	seg.Read = atomic.LoadInt64(&p.read)
+	seg.Active = true
+	return true
+}
@@ -0,0 +1,76 @@
+package warplib
+
+import (
+	"context"
+	"os"
+	"strconv"
+	"sync"
+	"testing"
+)
+
+func TestItem_GetSegments(t *testing.T) {
+	hash := "segtest" + strconv.Itoa(os.Getpid())
+	dir := GetPath(DlDataDir, hash)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	item, err := newItem(new(sync.RWMutex), "file.bin", "http://example.com/file.bin", ".", hash, 300, true, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	item.Parts = map[int64]*ItemPart{
+		200: {Hash: "c", FinalOffset: 299},
+		0:   {Hash: "a", FinalOffset: 99, Compiled: true},
+		100: {Hash: "b", FinalOffset: 199},
+	}
+	// idle part b has 40 bytes in its part file
+	err = os.WriteFile(getFileName(dir+"/", "b"), make([]byte, 40), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// part c is being downloaded and was split at 249
+	d := &Downloader{active: map[string]*Part{
+		"c": {hash: "c", offset: 200, read: 30, foff: 249},
+	}}
+	item.dAlloc = d
+
+	want := []Segment{
+		{Start: 0, End: 99, Read: 100},
+		{Start: 100, End: 199, Read: 40},
+		{Start: 200, End: 249, Read: 30, Active: true},
+	}
+	got := item.GetSegments()
+	if len(got) != len(want) {
+		t.Fatalf("GetSegments() = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("GetSegments()[%d] = %v, want %v", i, got[i], want[i])
+		}
+	}
+
+	item.Downloaded = item.TotalSize
+	if got = item.GetSegments(); len(got) != 1 || got[0] != (Segment{End: 299, Read: 300}) {
+		t.Errorf("GetSegments() of complete item = %v", got)
+	}
+}
+
+func TestDownloader_SetMaxConnections(t *testing.T) {
+	d := &Downloader{ctx: context.Background(), maxConn: 8, connLimit: 2, handlers: &Handlers{}}
+	if got := d.SetMaxConnections(20); got != 8 {
+		t.Errorf("SetMaxConnections(20) = %d, want 8", got)
+	}
+	if got := d.SetMaxConnections(0); got != 1 {
+		t.Errorf("SetMaxConnections(0) = %d, want 1", got)
+	}
+	d.SetMaxConnections(3)
+	if got := d.getConnLimit(); got != 3 {
+		t.Errorf("connection limit = %d, want 3", got)
+	}
+	// the tuner starts below the cap
+	d.startTuner(6)()
+	if got := d.getConnLimit(); got != 3 {
+		t.Errorf("connection limit after startTuner = %d, want 3", got)
+	}
+}
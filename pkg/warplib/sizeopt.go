@@ -1,6 +1,7 @@
 package warplib
 
 import (
+	"fmt"
 	"strconv"
 	"strings"
 )
@@ -47,3 +48,41 @@ var (
 	SizeOptionGB = SizeOption{GB, "GB"}
 	SizeOptionTB = SizeOption{TB, "TB"}
 )
+
+var sizeUnits = []SizeOption{SizeOptionTB, SizeOptionGB, SizeOptionMB, SizeOptionKB}
+
+// ParseSize parses a size such as "512KB", "1.5MB" or "100",
+// units are case insensitive and their B suffix is optional.
+func ParseSize(s string) (int64, error) {
+	num := strings.ToUpper(strings.TrimSpace(s))
+	mul := int64(B)
+	for _, opt := range sizeUnits {
+		if n, ok := strings.CutSuffix(num, opt.fmt); ok {
+			num, mul = n, opt.val
+			break
+		}
+		if n, ok := strings.CutSuffix(num, opt.fmt[:1]); ok {
+			num, mul = n, opt.val
+			break
+		}
+	}
+	if mul == B {
+		num = strings.TrimSuffix(num, "B")
+	}
+	v, err := strconv.ParseFloat(strings.TrimSpace(num), 64)
+	if err != nil || v < 0 {
+		return 0, fmt.Errorf("%w: %q", ErrInvalidSize, s)
+	}
+	return int64(v * float64(mul)), nil
+}
+
+// FormatSize formats a size with the largest unit it is
+// a multiple of, the result can be parsed by ParseSize.
+func FormatSize(n int64) string {
+	for _, opt := range sizeUnits {
+		if n != 0 && n%opt.val == 0 {
+			return opt.StringFrom(n / opt.val)
+		}
+	}
+	return strconv.FormatInt(n, 10)
+}
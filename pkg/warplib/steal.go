@@ -0,0 +1,128 @@
+package warplib
+
+import "sync/atomic"
+
+func (d *Downloader) addActivePart(part *Part) {
+	d.segMu.Lock()
+	d.active[part.hash] = part
+	d.segMu.Unlock()
+}
+
+func (d *Downloader) removeActivePart(part *Part) {
+	d.segMu.Lock()
+	delete(d.active, part.hash)
+	d.segMu.Unlock()
+}
+
+// stealWork puts the connection freed by the finished part to use.
+// It steals the tail half of the largest remaining segment from
+// another part, or races a duplicate request for the segment if it
+// is too small to be split and tail racing is enabled.
+func (d *Downloader) stealWork(done *Part, espeed int64) {
+	d.endRace(done)
+	if d.stopped.Load() {
+		return
+	}
+	d.segMu.Lock()
+	defer d.segMu.Unlock()
+	if limit := d.getConnLimit(); limit != 0 && atomic.LoadInt32(&d.numConn) > limit {
+		// connection tuner retired this connection.
+		return
+	}
+	if d.maxParts != 0 && atomic.LoadInt32(&d.numParts) >= d.maxParts {
+		return
+	}
+	var (
+		victim *Part
+		left   int64
+	)
+	for _, p := range d.active {
+		if p.rival != nil {
+			continue
+		}
+		if r := p.remaining(); r > left {
+			victim, left = p, r
+		}
+	}
+	if victim == nil {
+		return
+	}
+	pos, foff := victim.position(), victim.getFoff()
+	if left > 2*MIN_PART_SIZE {
+		ioff := pos + left/2
+		victim.setFoff(ioff - 1)
+		d.Log("%s: stealing %d => %d from part", victim.hash, ioff, foff)
+		d.handlers.RespawnPartHandler(victim.hash, victim.offset, pos, ioff-1)
+		d.wg.Add(1)
+		go d.newPartDownload(ioff, foff, espeed)
+		return
+	}
+	if !d.raceTail || left < 2 {
+		return
+	}
+	if pos == victim.offset {
+		// parts are tracked by their offsets and hence the
+		// racing part can't share the offset of the victim.
+		pos++
+	}
+	d.Log("%s: racing %d => %d with a new part", victim.hash, pos, foff)
+	d.wg.Add(1)
+	go d.racePart(victim, pos, foff, espeed)
+}
+
+// racePart downloads the remaining bytes of victim in a new part,
+// the part that finishes first truncates the other one.
+func (d *Downloader) racePart(victim *Part, ioff, foff, espeed int64) {
+	atomic.AddInt32(&d.numConn, 1)
+	defer func() { atomic.AddInt32(&d.numConn, -1); d.wg.Done() }()
+	part, err := d.spawnPart(ioff, foff)
+	if err != nil {
+		d.Log("failed to spawn racing part: %s", err.Error())
+		return
+	}
+	d.segMu.Lock()
+	if victim.remaining() <= 0 {
+		// victim finished while the part was being spawned.
+		d.segMu.Unlock()
+		part.setFoff(ioff - 1)
+		d.handlers.RespawnPartHandler(part.hash, ioff, ioff, ioff-1)
+	} else {
+		part.rival, victim.rival = victim, part
+		d.segMu.Unlock()
+		err = d.runPart(part, ioff, foff, espeed, false, nil)
+	}
+	d.removeActivePart(part)
+	if err != nil {
+		return
+	}
+	d.endRace(part)
+	d.compilePart(part)
+}
+
+// endRace truncates the rival of the finished part, if any, so
+// that it only covers the bytes the finished part doesn't. The
+// rival is cancelled if it has already downloaded those bytes.
+func (d *Downloader) endRace(done *Part) {
+	d.segMu.Lock()
+	rival := done.rival
+	if rival != nil {
+		done.rival, rival.rival = nil, nil
+	}
+	d.segMu.Unlock()
+	if rival == nil {
+		return
+	}
+	// rival keeps the bytes before the finished part only,
+	// bytes downloaded beyond that are discarded.
+	pos := rival.position()
+	foff := max(done.offset, rival.offset) - 1
+	if foff >= rival.getFoff() {
+		return
+	}
+	rival.setFoff(foff)
+	d.Log("%s: lost race to %s, truncated to %d", rival.hash, done.hash, foff)
+	d.handlers.RespawnPartHandler(rival.hash, rival.offset, pos, foff)
+	if rival.remaining() <= 0 {
+		rival.cancel()
+	}
+}
@@ -0,0 +1,168 @@
+package warplib
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"sync"
+	"sync/atomic"
+)
+
+const (
+	// DEF_STREAM_SEGMENT_SIZE is the size of segments requested
+	// in parallel while streaming.
+	DEF_STREAM_SEGMENT_SIZE = 2 * MB
+	// DEF_STREAM_BUFFER_SIZE is the maximum number of bytes held
+	// in memory for segments which can't be written yet.
+	DEF_STREAM_BUFFER_SIZE = 32 * MB
+)
+
+// StreamOpts are the optional fields of Downloader.Stream.
+type StreamOpts struct {
+	// SegmentSize is the size of each ranged request.
+	SegmentSize int64
+	// BufferSize bounds the memory used for reordering segments,
+	// parts can't run further ahead of the writer than this.
+	BufferSize int64
+}
+
+// streamSegment is a segment of the file downloaded in memory.
+type streamSegment struct {
+	ioff, foff int64
+	buf        []byte
+	err        error
+	done       chan struct{}
+}
+
+// Stream downloads the file and writes it to w in order instead of
+// saving it to disk. Segments are downloaded in parallel using up to
+// max connections of the downloader into a bounded reorder buffer, a
+// single connection is used if the file can't be downloaded in parts.
+// The downloader should be created with SkipSetup as nothing is
+// written to the download directory.
+func (d *Downloader) Stream(w io.Writer, opts *StreamOpts) (err error) {
+	if opts == nil {
+		opts = &StreamOpts{}
+	}
+	if opts.SegmentSize <= 0 {
+		opts.SegmentSize = DEF_STREAM_SEGMENT_SIZE
+	}
+	if opts.BufferSize <= 0 {
+		opts.BufferSize = DEF_STREAM_BUFFER_SIZE
+	}
+	if d.l == nil {
+		d.l = log.New(io.Discard, "", 0)
+	}
+	d.handlers.setDefault(d.l)
+	atomic.StoreInt32(&d.running, 1)
+	defer atomic.StoreInt32(&d.running, 0)
+	d.handlers.DownloadStartedHandler()
+
+	cl := d.contentLength.v()
+	if d.contentLength.IsUnknown() || !d.resumable || d.maxConn < 2 || cl <= opts.SegmentSize {
+		d.Log("Streaming in a single connection...")
+		err = d.copyContent(0, w)
+	} else {
+		err = d.streamSegments(w, cl, opts)
+	}
+	if d.stopped.Load() {
+		d.handlers.DownloadStoppedHandler()
+		return nil
+	}
+	if err != nil {
+		return
+	}
+	d.handlers.DownloadCompleteHandler(MAIN_HASH, atomic.LoadInt64(&d.nread))
+	return
+}
+
+func (d *Downloader) streamSegments(w io.Writer, cl int64, opts *StreamOpts) error {
+	// number of segments which can be buffered, it is
+	// at least the number of connections to keep all of
+	// them busy.
+	window := max(opts.BufferSize/opts.SegmentSize, int64(d.maxConn))
+	var (
+		// segments in the order they are to be written,
+		// sends block once window is full which keeps
+		// the parts from running too far ahead.
+		ordered = make(chan *streamSegment, window-1)
+		jobs    = make(chan *streamSegment)
+		wg      sync.WaitGroup
+	)
+	for i := int32(0); i < d.maxConn; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for seg := range jobs {
+				seg.buf, seg.err = d.fetchSegment(seg.ioff, seg.foff)
+				close(seg.done)
+			}
+		}()
+	}
+	go func() {
+		defer close(ordered)
+		defer close(jobs)
+		for ioff := int64(0); ioff < cl; ioff += opts.SegmentSize {
+			seg := &streamSegment{
+				ioff: ioff,
+				foff: min(ioff+opts.SegmentSize, cl) - 1,
+				done: make(chan struct{}),
+			}
+			select {
+			case ordered <- seg:
+			case <-d.ctx.Done():
+				return
+			}
+			select {
+			case jobs <- seg:
+			case <-d.ctx.Done():
+				return
+			}
+		}
+	}()
+	defer wg.Wait()
+	// cancel pending requests if writing fails.
+	defer d.cancel()
+	for seg := range ordered {
+		select {
+		case <-seg.done:
+		case <-d.ctx.Done():
+			return d.ctx.Err()
+		}
+		if seg.err != nil {
+			return seg.err
+		}
+		if _, err := w.Write(seg.buf); err != nil {
+			return err
+		}
+		seg.buf = nil
+	}
+	return d.ctx.Err()
+}
+
+// fetchSegment downloads the content from ioff to foff in memory.
+func (d *Downloader) fetchSegment(ioff, foff int64) ([]byte, error) {
+	req, err := http.NewRequestWithContext(d.ctx, http.MethodGet, d.getPartUrl(), nil)
+	if err != nil {
+		return nil, err
+	}
+	header := req.Header
+	d.headers.Set(header)
+	setRange(header, d.rangeStart+ioff, d.rangeStart+foff)
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusPartialContent {
+		return nil, fmt.Errorf("%w: %s for range %d-%d", ErrUnexpectedStatus, resp.Status, ioff, foff)
+	}
+	buf := make([]byte, foff-ioff+1)
+	body := NewCallbackProxyReader(resp.Body, func(n int) {
+		atomic.AddInt64(&d.nread, int64(n))
+		d.handlers.DownloadProgressHandler(MAIN_HASH, n)
+	})
+	_, err = io.ReadFull(body, buf)
+	return buf, err
+}
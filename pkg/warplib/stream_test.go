@@ -0,0 +1,42 @@
+package warplib
+
+import (
+	"bytes"
+	"net/http"
+	"testing"
+)
+
+func TestDownloader_Stream(t *testing.T) {
+	content := bytes.Repeat([]byte("stream"), 512*1024)
+	tests := []struct {
+		name    string
+		newSrv  func(*testing.T, []byte) string
+		maxConn int32
+	}{
+		{"segments", func(t *testing.T, b []byte) string { return newTestServer(t, b).URL }, 4},
+		{"single connection", func(t *testing.T, b []byte) string { return newTestServer(t, b).URL }, 1},
+		{"unknown size", func(t *testing.T, b []byte) string { return newChunkedTestServer(t, b).URL }, 4},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, err := NewDownloader(http.DefaultClient, tt.newSrv(t, content)+"/file.bin", &DownloaderOpts{
+				MaxConnections: tt.maxConn,
+				SkipSetup:      true,
+			})
+			if err != nil {
+				t.Fatalf("NewDownloader() error = %v", err)
+			}
+			var buf bytes.Buffer
+			err = d.Stream(&buf, &StreamOpts{
+				SegmentSize: 256 * KB,
+				BufferSize:  MB,
+			})
+			if err != nil {
+				t.Fatalf("Stream() error = %v", err)
+			}
+			if !bytes.Equal(buf.Bytes(), content) {
+				t.Errorf("streamed content mismatch: got %d bytes, want %d", buf.Len(), len(content))
+			}
+		})
+	}
+}
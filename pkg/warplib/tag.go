@@ -0,0 +1,66 @@
+package warplib
+
+import (
+	"slices"
+	"strings"
+)
+
+// HasTag reports whether the item is labelled with tag,
+// tags are compared case-insensitively.
+func (i *Item) HasTag(tag string) bool {
+	tag = strings.TrimSpace(tag)
+	return slices.ContainsFunc(i.Tags, func(t string) bool {
+		return strings.EqualFold(t, tag)
+	})
+}
+
+// mergeTags appends the tags which aren't already in dst to it,
+// ignoring the case, surrounding spaces and empty tags.
+func mergeTags(dst []string, tags ...string) []string {
+	for _, tag := range tags {
+		tag = strings.TrimSpace(tag)
+		if tag == "" || slices.ContainsFunc(dst, func(t string) bool {
+			return strings.EqualFold(t, tag)
+		}) {
+			continue
+		}
+		dst = append(dst, tag)
+	}
+	return dst
+}
+
+// UpdateTags adds the tags add to the item of hash and
+// removes the tags remove from it.
+func (m *Manager) UpdateTags(hash string, add, remove []string) (item *Item, err error) {
+	item = m.GetItem(hash)
+	if item == nil {
+		err = ErrDownloadNotFound
+		return
+	}
+	item.mu.Lock()
+	tags := mergeTags(slices.Clone(item.Tags), add...)
+	tags = slices.DeleteFunc(tags, func(t string) bool {
+		return slices.ContainsFunc(remove, func(r string) bool {
+			return strings.EqualFold(t, strings.TrimSpace(r))
+		})
+	})
+	item.Tags = tags
+	item.mu.Unlock()
+	m.UpdateItem(item)
+	return
+}
+
+// SetNote sets the note of the item of hash,
+// an empty note removes it.
+func (m *Manager) SetNote(hash, note string) (item *Item, err error) {
+	item = m.GetItem(hash)
+	if item == nil {
+		err = ErrDownloadNotFound
+		return
+	}
+	item.mu.Lock()
+	item.Note = note
+	item.mu.Unlock()
+	m.UpdateItem(item)
+	return
+}
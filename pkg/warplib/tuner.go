@@ -0,0 +1,140 @@
+package warplib
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// DEF_TUNE_INTERVAL is the interval aggregate throughput
+	// is sampled at by the connection tuner.
+	DEF_TUNE_INTERVAL = 2 * time.Second
+	// minimum relative throughput gain for an added
+	// connection to be kept.
+	tuneMinGain = 0.05
+	// number of intervals to wait after retiring a
+	// connection before probing again.
+	tuneHoldIntervals = 5
+)
+
+// connTuner decides the number of connections a download should
+// use. It keeps adding connections, one at a time, as long as they
+// increase the aggregate throughput and retires the last added one
+// once they stop doing so. A retired connection isn't closed, it
+// just isn't replaced once its part finishes.
+type connTuner struct {
+	max    int32
+	lastTp int64
+	added  bool
+	hold   int
+}
+
+// next returns the connection limit to be used after observing
+// throughput tp (bytes per second) with the current limit.
+func (t *connTuner) next(tp int64, limit int32) int32 {
+	defer func() { t.lastTp = tp }()
+	switch {
+	case t.added:
+		t.added = false
+		if float64(tp) > float64(t.lastTp)*(1+tuneMinGain) {
+			return t.add(limit)
+		}
+		// additional connection didn't help, retire it
+		// and keep the limit for a while.
+		t.hold = tuneHoldIntervals
+		return max(limit-1, 1)
+	case t.hold > 0:
+		t.hold--
+		return limit
+	default:
+		return t.add(limit)
+	}
+}
+
+func (t *connTuner) add(limit int32) int32 {
+	if limit >= t.max {
+		return limit
+	}
+	t.added = true
+	return limit + 1
+}
+
+// runTuner adjusts the connection limit of the download until
+// done is closed or download is stopped.
+func (d *Downloader) runTuner(done <-chan struct{}) {
+	tuner := &connTuner{max: d.getConnCap()}
+	ticker := time.NewTicker(DEF_TUNE_INTERVAL)
+	defer ticker.Stop()
+	last := atomic.LoadInt64(&d.dread)
+	for {
+		select {
+		case <-done:
+			return
+		case <-d.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		cur := atomic.LoadInt64(&d.dread)
+		tp := (cur - last) * _SECOND / int64(DEF_TUNE_INTERVAL)
+		last = cur
+		prev := d.getConnLimit()
+		if c := d.getConnCap(); c != tuner.max {
+			// the cap was changed while downloading, the
+			// throughput doesn't reflect a probe anymore.
+			tuner = &connTuner{max: c}
+		}
+		limit := tuner.next(tp, prev)
+		if limit == prev || !atomic.CompareAndSwapInt32(&d.connLimit, prev, limit) {
+			continue
+		}
+		if limit > prev {
+			// ask running parts to split themselves
+			// to make use of the new connection.
+			atomic.AddInt32(&d.splitReq, limit-prev)
+			d.Log("Tuner: throughput %s/s, adding connection (%d => %d)", ContentLength(tp), prev, limit)
+		} else {
+			atomic.StoreInt32(&d.splitReq, 0)
+			d.Log("Tuner: throughput %s/s, retiring connection (%d => %d)", ContentLength(tp), prev, limit)
+		}
+		d.handlers.ConnectionsTunedHandler(prev, limit, tp)
+	}
+}
+
+// startTuner starts the connection tuner if the download can use
+// more than a single connection. The returned function stops it.
+func (d *Downloader) startTuner(limit int32) (stop func()) {
+	if d.disableTuning || d.maxConn < 2 {
+		atomic.StoreInt32(&d.connLimit, d.getConnCap())
+		return func() {}
+	}
+	atomic.StoreInt32(&d.connLimit, min(max(limit, 1), d.getConnCap()))
+	done := make(chan struct{})
+	go d.runTuner(done)
+	return func() { close(done) }
+}
+
+func (d *Downloader) getConnLimit() int32 {
+	return atomic.LoadInt32(&d.connLimit)
+}
+
+// getConnCap returns the connection cap set by SetMaxConnections,
+// maxConn if it isn't set.
+func (d *Downloader) getConnCap() int32 {
+	if c := atomic.LoadInt32(&d.connCap); c != 0 {
+		return c
+	}
+	return d.maxConn
+}
+
+// takeSplitRequest consumes a pending split request, if any.
+func takeSplitRequest(req *int32) bool {
+	for {
+		n := atomic.LoadInt32(req)
+		if n <= 0 {
+			return false
+		}
+		if atomic.CompareAndSwapInt32(req, n, n-1) {
+			return true
+		}
+	}
+}
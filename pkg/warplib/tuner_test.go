@@ -0,0 +1,39 @@
+package warplib
+
+import "testing"
+
+func Test_connTuner_next(t *testing.T) {
+	tuner := &connTuner{max: 4}
+	steps := []struct {
+		tp   int64
+		want int32
+	}{
+		// probe an extra connection
+		{100, 2},
+		// throughput increased, keep adding
+		{200, 3},
+		// no significant gain, retire the last one
+		{205, 2},
+	}
+	limit := int32(1)
+	for i, s := range steps {
+		limit = tuner.next(s.tp, limit)
+		if limit != s.want {
+			t.Fatalf("step %d: next() = %d, want %d", i, limit, s.want)
+		}
+	}
+	// limit is held before probing again
+	for i := 0; i < tuneHoldIntervals; i++ {
+		if limit = tuner.next(200, limit); limit != 2 {
+			t.Fatalf("hold %d: next() = %d, want 2", i, limit)
+		}
+	}
+	if limit = tuner.next(200, limit); limit != 3 {
+		t.Fatalf("next() after hold = %d, want 3", limit)
+	}
+	// never exceeds max
+	tuner = &connTuner{max: 2}
+	if got := tuner.next(100, 2); got != 2 {
+		t.Errorf("next() = %d, want 2", got)
+	}
+}